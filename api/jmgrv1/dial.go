@@ -0,0 +1,24 @@
+// Package jmgrv1 is the gRPC API of 'jmgr daemon -grpc path', generated from api/jmgr.proto, and Dial.
+//
+//	c, conn, err := jmgrv1.Dial("/var/run/jmgr-grpc.sock")
+//	if err != nil {
+//		log.Fatalln(err)
+//	}
+//	defer conn.Close()
+//	reply, err := c.List(context.Background(), &jmgrv1.ListRequest{})
+package jmgrv1
+
+import (
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Dial the gRPC socket of 'jmgr daemon', a root only Unix socket, so without TLS
+func Dial(socket string) (JmgrClient, *grpc.ClientConn, error) {
+
+	conn, err := grpc.NewClient("unix:"+socket, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, nil, err
+	}
+	return NewJmgrClient(conn), conn, nil
+}
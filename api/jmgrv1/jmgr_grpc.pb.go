@@ -0,0 +1,388 @@
+// jmgr(8) control API, the typed version of the JSON-RPC methods of 'jmgr daemon' (Jmgr.List, Jmgr.Jail, ...).
+// Served by 'jmgr daemon -grpc path' on a root only Unix socket. The Go code in jmgr/api/jmgrv1 is generated,
+// after a change run: make proto
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.4.0
+// - protoc             (unknown)
+// source: jmgr.proto
+
+package jmgrv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.62.0 or later.
+const _ = grpc.SupportPackageIsVersion8
+
+const (
+	Jmgr_List_FullMethodName     = "/jmgr.v1.Jmgr/List"
+	Jmgr_GetJail_FullMethodName  = "/jmgr.v1.Jmgr/GetJail"
+	Jmgr_Refresh_FullMethodName  = "/jmgr.v1.Jmgr/Refresh"
+	Jmgr_Start_FullMethodName    = "/jmgr.v1.Jmgr/Start"
+	Jmgr_Stop_FullMethodName     = "/jmgr.v1.Jmgr/Stop"
+	Jmgr_Restart_FullMethodName  = "/jmgr.v1.Jmgr/Restart"
+	Jmgr_Create_FullMethodName   = "/jmgr.v1.Jmgr/Create"
+	Jmgr_Snapshot_FullMethodName = "/jmgr.v1.Jmgr/Snapshot"
+)
+
+// JmgrClient is the client API for Jmgr service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type JmgrClient interface {
+	// All jails, Jmgr.List
+	List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListReply, error)
+	// One jail by name, Jmgr.Jail
+	GetJail(ctx context.Context, in *JailRequest, opts ...grpc.CallOption) (*Jail, error)
+	// Harvest the inventory now, Jmgr.Refresh
+	Refresh(ctx context.Context, in *RefreshRequest, opts ...grpc.CallOption) (*RefreshReply, error)
+	// The subcommands, Jmgr.Start, Jmgr.Stop, Jmgr.Create, Jmgr.Snapshot and Jmgr.Run (restart)
+	Start(ctx context.Context, in *RunRequest, opts ...grpc.CallOption) (*RunReply, error)
+	Stop(ctx context.Context, in *RunRequest, opts ...grpc.CallOption) (*RunReply, error)
+	Restart(ctx context.Context, in *RunRequest, opts ...grpc.CallOption) (*RunReply, error)
+	Create(ctx context.Context, in *RunRequest, opts ...grpc.CallOption) (*RunReply, error)
+	Snapshot(ctx context.Context, in *RunRequest, opts ...grpc.CallOption) (*RunReply, error)
+}
+
+type jmgrClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewJmgrClient(cc grpc.ClientConnInterface) JmgrClient {
+	return &jmgrClient{cc}
+}
+
+func (c *jmgrClient) List(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (*ListReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListReply)
+	err := c.cc.Invoke(ctx, Jmgr_List_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *jmgrClient) GetJail(ctx context.Context, in *JailRequest, opts ...grpc.CallOption) (*Jail, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Jail)
+	err := c.cc.Invoke(ctx, Jmgr_GetJail_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *jmgrClient) Refresh(ctx context.Context, in *RefreshRequest, opts ...grpc.CallOption) (*RefreshReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RefreshReply)
+	err := c.cc.Invoke(ctx, Jmgr_Refresh_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *jmgrClient) Start(ctx context.Context, in *RunRequest, opts ...grpc.CallOption) (*RunReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RunReply)
+	err := c.cc.Invoke(ctx, Jmgr_Start_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *jmgrClient) Stop(ctx context.Context, in *RunRequest, opts ...grpc.CallOption) (*RunReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RunReply)
+	err := c.cc.Invoke(ctx, Jmgr_Stop_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *jmgrClient) Restart(ctx context.Context, in *RunRequest, opts ...grpc.CallOption) (*RunReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RunReply)
+	err := c.cc.Invoke(ctx, Jmgr_Restart_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *jmgrClient) Create(ctx context.Context, in *RunRequest, opts ...grpc.CallOption) (*RunReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RunReply)
+	err := c.cc.Invoke(ctx, Jmgr_Create_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *jmgrClient) Snapshot(ctx context.Context, in *RunRequest, opts ...grpc.CallOption) (*RunReply, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RunReply)
+	err := c.cc.Invoke(ctx, Jmgr_Snapshot_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// JmgrServer is the server API for Jmgr service.
+// All implementations must embed UnimplementedJmgrServer
+// for forward compatibility
+type JmgrServer interface {
+	// All jails, Jmgr.List
+	List(context.Context, *ListRequest) (*ListReply, error)
+	// One jail by name, Jmgr.Jail
+	GetJail(context.Context, *JailRequest) (*Jail, error)
+	// Harvest the inventory now, Jmgr.Refresh
+	Refresh(context.Context, *RefreshRequest) (*RefreshReply, error)
+	// The subcommands, Jmgr.Start, Jmgr.Stop, Jmgr.Create, Jmgr.Snapshot and Jmgr.Run (restart)
+	Start(context.Context, *RunRequest) (*RunReply, error)
+	Stop(context.Context, *RunRequest) (*RunReply, error)
+	Restart(context.Context, *RunRequest) (*RunReply, error)
+	Create(context.Context, *RunRequest) (*RunReply, error)
+	Snapshot(context.Context, *RunRequest) (*RunReply, error)
+	mustEmbedUnimplementedJmgrServer()
+}
+
+// UnimplementedJmgrServer must be embedded to have forward compatible implementations.
+type UnimplementedJmgrServer struct {
+}
+
+func (UnimplementedJmgrServer) List(context.Context, *ListRequest) (*ListReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method List not implemented")
+}
+func (UnimplementedJmgrServer) GetJail(context.Context, *JailRequest) (*Jail, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetJail not implemented")
+}
+func (UnimplementedJmgrServer) Refresh(context.Context, *RefreshRequest) (*RefreshReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Refresh not implemented")
+}
+func (UnimplementedJmgrServer) Start(context.Context, *RunRequest) (*RunReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Start not implemented")
+}
+func (UnimplementedJmgrServer) Stop(context.Context, *RunRequest) (*RunReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Stop not implemented")
+}
+func (UnimplementedJmgrServer) Restart(context.Context, *RunRequest) (*RunReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Restart not implemented")
+}
+func (UnimplementedJmgrServer) Create(context.Context, *RunRequest) (*RunReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Create not implemented")
+}
+func (UnimplementedJmgrServer) Snapshot(context.Context, *RunRequest) (*RunReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Snapshot not implemented")
+}
+func (UnimplementedJmgrServer) mustEmbedUnimplementedJmgrServer() {}
+
+// UnsafeJmgrServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to JmgrServer will
+// result in compilation errors.
+type UnsafeJmgrServer interface {
+	mustEmbedUnimplementedJmgrServer()
+}
+
+func RegisterJmgrServer(s grpc.ServiceRegistrar, srv JmgrServer) {
+	s.RegisterService(&Jmgr_ServiceDesc, srv)
+}
+
+func _Jmgr_List_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JmgrServer).List(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Jmgr_List_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JmgrServer).List(ctx, req.(*ListRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Jmgr_GetJail_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(JailRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JmgrServer).GetJail(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Jmgr_GetJail_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JmgrServer).GetJail(ctx, req.(*JailRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Jmgr_Refresh_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RefreshRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JmgrServer).Refresh(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Jmgr_Refresh_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JmgrServer).Refresh(ctx, req.(*RefreshRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Jmgr_Start_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RunRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JmgrServer).Start(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Jmgr_Start_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JmgrServer).Start(ctx, req.(*RunRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Jmgr_Stop_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RunRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JmgrServer).Stop(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Jmgr_Stop_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JmgrServer).Stop(ctx, req.(*RunRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Jmgr_Restart_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RunRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JmgrServer).Restart(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Jmgr_Restart_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JmgrServer).Restart(ctx, req.(*RunRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Jmgr_Create_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RunRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JmgrServer).Create(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Jmgr_Create_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JmgrServer).Create(ctx, req.(*RunRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Jmgr_Snapshot_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RunRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(JmgrServer).Snapshot(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Jmgr_Snapshot_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(JmgrServer).Snapshot(ctx, req.(*RunRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Jmgr_ServiceDesc is the grpc.ServiceDesc for Jmgr service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Jmgr_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "jmgr.v1.Jmgr",
+	HandlerType: (*JmgrServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "List",
+			Handler:    _Jmgr_List_Handler,
+		},
+		{
+			MethodName: "GetJail",
+			Handler:    _Jmgr_GetJail_Handler,
+		},
+		{
+			MethodName: "Refresh",
+			Handler:    _Jmgr_Refresh_Handler,
+		},
+		{
+			MethodName: "Start",
+			Handler:    _Jmgr_Start_Handler,
+		},
+		{
+			MethodName: "Stop",
+			Handler:    _Jmgr_Stop_Handler,
+		},
+		{
+			MethodName: "Restart",
+			Handler:    _Jmgr_Restart_Handler,
+		},
+		{
+			MethodName: "Create",
+			Handler:    _Jmgr_Create_Handler,
+		},
+		{
+			MethodName: "Snapshot",
+			Handler:    _Jmgr_Snapshot_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "jmgr.proto",
+}
@@ -0,0 +1,172 @@
+// Package client is the Go API of jmgr, for programs that embed jmgr (exporters, web UIs, tests) instead of
+// running the binary. It talks JSON-RPC to 'jmgr daemon' on its Unix socket, see jmgr(8) DAEMON. The jails,
+// usage samples and updates are the types of package jmgr/inventory, the same the daemon serves.
+//
+//	c, err := client.Dial(client.DefaultSocket)
+//	if err != nil {
+//		log.Fatalln(err)
+//	}
+//	defer c.Close()
+//	jails, err := c.List()
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"strings"
+	"time"
+
+	"jmgr/inventory"
+)
+
+// DefaultSocket of 'jmgr daemon'
+const DefaultSocket = "/var/run/jmgr.sock"
+
+// Jail as in 'jmgr -json jails', use Runs() for the state
+type Jail = inventory.Jail
+
+// Reply the output and exit status of a subcommand run by the daemon
+type Reply struct {
+	Stdout string `json:"stdout"`
+	Stderr string `json:"stderr"`
+	Exit   int    `json:"exit"`
+}
+
+// Err is nil if the subcommand succeeded, else the error with its stderr and exit status, see jmgr(8) EXIT STATUS
+func (r Reply) Err() error {
+	if r.Exit == 0 {
+		return nil
+	}
+	return &ExitError{Code: r.Exit, Message: strings.TrimSpace(r.Stderr)}
+}
+
+// ExitError a subcommand that failed, Code is the jmgr exit status
+type ExitError struct {
+	Code    int
+	Message string
+}
+
+func (e *ExitError) Error() string { return e.Message }
+
+// Client of 'jmgr daemon'
+type Client struct {
+	rpc *rpc.Client
+}
+
+// Dial the daemon on socket, root only
+func Dial(socket string) (*Client, error) {
+
+	c, err := jsonrpc.Dial("unix", socket)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{rpc: c}, nil
+}
+
+// Close the connection
+func (c *Client) Close() error {
+	return c.rpc.Close()
+}
+
+// Config the config and all jails as 'jmgr -json config', decoded into v
+func (c *Client) Config(v any) error {
+
+	var raw json.RawMessage
+	if err := c.rpc.Call("Jmgr.Config", struct{}{}, &raw); err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
+
+// List all jails
+func (c *Client) List() ([]Jail, error) {
+
+	var jails []Jail
+	err := c.rpc.Call("Jmgr.List", struct{}{}, &jails)
+	return jails, err
+}
+
+// Jail one jail by name
+func (c *Client) Jail(name string) (Jail, error) {
+
+	var jail Jail
+	err := c.rpc.Call("Jmgr.Jail", name, &jail)
+	return jail, err
+}
+
+// Refresh the inventory of the daemon now
+func (c *Client) Refresh() error {
+
+	var ok bool
+	return c.rpc.Call("Jmgr.Refresh", struct{}{}, &ok)
+}
+
+// Sample the usage of a running jail at a time, see History
+type Sample = inventory.HistorySample
+
+// History the usage samples of the jails, all if none, in the last since, by jail. The daemon samples them every -history interval
+func (c *Client) History(since time.Duration, jails ...string) (map[string][]Sample, error) {
+
+	var history map[string][]Sample
+	err := c.rpc.Call("Jmgr.History", struct {
+		Jails []string      `json:"jails"`
+		Since time.Duration `json:"since"`
+	}{Jails: jails, Since: since}, &history)
+	return history, err
+}
+
+// Update the pending updates of a jail, see Updates
+type Update = inventory.UpdateCheck
+
+// Updates the pending updates by jail of the last check of the daemon, empty if it runs without -metrics
+func (c *Client) Updates() (map[string]Update, error) {
+
+	var updates map[string]Update
+	err := c.rpc.Call("Jmgr.Updates", struct{}{}, &updates)
+	return updates, err
+}
+
+// Start the jails
+func (c *Client) Start(names ...string) (Reply, error) {
+	return c.call("Jmgr.Start", names)
+}
+
+// Stop the jails
+func (c *Client) Stop(names ...string) (Reply, error) {
+	return c.call("Jmgr.Stop", names)
+}
+
+// Create a jail with the arguments of 'jmgr create', ex: "-v", "14.2-RELEASE", "web", "10.0.0.5"
+func (c *Client) Create(args ...string) (Reply, error) {
+	return c.call("Jmgr.Create", args)
+}
+
+// Snapshot with the arguments of 'jmgr snapshot', ex: "web" or "@db", "nightly"
+func (c *Client) Snapshot(args ...string) (Reply, error) {
+	return c.call("Jmgr.Snapshot", args)
+}
+
+// Run one of the subcommands the daemon runs, ex: "restart", "web". yes answers 'yes' to the questions
+func (c *Client) Run(yes bool, args ...string) (Reply, error) {
+
+	var reply Reply
+	err := c.rpc.Call("Jmgr.Run", struct {
+		Args []string `json:"args"`
+		Json bool     `json:"json"`
+		Yes  bool     `json:"yes"`
+	}{Args: args, Yes: yes}, &reply)
+	return reply, errors.Join(err, reply.Err())
+}
+
+// call a method with the arguments, the error is the failed call or subcommand
+func (c *Client) call(method string, args []string) (Reply, error) {
+
+	var reply Reply
+	if args == nil {
+		args = []string{}
+	}
+	err := c.rpc.Call(method, args, &reply)
+	return reply, errors.Join(err, reply.Err())
+}
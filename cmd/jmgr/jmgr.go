@@ -0,0 +1,13097 @@
+/*-
+ * Copyright (c) 2024 peter@libassi.se
+ *
+ * SPDX-License-Identifier: BSD-2-Clause
+ */
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"compress/gzip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"log/syslog"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"os/exec"
+	"os/signal"
+	"os/user"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"text/template"
+	"time"
+
+	"jmgr"
+	"jmgr/api/jmgrv1"
+	"jmgr/config"
+	"jmgr/inventory"
+	"jmgr/lifecycle"
+	"jmgr/system"
+	"jmgr/templates"
+	"jmgr/zfs"
+
+	"github.com/janeczku/go-spinner"
+	"github.com/jlaffaye/ftp"
+	"golang.org/x/term"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/yaml.v2"
+	"net/url"
+)
+
+const version = "0.003" // 2025-01-30
+
+// the jail inventory, shared with the Go client, see package jmgr/inventory
+type (
+	Jail          = inventory.Jail
+	JailMeta      = inventory.JailMeta
+	JailSettings  = inventory.JailSettings
+	RelUpgrade    = inventory.RelUpgrade
+	Healthcheck   = inventory.Healthcheck
+	HealthStatus  = inventory.HealthStatus
+	UpdateCheck   = inventory.UpdateCheck
+	HistorySample = inventory.HistorySample
+)
+
+// the settings of jmgr.conf, see package jmgr/config
+type (
+	PkgRepo = config.PkgRepo
+	Webhook = config.Webhook
+	Alert   = config.Alert
+)
+
+// JailProc a process in a jail, from ps(1), see package jmgr/lifecycle
+type JailProc = lifecycle.Proc
+
+// struct for a new jail
+type NewJail struct {
+	Name        string
+	Hostname    string
+	IP          string
+	Iface       string
+	InheritIP   bool
+	IPconf      string
+	Dataset     string
+	Path        string
+	ConfigPath  string
+	Template    string // template name, empty for the default JailConfTemplate
+	PkgCache    bool   // nullfs mount PkgCacheDir on the jail /var/cache/pkg
+	PkgRepos    []string
+	Nice        int           // niceness, template <Nice>
+	StopTimeout time.Duration // template <StopTimeout>, in seconds
+}
+
+// desired state of a jail in a manifest, see 'jmgr apply'
+type JailSpec struct {
+	Name     string   `yaml:"Name" json:"name"`
+	Release  string   `yaml:"Release,omitempty" json:"release,omitempty"`   // FreeBSD release, default host release
+	Image    string   `yaml:"Image,omitempty" json:"image,omitempty"`       // published image, instead of Release
+	IP       string   `yaml:"IP,omitempty" json:"ip,omitempty"`             // default DNS or inherit
+	Iface    string   `yaml:"Iface,omitempty" json:"iface,omitempty"`       // default JailIface
+	Hostname string   `yaml:"Hostname,omitempty" json:"hostname,omitempty"` // default jail name
+	Template string   `yaml:"Template,omitempty" json:"template,omitempty"` // default JailConfTemplate
+	Tags     []string `yaml:"Tags,omitempty" json:"tags,omitempty"`
+	PkgRepos []string `yaml:"PkgRepos,omitempty" json:"pkgrepos,omitempty"` // repos from PkgRepos in jmgr.conf
+	State    string   `yaml:"State,omitempty" json:"state,omitempty"`       // running or stopped, default unmanaged
+	Boot     *bool    `yaml:"Boot,omitempty" json:"boot,omitempty"`         // start on boot, default unmanaged
+}
+
+// manifest of jails, see 'jmgr apply'
+type Manifest struct {
+	Jails []JailSpec `yaml:"Jails"`
+}
+
+// result of 'jmgr apply' on a host
+type ApplyResult struct {
+	Host    string   `json:"host"`
+	Changes []string `json:"changes"`
+	Errors  []string `json:"errors"`
+}
+
+// result of audit for one jail
+type AuditResult struct {
+	Name       string   `json:"name"`
+	Status     string   `json:"status"` // ok, vulnerable, skipped or failed
+	Vulnerable []string `json:"vulnerable"`
+	Error      string   `json:"error"`
+}
+
+// result of update -all for one jail
+type UpdateResult struct {
+	Name     string `json:"name"`
+	Snapshot string `json:"snapshot"`
+	Status   string `json:"status"`
+	Error    string `json:"error"`
+}
+
+// state of 'update rel -all', kept in UpdateCacheDir/rel-<release>.yml until all jails are upgraded
+type RelState struct {
+	Release string                  `yaml:"Release" json:"release"`
+	Jails   map[string]UpdateResult `yaml:"Jails" json:"jails"`
+}
+
+// jls(8) json struct
+type JailSlices struct {
+	JailSlices []Jail `json:"jail"`
+}
+
+// jls(8) json struct
+type Jls struct {
+	Version string     `json:"__version"`
+	Jls     JailSlices `json:"jail-information"`
+}
+
+// Config struct for jmgr, the settings of jmgr.conf (package jmgr/config) and the jails
+type Jmgr struct {
+	config.Settings `yaml:",inline"`
+	useZFS          bool            // set by jmgrInit()
+	badConfig       bool            // set by jmgrInit() to indicate that we do not have resources to create or clone new jails
+	Jails           []Jail          `json:"jails"`
+	Problems        []ConfigProblem `json:"problems,omitempty"` // set by jmgrInit(), why the config is not ok
+}
+
+// ConfigProblem a setting jmgrInit() found not ok, see badConfig
+type ConfigProblem struct {
+	Key     string `json:"key"`
+	Value   string `json:"value"`
+	Problem string `json:"problem"`
+}
+
+// interface for register and consume providers of type CLI methods
+// Provider is a subcommand. Run must parse its flags, from a newFlagSet, before doing anything else,
+// 'jmgr help <subcommand>' is Run(subcommand -h).
+type Provider interface {
+	Run([]string)
+	Name() string     // primary subcommand name
+	Synopsis() string // one line description
+	Usage() string    // syntax, one line per form
+}
+
+// newFlagSet return a flag set for subcommand name, -h prints the subcommand synopsis, usage and flags
+func newFlagSet(name string) *flag.FlagSet {
+
+	fset := flag.NewFlagSet(name, flag.ExitOnError)
+	fset.Usage = func() {
+		if collectFlags != nil {
+			fset.VisitAll(func(f *flag.Flag) { *collectFlags = append(*collectFlags, f) })
+			runtime.Goexit()
+		}
+		if completeOut != nil {
+			fset.VisitAll(func(f *flag.Flag) {
+				if strings.HasPrefix("-"+f.Name, completeWord) {
+					fmt.Fprintln(completeOut, "-"+f.Name)
+				}
+			})
+			return
+		}
+		w := fset.Output()
+		if p, ok := SubC[name]; ok {
+			fmt.Fprintf(w, "jmgr %s: %s\n\nUsage:\n", p.Name(), p.Synopsis())
+			for _, line := range strings.Split(p.Usage(), "\n") {
+				if strings.HasPrefix(line, " ") {
+					fmt.Fprintln(w, "  "+line) // continuation, ex: filters
+				} else {
+					fmt.Fprintln(w, "  jmgr "+line)
+				}
+			}
+		}
+		var n int
+		fset.VisitAll(func(*flag.Flag) { n++ })
+		if n > 0 {
+			fmt.Fprintln(w, "\nOptions:")
+			fset.PrintDefaults()
+		}
+	}
+	return fset
+}
+
+// subcommand -> provider map
+var SubC = map[string]Provider{
+	"config":   ShowStruct{},
+	"enable":   EnableDisable{},
+	"disable":  EnableDisable{},
+	"enter":    Enter{},
+	"exec":     Exec{},
+	"start":    StartStop{},
+	"stop":     StartStop{},
+	"restart":  StartStop{},
+	"create":   Create{},
+	"clone":    Clone{},
+	"jails":    ShowJails{},
+	"jail":     ShowJails{},
+	"runs":     ShowJails{},
+	"destroy":  Destroy{},
+	"gc":       GC{},
+	"adopt":    Adopt{},
+	"update":   Update{},
+	"version":  Version{},
+	"snapshot": Snapshot{},
+	"rollback": Rollback{},
+	"subc":     ProviderMap{},
+
+	"sync-definitions": SyncDefinitions{},
+	"standby":          Standby{},
+	"tag":              Tag{},
+	"depend":           Tag{},
+	"publish":          Publish{},
+	"promote":          Promote{},
+	"pull":             Pull{},
+	"apply":            Apply{},
+	"fleet":            Fleet{},
+	"pkg":              Pkg{},
+	"audit":            Audit{},
+	"pkgcache":         PkgCache{},
+	"repo":             Tag{},
+	"verify":           Verify{},
+	"hold":             Maintenance{},
+	"window":           Maintenance{},
+	"protect":          Protect{},
+	"unprotect":        Protect{},
+	"completion":       Completion{},
+	"gen-man":          GenMan{},
+	"describe":         Describe{},
+	"init":             Init{},
+	"daemon":           Daemon{},
+	"serve":            Serve{},
+	"check":            Check{},
+	"status":           Status{},
+	"service":          Service{},
+	"limits":           Limits{},
+	"cpuset":           Cpuset{},
+	"stats":            Stats{},
+	"top":              Top{},
+	"ps":               Ps{},
+	"sockets":          Sockets{},
+	"health":           Health{},
+	"nice":             Nice{},
+	"lifecycle":        Lifecycle{},
+}
+
+//
+// Main
+//
+
+func main() {
+
+	log.SetFlags(0) // Remove time and date
+
+	// global options, before the subcommand
+	args := os.Args[1:]
+	switch strings.ToLower(os.Getenv("JMGR_ASSUME_YES")) {
+	case "1", "y", "yes", "true":
+		assumeYes = true
+	}
+	profile = os.Getenv("JMGR_PROFILE")
+	daemonSocket = os.Getenv("JMGR_SOCKET")
+	for len(args) > 0 {
+		if args[0] == "-json" || args[0] == "--json" {
+			jsonMode()
+		} else if args[0] == "-json-errors" || args[0] == "--json-errors" {
+			jsonStderr = true
+			log.SetOutput(jsonErrors{})
+		} else if args[0] == "-y" || args[0] == "--yes" {
+			assumeYes = true
+		} else if (args[0] == "-profile" || args[0] == "--profile") && len(args) > 1 {
+			profile = args[1]
+			args = args[1:]
+		} else if name, ok := strings.CutPrefix(args[0], "-profile="); ok {
+			profile = name
+		} else if (args[0] == "-socket" || args[0] == "--socket") && len(args) > 1 {
+			daemonSocket = args[1]
+			args = args[1:]
+		} else if socket, ok := strings.CutPrefix(args[0], "-socket="); ok {
+			daemonSocket = socket
+		} else {
+			break
+		}
+		args = args[1:]
+	}
+	os.Args = append([]string{os.Args[0]}, args...)
+
+	if len(args) == 0 {
+		var s ShowJails
+		s.Run([]string{"jails"})
+		printJSON(nil)
+
+	} else {
+		// thin client, the daemon runs it
+		if len(daemonSocket) > 0 && slices.Contains(daemonCommands, args[0]) {
+			daemonRun(daemonSocket, args)
+		}
+
+		// Try if 'subcommand' resolve to a method that is registered as a provider, if so call it.
+		v := reflect.ValueOf(SubC[args[0]])
+		if v.IsValid() {
+			SubC[args[0]].Run(args)
+			printJSON(nil)
+			exit(0)
+		}
+
+		// hidden, called by the completion scripts
+		if args[0] == "__complete" {
+			complete(args[1:])
+			exit(0)
+		}
+
+		// help for a subcommand, see newFlagSet
+		if args[0] == "help" && len(args) == 2 && SubC[args[1]] != nil {
+			SubC[args[1]].Run([]string{args[1], "-h"})
+			exit(0)
+		}
+
+		// ok, maybe args[0] is a 'jail name', if so call showJails
+		cfg := jmgrInit()
+		if cfg.exist(args[0]) {
+			showJail(&cfg, []string{"jail", args[0]})
+			printJSON(nil)
+			exit(0)
+		}
+
+		// git style external subcommand, jmgr-'subcommand' in PATH
+		if path := pluginPath(args[0]); len(path) > 0 {
+			runPlugin(&cfg, path, args[1:])
+		}
+		// We still here?
+		help()
+	}
+}
+
+// JSONResult is the envelope printed on stdout by 'jmgr -json <subcommand>', error is set when the subcommand failed
+type JSONResult struct {
+	Result any    `json:"result"`
+	Error  string `json:"error,omitempty"`
+	Code   int    `json:"code,omitempty"`
+	Hint   string `json:"hint,omitempty"`
+}
+
+var jsonOutput bool     // global -json given
+var jsonStderr bool     // global -json-errors given
+var jsonPrinted bool    // the subcommand printed its result
+var jsonResult any      // partial result printed with the error, ex: the per jail results of update -all
+var jsonStdout *os.File // stdout, while os.Stdout is redirected to stderr
+
+// jsonErrors turns log output (fatal) into a JSON error envelope on stdout and/or an error object on stderr
+type jsonErrors struct{}
+
+func (jsonErrors) Write(p []byte) (int, error) {
+	msg := strings.TrimSpace(string(p))
+	if jsonOutput {
+		b, _ := json.Marshal(JSONResult{Result: jsonResult, Error: msg, Code: exitCode, Hint: exitHint})
+		jsonStdout.Write(append(b, '\n'))
+		jsonPrinted = true
+	}
+	if jsonStderr {
+		b, _ := json.Marshal(JmgrError{Code: exitCode, Message: msg, Hint: exitHint})
+		os.Stderr.Write(append(b, '\n'))
+	}
+	return len(p), nil
+}
+
+// jsonMode reserve stdout for the JSON envelope, ordinary output and prompts are sent to stderr
+func jsonMode() {
+	jsonOutput = true
+	jsonStdout = os.Stdout
+	os.Stdout = os.Stderr
+	log.SetOutput(jsonErrors{})
+}
+
+// Exit codes, automation can tell a missing jail from a failed zfs command
+const (
+	exitError    = 1 // any other failure
+	exitUsage    = 2 // unknown subcommand or bad arguments
+	exitNotFound = 3 // jail, snapshot, file or dataset not found
+	exitNeedRoot = 4 // needs root
+	exitConflict = 5 // already exist, not in the right state, on hold, child jail...
+	exitCommand  = 6 // an external command (zfs, jail, pkg, freebsd-update...) failed
+	exitConfig   = 7 // the jmgr config is not ok
+	exitTimeout  = 8 // a jail did not stop or become healthy in time
+)
+
+var exitCode = exitError // exit code, set by fatal
+var exitHint string      // hint printed with the error, set by fatal
+
+// JmgrError is an error with its exit code and a hint, the error object of -json-errors
+type JmgrError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Hint    string `json:"hint,omitempty"`
+	err     error  // wrapped error, ex: *exec.ExitError
+}
+
+func (e *JmgrError) Error() string { return e.Message }
+func (e *JmgrError) Unwrap() error { return e.err }
+
+// jmgrError return an error with exit code and hint
+func jmgrError(code int, message string, hint string) error {
+	return &JmgrError{Code: code, Message: message, Hint: hint}
+}
+
+// errNeedRoot, the hint is for all the need root errors
+var errNeedRoot = jmgrError(exitNeedRoot, "need root capabilites to perform this task", hintRoot)
+
+const hintRoot = "Run jmgr as root, ex: doas jmgr ..."
+
+// errNoJail return the not found error for jail name
+func errNoJail(name string) error {
+	return jmgrError(exitNotFound, "Jail "+name+" does not exist.", "See the jails with: jmgr jails")
+}
+
+// fatal print err and exit with the exit code of err, 1 if err is not a JmgrError
+func fatal(err error) {
+	var e *JmgrError
+	var se *system.Error
+	if errors.As(err, &e) {
+		exitCode, exitHint = e.Code, e.Hint
+	} else if errors.As(err, &se) {
+		exitCode = exitCommand
+	}
+	log.Print(err.Error())
+	exit(exitCode)
+}
+
+// printJSON print result in the JSON envelope, once. No-op if global -json is not given
+func printJSON(result any) {
+	if !jsonOutput || jsonPrinted {
+		return
+	}
+	b, err := json.Marshal(JSONResult{Result: result})
+	if err != nil {
+		b, _ = json.Marshal(JSONResult{Error: "Problem with JSON encode: " + err.Error()})
+	}
+	jsonStdout.Write(append(b, '\n'))
+	jsonPrinted = true
+}
+
+//
+// CLI methods, adheres to the 'Provider' interface
+//
+
+// Version emits current software version
+type Version struct{}
+
+func (Version) Name() string     { return "version" }
+func (Version) Synopsis() string { return "Print the jmgr version." }
+func (Version) Usage() string    { return "version" }
+
+func (Version) Run(args []string) {
+
+	newFlagSet(args[0]).Parse(args[1:])
+	fmt.Println(version)
+	printJSON(map[string]string{"version": version})
+}
+
+// Show info from the Jmgr struct
+type ShowStruct struct{}
+
+func (ShowStruct) Name() string     { return "config" }
+func (ShowStruct) Synopsis() string { return "Print the jmgr configuration." }
+func (ShowStruct) Usage() string {
+	return `config [-json]
+config -check
+config migrate [-n]
+config diff
+config get 'key'
+config set 'key' 'value'`
+}
+
+func (ShowStruct) Run(args []string) {
+
+	jflag := newFlagSet(args[0])
+	wantJson := jflag.Bool("json", false, "Print config and all jails in JSON format")
+	check := jflag.Bool("check", false, "Validate the config file, report all problems with line numbers, exit 7 if any.")
+	dryRun := jflag.Bool("n", false, "Dry run, only report what config migrate would change.")
+	jflag.Parse(args[1:])
+
+	if *check {
+		file := config.File()
+		problems, err := configCheckFile(file)
+		if err != nil {
+			fatal(err)
+		}
+		if len(problems) > 0 {
+			jsonResult = problems
+			for _, p := range problems {
+				fmt.Println(p)
+			}
+			fatal(jmgrError(exitConfig, file+": "+strconv.Itoa(len(problems))+" problem(s)", ""))
+		}
+		if jsonOutput {
+			printJSON([]string{})
+			return
+		}
+		fmt.Println(file + ": OK")
+		return
+	}
+
+	switch jflag.Arg(0) {
+	case "get":
+		if jflag.NArg() != 2 {
+			fatal(jmgrError(exitUsage, "Syntax: jmgr config get 'key'", "See: jmgr help"))
+		}
+		key, err := config.Key(jflag.Arg(1))
+		if err != nil {
+			fatal(err)
+		}
+		cfg := jmgrInit()
+		value := reflect.ValueOf(cfg).FieldByName(key).String()
+		if jsonOutput {
+			printJSON(map[string]string{key: value})
+			return
+		}
+		fmt.Println(value)
+		return
+
+	case "set":
+		if jflag.NArg() != 3 {
+			fatal(jmgrError(exitUsage, "Syntax: jmgr config set 'key' 'value'", "See: jmgr help"))
+		}
+		key, err := config.Key(jflag.Arg(1))
+		if err != nil {
+			fatal(err)
+		}
+		err = config.Set(config.File(), key, jflag.Arg(2))
+		if err != nil {
+			fatal(err)
+		}
+		if env, ok := os.LookupEnv(config.EnvName(key)); ok {
+			fmt.Println("Note: " + key + " is overridden by env " + config.EnvName(key) + "=" + env)
+		}
+		for _, f := range config.ConfDFiles(config.File()) {
+			var part config.Settings
+			if b, err := os.ReadFile(f); err == nil && config.Decode(b, config.Format(f), &part, false) == nil {
+				if value := reflect.ValueOf(part).FieldByName(key).String(); len(value) > 0 {
+					fmt.Println("Note: " + key + " is overridden by " + f + ": " + value)
+				}
+			}
+		}
+		if jsonOutput {
+			printJSON(map[string]string{key: jflag.Arg(2)})
+		}
+		return
+
+	case "diff":
+		cfg := jmgrInit()
+		diff := config.Diff(cfg.Settings)
+		if jsonOutput {
+			printJSON(map[string]any{"settings": diff, "problems": append([]ConfigProblem{}, cfg.Problems...)})
+			return
+		}
+		keys := make([]string, 0, len(diff))
+		for key := range diff {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+		for _, key := range keys {
+			value := fmt.Sprintf("%v", diff[key])
+			if _, ok := os.LookupEnv(config.EnvName(key)); ok {
+				value += " (env " + config.EnvName(key) + ")"
+			}
+			fmt.Fprintf(w, "%s\t=\t%s\n", key, value)
+		}
+		w.Flush()
+		for _, p := range cfg.Problems {
+			fmt.Println("Problem: " + p.Key + " = " + p.Value + ": " + p.Problem)
+		}
+		return
+
+	case "migrate":
+		jflag.Parse(jflag.Args()[1:])
+		for _, file := range append([]string{config.File()}, config.ConfDFiles(config.File())...) {
+			changes, err := config.MigrateFile(file, *dryRun)
+			if err != nil {
+				fatal(err)
+			}
+			for _, c := range changes {
+				fmt.Println(file + ": " + c)
+			}
+		}
+		return
+
+	case "":
+	default:
+		fatal(jmgrError(exitUsage, "Unknown: config "+jflag.Arg(0)+".", "See: jmgr help config"))
+	}
+
+	var cfg Jmgr = jmgrInit()
+
+	if *wantJson {
+		b, err := json.Marshal(cfg)
+		if err != nil {
+			fatal(fmt.Errorf("Problem with JSON encode: %w", err))
+		}
+		fmt.Println(string(b[:]))
+
+	} else if jsonOutput {
+		printJSON(cfg)
+
+	} else {
+		var rowsFmt string = "%s\t=\t%s\n"
+		var rowsFmtBool string = "%s\t=\t%v\n"
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 1, ' ', 0)
+		values := reflect.ValueOf(cfg.Settings)
+		types := values.Type()
+
+		for i := 0; i < values.NumField(); i++ {
+			if types.Field(i).Type.Kind() == reflect.Bool {
+				fmt.Fprintf(w, rowsFmtBool, types.Field(i).Name, values.Field(i))
+			} else if _, ok := os.LookupEnv(config.EnvName(types.Field(i).Name)); ok && types.Field(i).Type.Kind() == reflect.String {
+				fmt.Fprintf(w, rowsFmt, types.Field(i).Name, values.Field(i).String()+" (env "+config.EnvName(types.Field(i).Name)+")")
+			} else {
+				fmt.Fprintf(w, rowsFmt, types.Field(i).Name, values.Field(i))
+			}
+			if types.Field(i).Name == "ZFSdataSet" {
+				fmt.Fprintf(w, rowsFmtBool, "useZFS", cfg.useZFS)
+				fmt.Fprintf(w, rowsFmtBool, "badConfig", cfg.badConfig)
+			}
+		}
+		w.Flush()
+		for _, p := range cfg.Problems {
+			fmt.Println("Problem: " + p.Key + " = " + p.Value + ": " + p.Problem)
+		}
+	}
+}
+
+// EnableDisable enable or disable a jail to start on boot
+type EnableDisable struct{}
+
+func (EnableDisable) Name() string { return "enable" }
+func (EnableDisable) Synopsis() string {
+	return "Enable or disable a jail to start on boot, and the boot order."
+}
+func (EnableDisable) Usage() string {
+	return `enable [-before 'jail name' | -after 'jail name'] [-parallel yes|no] [-reverse-stop yes|no] 'jail name'
+enable -order | -parallel yes|no | -reverse-stop yes|no
+disable 'jail name'`
+}
+
+func (EnableDisable) Run(args []string) {
+
+	var sysrc string = "/usr/sbin/sysrc"
+	fset := newFlagSet(args[0])
+	before := fset.String("before", "", "Start the jail at boot before this enabled jail, moved if it is enabled.")
+	after := fset.String("after", "", "Start the jail at boot after this enabled jail, moved if it is enabled.")
+	order := fset.Bool("order", false, "Sort jail_list by Priority and the jail dependencies.")
+	parallel := fset.String("parallel", "", "Set jail_parallel_start, yes or no: start the jails at boot in parallel.")
+	reverseStop := fset.String("reverse-stop", "", "Set jail_reverse_stop, yes or no: stop the jails at shutdown in reverse order.")
+	fset.Parse(args[1:])
+	args = append([]string{args[0]}, fset.Args()...)
+
+	rcVars := map[string]string{"jail_parallel_start": *parallel, "jail_reverse_stop": *reverseStop}
+	for _, v := range []string{*parallel, *reverseStop} {
+		if len(v) > 0 && v != "yes" && v != "no" {
+			help()
+		}
+	}
+	if (len(*before) > 0 && len(*after) > 0) || (args[0] == "disable" && (len(*before) > 0 || len(*after) > 0 || *order || len(*parallel) > 0 || len(*reverseStop) > 0)) {
+		help()
+	}
+
+	// boot order only
+	if args[0] == "enable" && (*order || len(*parallel) > 0 || len(*reverseStop) > 0) && len(args) == 1 {
+		if len(*before) > 0 || len(*after) > 0 {
+			help()
+		}
+		if system.NotRoot() {
+			fatal(errNeedRoot)
+		}
+		cfg := jmgrInit()
+		if *order {
+			list, err := cfg.bootOrder(jailList())
+			if err != nil {
+				fatal(err)
+			}
+			if err := setJailList(list); err != nil {
+				fatal(err)
+			}
+		}
+		if err := setRcVars(rcVars); err != nil {
+			fatal(err)
+		}
+		printBootOrder(&cfg)
+		return
+	}
+	if *order {
+		help()
+	}
+
+	cfg, jail, err := verifyArgs(2, 1, true, true, args)
+	if err != nil {
+		fatal(err)
+	}
+
+	if len(jail.Parent) > 0 {
+		fatal(jmgrError(exitConflict, "Jail "+jail.Name+" is a child of "+jail.Parent+", Can't continue.", "Manage it from the parent jail "+jail.Parent+"."))
+	}
+	target := *before + *after
+	if len(target) > 0 && (target == jail.Name || !slices.Contains(jailList(), target)) {
+		fatal(jmgrError(exitConflict, "Jail "+target+" is not enabled, "+jail.Name+" can't start before or after it.", "Enable it first with: jmgr enable "+target))
+	}
+
+	switch args[0] {
+
+	case "enable":
+
+		if jail.OnBoot == "No" {
+
+			b, err := system.Run(sysrc, []string{"-n", "jail_enable"})
+			if err != nil {
+				fatal(fmt.Errorf("EnableDisable(): %w", err))
+			}
+
+			if string(bytes.TrimRight(b, "\n")) != "YES" {
+				_, err := system.Run(sysrc, []string{"jail_enable=YES"})
+				if err != nil {
+					fatal(fmt.Errorf("EnableDisable(): %w", err))
+				}
+			}
+
+			if len(target) == 0 {
+				_, err = system.Run(sysrc, []string{"jail_list+=" + jail.Name})
+				if err != nil {
+					fatal(fmt.Errorf("EnableDisable(): %w", err))
+				}
+			}
+			event("enable", jail.Name, "")
+		}
+
+		// move or insert the jail next to another enabled jail
+		if len(target) > 0 {
+			list := slices.DeleteFunc(jailList(), func(name string) bool { return name == jail.Name })
+			i := slices.Index(list, target)
+			if len(*after) > 0 {
+				i++
+			}
+			if err := setJailList(slices.Insert(list, i, jail.Name)); err != nil {
+				fatal(err)
+			}
+		}
+		if err := setRcVars(rcVars); err != nil {
+			fatal(err)
+		}
+		if len(target) > 0 || len(*parallel) > 0 || len(*reverseStop) > 0 {
+			printBootOrder(cfg)
+		}
+
+	case "disable":
+
+		if jail.OnBoot == "Yes" {
+
+			_, err := system.Run(sysrc, []string{"jail_list-=" + jail.Name})
+			if err != nil {
+				fatal(fmt.Errorf("EnableDisable(): %w", err))
+			}
+			event("disable", jail.Name, "")
+		}
+	}
+}
+
+// jailList return the jails in jail_list of rc.conf, in boot order
+func jailList() []string {
+
+	b, _ := system.Run("/usr/sbin/sysrc", []string{"-n", "jail_list"}) // fails when jail_list is not set
+	return strings.Fields(string(b))
+}
+
+// setJailList set jail_list in rc.conf to the jails, in that order
+func setJailList(list []string) error {
+
+	if _, err := system.Run("/usr/sbin/sysrc", []string{"jail_list=" + strings.Join(list, " ")}); err != nil {
+		return fmt.Errorf("setJailList() %w", err)
+	}
+	return nil
+}
+
+// setRcVars set the rc.conf variables with a yes or no value to YES or NO, the others are left
+func setRcVars(vars map[string]string) error {
+
+	for name, value := range vars {
+		if len(value) == 0 {
+			continue
+		}
+		if _, err := system.Run("/usr/sbin/sysrc", []string{name + "=" + strings.ToUpper(value)}); err != nil {
+			return fmt.Errorf("setRcVars() %w", err)
+		}
+	}
+	return nil
+}
+
+// bootOrder return the jails of list by Priority and after the jails they depend on, the jails jmgr does not know last
+func (cfg *Jmgr) bootOrder(list []string) ([]string, error) {
+
+	var jails []Jail
+	var unknown []string
+	for _, name := range list {
+		if cfg.exist(name) {
+			jails = append(jails, cfg.jail(name))
+		} else {
+			unknown = append(unknown, name)
+		}
+	}
+	slices.SortStableFunc(jails, func(a, b Jail) int { return a.Settings.Priority - b.Settings.Priority })
+	ordered, err := cfg.startOrder(jails)
+	if err != nil {
+		return nil, jmgrError(exitConfig, err.Error(), "See the dependencies with: jmgr depend 'jail name'")
+	}
+	var order []string
+	for _, jail := range ordered {
+		order = append(order, jail.Name)
+	}
+	return append(order, unknown...), nil
+}
+
+// printBootOrder print jail_list and the parallel start and reverse stop settings, with a warning for a jail that starts
+// before a jail it depends on
+func printBootOrder(cfg *Jmgr) {
+
+	list := jailList()
+	parallel, _ := system.Run("/usr/sbin/sysrc", []string{"-n", "jail_parallel_start"})
+	reverse, _ := system.Run("/usr/sbin/sysrc", []string{"-n", "jail_reverse_stop"})
+	rcVar := func(b []byte) string {
+		if v := strings.TrimSpace(string(b)); len(v) > 0 {
+			return v
+		}
+		return "NO"
+	}
+	printJSON(map[string]any{"jail_list": list, "jail_parallel_start": rcVar(parallel), "jail_reverse_stop": rcVar(reverse)})
+	if jsonOutput {
+		return
+	}
+	fmt.Println("Boot order:", strings.Join(list, " "))
+	fmt.Println("Parallel start:", rcVar(parallel), " Reverse stop:", rcVar(reverse))
+	for i, name := range list {
+		for _, dep := range cfg.jail(name).Meta.Depends {
+			if slices.Contains(list[i+1:], dep) {
+				fmt.Println("Warning: " + name + " starts before " + dep + " it depends on, see: jmgr enable -order")
+			}
+		}
+	}
+}
+
+// Enter jexec into a running jail, optional 'user name'
+type Enter struct{}
+
+func (Enter) Name() string     { return "enter" }
+func (Enter) Synopsis() string { return "Run a shell (jexec) in a running jail." }
+func (Enter) Usage() string    { return "enter 'jail name' [ 'user name' ]" }
+
+func (Enter) Run(args []string) {
+
+	fset := newFlagSet(args[0])
+	fset.Parse(args[1:])
+	args = append([]string{args[0]}, fset.Args()...)
+
+	cfg, jail, err := verifyArgs(2, 1, true, true, args)
+	if err != nil {
+		fatal(err)
+	}
+
+	if !jail.Runs() {
+		fatal(jmgrError(exitConflict, "Jail "+jail.Name+" is not running.", "Start it with: jmgr start "+jail.Name))
+
+	}
+
+	if len(args) >= 3 {
+		cfg.JailUser = args[2]
+	}
+
+	cmd := exec.Command(system.Tool("/usr/sbin/jexec"), []string{jail.Name, "login", "-f", cfg.JailUser}...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Stdin = os.Stdin
+
+	err = cmd.Run()
+	if err != nil {
+		fatal(fmt.Errorf("Command finished with error: %w", err))
+	}
+}
+
+// Exec run a command in a running jail, for scripts, with the exit status of the command
+type Exec struct{}
+
+func (Exec) Name() string { return "exec" }
+func (Exec) Synopsis() string {
+	return "Run a command (jexec) in a running jail, or in many in parallel."
+}
+func (Exec) Usage() string {
+	return `exec [-u 'user name'] 'jail name' [--] 'command' [ 'argument' ... ]
+exec [-f] [-u 'user name'] [-parallel 'n'] -all | -tag 'tag' | 'glob' | '@tag' [--] 'command' [ 'argument' ... ]`
+}
+
+// ExecResult the result of exec in one jail, see execAll
+type ExecResult struct {
+	Jail   string `json:"jail"`
+	Exit   int    `json:"exit"` // exit status of the command, 128 + signal if killed
+	Output string `json:"output"`
+	Error  string `json:"error,omitempty"` // jexec failed
+}
+
+func (Exec) Run(args []string) {
+
+	fset := newFlagSet(args[0])
+	user := fset.String("u", "", "Run the command as the user in the jail, default root.")
+	all := fset.Bool("all", false, "Run the command in all running jails.")
+	tag := fset.String("tag", "", "Run the command in the running jails with the tag.")
+	parallel := fset.Int("parallel", 4, "Number of jails to run the command in parallel (with -all, -tag, glob or @tag).")
+	force := fset.Bool("f", false, "Run the command in the jails selected without prompting for confirmation.")
+	fset.Parse(args[1:])
+	args = append([]string{args[0]}, fset.Args()...)
+
+	// many jails, all positional args are the command
+	if *all || len(*tag) > 0 || (len(args) > 1 && isPattern(args[1])) {
+		var selection []string
+		if !*all && len(*tag) == 0 {
+			selection, args = []string{args[1]}, append(args[:1], args[2:]...)
+		}
+		if len(args) > 1 && args[1] == "--" {
+			args = append(args[:1], args[2:]...)
+		}
+		if len(args) < 2 || *parallel < 1 {
+			help()
+		}
+		execAll(selection, *tag, *user, args[1:], *parallel, *force)
+		return
+	}
+
+	if len(args) > 2 && args[2] == "--" {
+		args = append(args[:2], args[3:]...)
+	}
+
+	_, jail, err := verifyArgs(3, 1, true, true, args)
+	if err != nil {
+		fatal(err)
+	}
+
+	if !jail.Runs() {
+		fatal(jmgrError(exitConflict, "Jail "+jail.Name+" is not running.", "Start it with: jmgr start "+jail.Name))
+	}
+
+	jexecArgs := []string{jail.Name}
+	if len(*user) > 0 {
+		jexecArgs = []string{"-U", *user, jail.Name}
+	}
+	// not a login shell, stdin, stdout and stderr are passed as is
+	err = system.RunStdin("/usr/sbin/jexec", append(jexecArgs, args[2:]...))
+	if code, ok := exitStatus(err); ok {
+		exit(code)
+	}
+	if err != nil {
+		fatal(err)
+	}
+}
+
+// exitStatus return the exit status of a command that ran and failed, 128 + the signal number if it was killed
+func exitStatus(err error) (int, bool) {
+
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return 0, false
+	}
+	if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return 128 + int(status.Signal()), true
+	}
+	return exitErr.ExitCode(), true
+}
+
+// execAll run the command in the running jails selected, all if none is selected and no tag, 'parallel' jails at a time.
+// Each output line is prefixed with the jail name, the exit status per jail is printed at the end
+func execAll(selection []string, tag string, user string, command []string, parallel int, force bool) {
+
+	if system.NotRoot() {
+		fatal(errNeedRoot)
+	}
+	cfg := jmgrInit()
+	names, _, err := cfg.selectJails(selection, tag)
+	if err != nil {
+		fatal(err)
+	}
+	if len(selection) == 0 && len(tag) == 0 {
+		for _, jail := range cfg.Jails {
+			if len(jail.Parent) == 0 {
+				names = append(names, jail.Name)
+			}
+		}
+	}
+	var jails []string
+	width := 0
+	for _, name := range names {
+		if jail := cfg.jail(name); !jail.Runs() {
+			fmt.Println(name + " is not running, skipped.")
+			continue
+		}
+		jails = append(jails, name)
+		width = max(width, len(name))
+	}
+	if len(jails) == 0 {
+		fatal(jmgrError(exitConflict, "No running jail selected.", "See the running jails with: jmgr runs"))
+	}
+	if !force {
+		confirmSelection("exec '"+strings.Join(command, " ")+"' in", jails)
+	}
+
+	var mu sync.Mutex // one line at a time
+	results := make([]ExecResult, len(jails))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, name := range jails {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			jexecArgs := []string{name}
+			if len(user) > 0 {
+				jexecArgs = []string{"-U", user, name}
+			}
+			cmd := exec.Command(system.Tool("/usr/sbin/jexec"), append(jexecArgs, command...)...)
+			pr, pw := io.Pipe()
+			cmd.Stdout, cmd.Stderr = pw, pw
+			var output strings.Builder
+			done := make(chan struct{})
+			go func() {
+				// bufio.Reader, a bufio.Scanner stops at a line longer than its buffer
+				reader := bufio.NewReader(pr)
+				for {
+					line, err := reader.ReadString('\n')
+					if len(line) > 0 {
+						line = strings.TrimSuffix(line, "\n")
+						mu.Lock()
+						fmt.Printf("%-*s | %s\n", width, name, line)
+						mu.Unlock()
+						output.WriteString(line + "\n")
+					}
+					if err != nil {
+						break
+					}
+				}
+				close(done)
+			}()
+			err := cmd.Run()
+			pw.Close()
+			<-done
+
+			results[i] = ExecResult{Jail: name, Output: output.String()}
+			if code, ok := exitStatus(err); ok {
+				results[i].Exit = code
+			} else if err != nil {
+				results[i].Exit = -1
+				results[i].Error = err.Error()
+			}
+		}(i, name)
+	}
+	wg.Wait()
+
+	var failed int
+	var rowsFmt string = "%s\t%s\t%s\n"
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, rowsFmt, "Jail", "Exit", "Error")
+	for _, r := range results {
+		if r.Exit != 0 {
+			failed++
+		}
+		exit := strconv.Itoa(r.Exit)
+		if r.Exit < 0 {
+			exit = "-"
+		}
+		fmt.Fprintf(w, rowsFmt, r.Jail, exit, r.Error)
+	}
+	w.Flush()
+
+	if failed > 0 {
+		jsonResult = results
+		fatal(jmgrError(exitCommand, strconv.Itoa(failed)+" of "+strconv.Itoa(len(results))+" jails failed.", ""))
+	}
+	printJSON(results)
+}
+
+// Create a new thick jail
+type Create struct{}
+
+func (Create) Name() string     { return "create" }
+func (Create) Synopsis() string { return "Create a new jail from a FreeBSD release or an image." }
+func (Create) Usage() string {
+	return `create [-f] [-v 'FreeBSD Release'] [-hostname 'host name'] [-t 'template'] [-pkgcache] [-repo 'repo,repo2'] 'jail name' [ 'IP address' [ 'interface name' ] ]
+create -l
+create [-f] [-insecure] -image 'registry/name:tag' [create options] 'jail name' [ 'IP address' [ 'interface name' ] ]`
+}
+
+func (Create) Run(args []string) {
+
+	cset := newFlagSet(args[0])
+	force := cset.Bool("f", false, "Create jail without prompting for confirmation.")
+	version := cset.String("v", "", "Freebsd Release, ex: 13.4-RELEASE, if not defined jail is created with host release.")
+	list := cset.Bool("l", false, "List available releases")
+	hostname := cset.String("hostname", "", "Jail hostname, if not defined the jail name is used.")
+	template := cset.String("t", "", "Named jail.conf template in JailTemplateDir, if not defined JailConfTemplate is used.")
+	image := cset.String("image", "", "Create the jail from a published image, registry/name:tag. See 'jmgr pull'.")
+	insecure := cset.Bool("insecure", false, "Use http instead of https to the image registry.")
+	pkgCache := cset.Bool("pkgcache", false, "Mount the shared pkg cache PkgCacheDir on /var/cache/pkg in the jail.")
+	repos := cset.String("repo", "", "Comma separated pkg repositories from PkgRepos in jmgr.conf, written to the jail.")
+
+	cset.Parse(args[1:])
+	args = cset.Args()
+
+	if *list {
+		err := printRel()
+		if err != nil {
+			fatal(fmt.Errorf("Update() get avaliable releases failed: %w", err))
+		}
+		exit(0)
+	}
+
+	cfg, _, err := verifyArgs(1, 0, true, false, args)
+	if err != nil {
+		fatal(err)
+	}
+
+	if cfg.badConfig {
+		fatal(jmgrError(exitConfig, "jmgr config is not ok. run 'jmgr config' to see the problems reported.", "Run: jmgr config -check"))
+	}
+
+	// defaults from the jail settings in jmgr.conf
+	settings := cfg.JailSettings[args[0]]
+	if len(*hostname) == 0 {
+		*hostname = settings.Hostname
+	}
+	if len(*template) == 0 {
+		*template = settings.Template
+	}
+	if len(*repos) == 0 {
+		*repos = strings.Join(settings.PkgRepos, ",")
+	}
+
+	// check if we can create a new jail with user input
+	newJail, err := cfg.newJailCheck(force, *hostname, args)
+	if err != nil {
+		fatal(err)
+	}
+	newJail.Nice = settings.Nice
+	newJail.StopTimeout, _ = time.ParseDuration(settings.StopTimeout) // checked by the config check
+
+	newJail.Template = *template
+	if _, err := cfg.templateFile(newJail.Template); err != nil {
+		fatal(err)
+	}
+
+	newJail.PkgCache = *pkgCache
+	if newJail.PkgCache && len(cfg.PkgCacheDir) == 0 {
+		fatal(jmgrError(exitConfig, "PkgCacheDir is not set in the jmgr config, can't use -pkgcache.", "Set it with: jmgr config set PkgCacheDir 'directory'"))
+	}
+
+	if len(*repos) > 0 {
+		newJail.PkgRepos = strings.Split(*repos, ",")
+		for _, repo := range newJail.PkgRepos {
+			if _, ok := cfg.PkgRepos[repo]; !ok {
+				fatal(jmgrError(exitConfig, "No pkg repository "+repo+" in PkgRepos in the jmgr config.", "See the repositories with: jmgr config get PkgRepos"))
+			}
+		}
+	}
+
+	var osVersion string
+	var ref *imageRef
+	var manifest ociImageManifest
+	if len(*image) > 0 {
+		ref, err = parseImageRef(*image, *insecure)
+		if err != nil {
+			fatal(err)
+		}
+		var config ociImageConfig
+		manifest, config, err = ref.pull(cfg.imageStore())
+		if err != nil {
+			fatal(err)
+		}
+		osVersion = config.Config.Labels["org.jmgr.osversion"]
+		fmt.Println("Image:", ref.String())
+	} else if len(*version) > 1 {
+		osVersion = *version
+	} else {
+		osVersion, err = hostVersion()
+		if err != nil {
+			fatal(fmt.Errorf("Create(): %w", err))
+		}
+	}
+
+	// Good to go.
+	fmt.Println("Jail Name:", newJail.Name)
+	fmt.Println("Jail Hostname:", newJail.Hostname)
+	if newJail.InheritIP {
+		fmt.Println("Jail IP: Inherit host IP address")
+	} else {
+		fmt.Println("Jail IP:", newJail.IP)
+		fmt.Println("Jail Iface:", newJail.Iface)
+	}
+	fmt.Println("os version: ", osVersion)
+	if len(newJail.Template) > 0 {
+		fmt.Println("Jail template:", newJail.Template)
+	}
+
+	if !*force {
+		askExitOnNo("Create this jail(yes/No)? ")
+	}
+
+	osBits := cfg.OsMediaDir + "/" + osVersion + ".txz"
+	unpack := []string{osBits}
+
+	if _, err := os.Stat(cfg.OsMediaDir); os.IsNotExist(err) {
+		// create media dir
+		err := os.MkdirAll(cfg.OsMediaDir, 0755)
+		if err != nil {
+			fatal(fmt.Errorf("Error creating directory %w", err))
+		}
+	}
+
+	if ref != nil {
+		// image layers replace the OS bits
+		unpack = nil
+		for _, l := range manifest.Layers {
+			unpack = append(unpack, blobFile(cfg.imageStore(), l.Digest))
+		}
+	} else if f, err := os.Stat(osBits); os.IsNotExist(err) || f.Size() < 1 {
+
+		hw, err := machine()
+		if err != nil {
+			fatal(err)
+		}
+		bitsURL := cfg.OsUrlPrefix + "/" + hw + "/" + osVersion + "/base.txz"
+
+		// Download
+		err = download(bitsURL, osBits, "Downloading FreeBSD: "+bitsURL)
+		if err != nil {
+			fatal(fmt.Errorf("Create() fetch %w", err))
+		}
+		fmt.Println("/ Download completed.")
+	}
+
+	if cfg.useZFS {
+		// create Jail dataset
+		_, err = system.Run("/sbin/zfs", []string{"create", newJail.Dataset})
+		if err != nil {
+			fatal(fmt.Errorf("Create dataset: %w", err))
+		}
+
+		// get path for new dataset
+		newJail.Path, err = zfs.Mountpoint(newJail.Dataset)
+		if err != nil {
+			fatal(fmt.Errorf("Create,zfs list %w", err))
+		}
+
+		//Just checking
+		if len(newJail.Path) == 0 || len(newJail.Dataset) == 0 {
+			fatal(jmgrError(exitError, "There is a problem. have dataset: "+newJail.Dataset+", filesystem: "+newJail.Path, ""))
+		}
+	} else {
+		newJail.Path = cfg.JailsHome + "/" + newJail.Name
+		err := os.MkdirAll(newJail.Path, 0755)
+		if err != nil {
+			fatal(fmt.Errorf("Error creating directory %w", err))
+		}
+	}
+
+	// unpack OS bits (or image layers in order) to new jail dir
+	for _, bits := range unpack {
+		err = untar(bits, newJail.Path)
+		if err != nil {
+			fatal(fmt.Errorf("Create() unpack %w", err))
+		}
+		fmt.Println("/ Unpack completed.")
+	}
+
+	err = cfg.createJailConfig(newJail)
+	if err != nil {
+		fatal(err)
+	}
+
+	err = cfg.writePkgRepos(newJail.Path, newJail.PkgRepos)
+	if err != nil {
+		fatal(err)
+	}
+
+	// run postinstall script
+	err = runHook("PostInstall", cfg.PostInstall, []string{newJail.Name, newJail.Path, newJail.ConfigPath})
+	if err != nil {
+		fatal(err)
+	}
+
+	// packages and boot from the jail settings in jmgr.conf
+	if len(settings.Pkgs) > 0 {
+		fmt.Println("Install packages:", strings.Join(settings.Pkgs, " "))
+		out, err := jmgrCmd(append([]string{"pkg", "-f", newJail.Name, "install", "-y"}, settings.Pkgs...)...)
+		fmt.Print(out)
+		if err != nil {
+			fatal(fmt.Errorf("Create() install packages: %w", err))
+		}
+	}
+	if settings.Boot != nil && *settings.Boot {
+		if out, err := jmgrCmd("enable", newJail.Name); err != nil {
+			fatal(jmgrError(exitCommand, "Create() enable: "+strings.TrimSpace(out), ""))
+		}
+	}
+	fmt.Println("Jail", newJail.Name, "created.")
+	event("create", newJail.Name, osVersion)
+}
+
+// Clone a existing jail to a new jail
+type Clone struct{}
+
+func (Clone) Name() string     { return "clone" }
+func (Clone) Synopsis() string { return "Clone a jail, locally or to a remote jmgr host." }
+func (Clone) Usage() string {
+	return `clone [-f] [-hostname 'host name'] [-t 'template'] [-keepid] [-clearlogs] [-live] [-thin] [-pool 'ZFS dataset' | -dest 'directory'] 'from jail name' 'new jail name' [ 'new jail IP address' [ 'new jail interface' ] ]
+clone -n 'count' [clone options] 'from jail name' 'new jail name prefix'
+clone -host 'user@host' [-f] [-start] [-hostname 'host name'] [-t 'template'] [-keepid] [-clearlogs] 'from jail name' 'new jail name' [ 'IP address' [ 'interface' ] ]`
+}
+
+func (Clone) Run(args []string) {
+
+	fset := newFlagSet(args[0])
+	force := fset.Bool("f", false, "Clone jail without prompting for confirmation.")
+	hostname := fset.String("hostname", "", "New jail hostname, if not defined the new jail name is used.")
+	template := fset.String("t", "", "Named jail.conf template in JailTemplateDir, if not defined the source jail template is used.")
+	keepId := fset.Bool("keepid", false, "Keep the source jail identity (hostname, SSH host keys, hostid) in the new jail.")
+	clearLogs := fset.Bool("clearlogs", false, "Clear the log files in the new jail /var/log.")
+	live := fset.Bool("live", false, "Clone a running non-ZFS jail without stopping it, copy twice with rsync.")
+	pool := fset.String("pool", "", "ZFS dataset, ex: ssd/jails, where the new jail dataset is created instead of ZFSdataSet.")
+	dest := fset.String("dest", "", "Directory, ex: /ssd/jails, where the new jail is created (no ZFS) instead of JailsHome.")
+	thin := fset.Bool("thin", false, "Thin clone, 'zfs clone' of a snapshot of the source jail. See 'jmgr promote'.")
+	count := fset.Int("n", 0, "Bulk clone, create 'n' jails named <new jail name>1..n with IP addresses from JailIPPool.")
+	host := fset.String("host", "", "Clone to a remote jmgr host, user@host, with zfs send over ssh.")
+	start := fset.Bool("start", false, "Start the new jail on the remote host (with -host).")
+	fset.Parse(args[1:])
+	args = fset.Args()
+
+	if len(*host) > 0 {
+		remoteClone(*host, *force, *start, *hostname, *template, *keepId, *clearLogs, args)
+		return
+	}
+
+	if *count > 0 {
+		bulkClone(fset, *count, *force, *hostname, args)
+		return
+	}
+	cfg, oldJail, err := verifyArgs(2, 0, true, true, args)
+	if err != nil {
+		fatal(err)
+	}
+
+	if cfg.badConfig {
+		fatal(jmgrError(exitConfig, "jmgr config is not ok. run 'jmgr config' to see the problems reported.", "Run: jmgr config -check"))
+	}
+
+	newJail, err := cfg.newJailCheck(force, *hostname, args[1:])
+	if err != nil {
+		fatal(err)
+	}
+
+	newJail.Template = oldJail.Meta.Template
+	if len(*template) > 0 {
+		newJail.Template = *template
+	}
+	newJail.PkgCache = oldJail.Meta.PkgCache && len(cfg.PkgCacheDir) > 0
+	newJail.PkgRepos = oldJail.Meta.PkgRepos
+	if _, err := cfg.templateFile(newJail.Template); err != nil {
+		fatal(err)
+	}
+
+	err = newJail.destination(*pool, *dest)
+	if err != nil {
+		fatal(err)
+	}
+
+	if *thin {
+		if len(oldJail.Dataset) == 0 || len(newJail.Dataset) == 0 {
+			fatal(jmgrError(exitConflict, "Thin clone needs ZFS, both for "+oldJail.Name+" and the new jail.", "Clone without -thin."))
+		}
+		if strings.SplitN(oldJail.Dataset, "/", 2)[0] != strings.SplitN(newJail.Dataset, "/", 2)[0] {
+			fatal(jmgrError(exitUsage, "Thin clone must be in the same pool as "+oldJail.Dataset+".", "Use a -pool dataset in the same pool, or clone without -thin."))
+		}
+	}
+
+	// Good to go.
+	fmt.Println("Jail Name:", newJail.Name)
+	fmt.Println("Jail Hostname:", newJail.Hostname)
+	if newJail.InheritIP {
+		fmt.Println("Jail IP: Inherit host IP address")
+	} else {
+		fmt.Println("Jail IP:", newJail.IP)
+		fmt.Println("Jail Iface:", newJail.Iface)
+	}
+	if len(*pool) > 0 {
+		fmt.Println("Jail Dataset:", newJail.Dataset)
+	}
+	if len(*dest) > 0 {
+		fmt.Println("Jail Path:", newJail.Path)
+	}
+
+	if !*force {
+		askExitOnNo("Clone this jail from " + oldJail.Name + " (yes/No)? ")
+	}
+
+	// PreClone/PostClone hooks: source jail name, source path, new jail name
+	hookArgs := []string{oldJail.Name, oldJail.Path, newJail.Name}
+
+	if *thin {
+
+		err = runHook("PreClone", cfg.PreClone, hookArgs)
+		if err != nil {
+			fatal(err)
+		}
+		snapshot, err := zfs.Snapshot(oldJail.Dataset)
+		if err != nil {
+			fatal(fmt.Errorf("Clone, %w", err))
+		}
+		err = runHook("PostClone", cfg.PostClone, hookArgs)
+		if err != nil {
+			fatal(err)
+		}
+
+		// the snapshot is the origin of the new dataset and is kept until 'jmgr promote'
+		_, err = system.Run("/sbin/zfs", []string{"clone", snapshot, newJail.Dataset})
+		if err != nil {
+			fatal(fmt.Errorf("Clone, zfs clone %w", err))
+		}
+
+		newJail.Path, err = zfs.Mountpoint(newJail.Dataset)
+		if err != nil {
+			fatal(fmt.Errorf("Clone, %w", err))
+		}
+
+	} else if len(oldJail.Dataset) > 0 && len(newJail.Dataset) > 0 {
+
+		// need a fresh snapshot from source jail
+		err = runHook("PreClone", cfg.PreClone, hookArgs)
+		if err != nil {
+			fatal(err)
+		}
+		snapshot, err := zfs.Snapshot(oldJail.Dataset)
+		if err != nil {
+			fatal(fmt.Errorf("Clone, %w", err))
+		}
+		err = runHook("PostClone", cfg.PostClone, hookArgs)
+		if err != nil {
+			fatal(err)
+		}
+		// zfs 'clone', also across pools
+		err = clone(true, snapshot, newJail.Dataset)
+		if err != nil {
+			fatal(fmt.Errorf("Clone, clone() %w", err))
+		}
+
+		// get newJail snapshot
+		b, err := system.Run("/sbin/zfs", []string{"list", "-H", "-t", "snapshot", "-o", "name", newJail.Dataset})
+		if err != nil {
+			fatal(fmt.Errorf("zfs list %w", err))
+		}
+
+		snaps := strings.Split(string(b[:]), "\n")
+		if len(snaps) > 1 {
+			newJailSnapshot := snaps[0]
+
+			// promote new jail snapshot
+			_, err = system.Run("/sbin/zfs", []string{"rollback", newJailSnapshot})
+			if err != nil {
+				fatal(fmt.Errorf("zfs rollback %w", err))
+			}
+
+			// destroy new jail snapshot
+			_, err = system.Run("/sbin/zfs", []string{"destroy", newJailSnapshot})
+			if err != nil {
+				fatal(fmt.Errorf("zfs destroy %w", err))
+			}
+		} else {
+			fatal(jmgrError(exitError, "Problem with new jail snapshot, can't continue", ""))
+		}
+
+		newJail.Path, err = zfs.Mountpoint(newJail.Dataset)
+		if err != nil {
+			fatal(fmt.Errorf("Clone, %w", err))
+		}
+
+	} else if len(oldJail.Dataset) > 0 {
+
+		// ZFS source to a directory, copy from the snapshot
+		err = runHook("PreClone", cfg.PreClone, hookArgs)
+		if err != nil {
+			fatal(err)
+		}
+		snapshot, err := zfs.Snapshot(oldJail.Dataset)
+		if err != nil {
+			fatal(fmt.Errorf("Clone, %w", err))
+		}
+		err = runHook("PostClone", cfg.PostClone, hookArgs)
+		if err != nil {
+			fatal(err)
+		}
+
+		err = cfg.newJailDir(&newJail)
+		if err != nil {
+			fatal(err)
+		}
+
+		err = clone(false, oldJail.Path+"/.zfs/snapshot/"+strings.SplitN(snapshot, "@", 2)[1], newJail.Path)
+		if err != nil {
+			fatal(err)
+		}
+
+	} else if *live && oldJail.Runs() {
+
+		err := cfg.newJailDir(&newJail)
+		if err != nil {
+			fatal(err)
+		}
+
+		// first pass with the jail running, second pass (only changes) in the PreClone/PostClone window
+		err = rsync(oldJail.Path, newJail.Path)
+		if err != nil {
+			fatal(err)
+		}
+		err = runHook("PreClone", cfg.PreClone, hookArgs)
+		if err != nil {
+			fatal(err)
+		}
+		err = rsync(oldJail.Path, newJail.Path)
+		if err != nil {
+			fatal(err)
+		}
+		err = runHook("PostClone", cfg.PostClone, hookArgs)
+		if err != nil {
+			fatal(err)
+		}
+
+	} else {
+
+		if oldJail.Runs() {
+			if !*force {
+				askExitOnNo("Ok to stop " + oldJail.Name + " (yes/No)? ")
+			}
+			err = runHook("PreClone", cfg.PreClone, hookArgs)
+			if err != nil {
+				fatal(err)
+			}
+			err = startstop("stop", oldJail)
+			if err != nil {
+				fatal(err)
+			}
+		}
+
+		err := cfg.newJailDir(&newJail)
+		if err != nil {
+			fatal(err)
+		}
+
+		err = clone(false, oldJail.Path, newJail.Path)
+		if err != nil {
+			fatal(err)
+		}
+
+		err = runHook("PostClone", cfg.PostClone, hookArgs)
+		if err != nil {
+			fatal(err)
+		}
+	}
+
+	err = cfg.createJailConfig(newJail)
+	if err != nil {
+		fatal(err)
+	}
+
+	// the clone should not claim to be the source jail on the network
+	if !*keepId || *clearLogs {
+		err = resetIdentity(newJail.Path, newJail.Hostname, *keepId, *clearLogs)
+		if err != nil {
+			fatal(err)
+		}
+	}
+
+	fmt.Println("Jail", newJail.Name, "created.")
+	event("create", newJail.Name, "clone of "+oldJail.Name)
+	cloneReport(oldJail.Path, oldJail.Dataset, newJail.Path, newJail.Dataset)
+}
+
+// List existing jails
+type ShowJails struct{}
+
+func (ShowJails) Name() string     { return "jails" }
+func (ShowJails) Synopsis() string { return "List all or running jails, or show a jail." }
+func (ShowJails) Usage() string {
+	return `jails [-q] [-watch 'seconds'] [-wide | -width 'n'] [-no-pager] [-eol-only] [-format 'Go template'] [-o csv|yaml] [-columns 'jid,name,...'] [filters] [-sort name|jid|used]
+runs [-q] [-watch 'seconds'] [-wide | -width 'n'] [-no-pager] [-eol-only] [-format 'Go template'] [-o csv|yaml] [-columns 'jid,name,...'] [filters] [-sort name|jid|used]
+jail [-format 'Go template'] 'jail name'
+ filters: [-state running|stopped] [-boot yes|no] [-release 'FreeBSD Release'] [-name 'glob'] [-tag 'tag']`
+}
+
+func (ShowJails) Run(args []string) {
+
+	fset := newFlagSet(args[0])
+	eolOnly := fset.Bool("eol-only", false, "Only list jails with an end-of-life or soon end-of-life FreeBSD release.")
+	format := fset.String("format", "", "Print each jail with a Go template, ex: '{{.Name}} {{.Ipv4}}'.")
+	output := fset.String("o", "", "Output format for jails and runs: csv or yaml.")
+	columns := fset.String("columns", "", "Columns and their order for jails and runs, ex: jid,name,ipv4,dataset,used,uptime.")
+	state := fset.String("state", "", "Only list running or stopped jails.")
+	boot := fset.String("boot", "", "Only list jails started at boot (yes) or not (no).")
+	release := fset.String("release", "", "Only list jails with this OS version, ex: 14.2-RELEASE.")
+	name := fset.String("name", "", "Only list jails with a name matching the glob, ex: 'web*'.")
+	tag := fset.String("tag", "", "Only list jails with the tag.")
+	sortBy := fset.String("sort", "", "Sort jails by name, jid or used.")
+	quiet := fset.Bool("q", false, "Only print the jail names, one per line.")
+	watch := fset.Int("watch", 0, "Redraw the listing every n seconds.")
+	wide := fset.Bool("wide", false, "Wide listing with all columns, whatever the terminal width.")
+	width := fset.Int("width", 0, "Terminal width for the listing, default the width of the terminal.")
+	noPager := fset.Bool("no-pager", false, "Do not page a listing longer than the terminal through $PAGER.")
+	fset.Parse(args[1:])
+	args = append([]string{args[0]}, fset.Args()...)
+
+	var cfg Jmgr = jmgrInit()
+
+	if len(args) == 1 {
+		opts := ListOptions{Runs: args[0] == "runs", EolOnly: *eolOnly, Format: *format, Output: *output, Columns: *columns,
+			State: *state, Boot: *boot, Release: *release, Name: *name, Tag: strings.TrimPrefix(*tag, "@"), Sort: *sortBy, Quiet: *quiet,
+			Wide: *wide, Width: *width, NoPager: *noPager}
+		if *watch > 0 && (args[0] == "runs" || args[0] == "jails") {
+			if jsonOutput || *quiet || len(*output) > 0 {
+				fatal(jmgrError(exitUsage, "-watch can't be combined with -json, -q or -o.", "See: jmgr help"))
+			}
+			watchJails(opts, time.Duration(*watch)*time.Second)
+		}
+		if args[0] == "runs" || args[0] == "jails" {
+			reportJails(opts, &cfg)
+		}
+	}
+
+	if len(args) == 2 {
+		if len(*format) > 0 && !jsonOutput {
+			if !cfg.exist(args[1]) {
+				fatal(errNoJail(args[1]))
+			}
+			formatJails(*format, []Jail{cfg.jail(args[1])})
+			return
+		}
+		showJail(&cfg, args)
+	}
+}
+
+// ListOptions select and format the jails for 'jmgr jails' and 'jmgr runs'
+type ListOptions struct {
+	Runs    bool            // only running jails
+	EolOnly bool            // only jails with an (soon) end-of-life release
+	Format  string          // Go template, see formatJails
+	Output  string          // csv or yaml, see exportJails
+	Columns string          // comma separated jailColumns keys, empty for the default columns
+	State   string          // running or stopped
+	Boot    string          // yes or no, jail_list in rc.conf
+	Release string          // OS version, ex: 14.2-RELEASE
+	Name    string          // glob, see filepath.Match
+	Tag     string          // tag without the @
+	Sort    string          // name, jid or used, default is the order of jail.conf
+	Quiet   bool            // only the names
+	Wide    bool            // all columns, ignore the terminal width
+	Width   int             // terminal width, 0 for the width of the terminal
+	NoPager bool            // see page
+	changed map[string]bool // -watch, jails started or stopped since the previous refresh
+}
+
+// marker return the first character of a listing row, '*' for a jail that changed state in watch mode
+func (opts ListOptions) marker(jail Jail) string {
+
+	if opts.changed[jail.Name] {
+		return "*"
+	}
+	return " "
+}
+
+// watchJails redraw the listing every interval, jails started or stopped since the previous refresh are marked with '*'
+func watchJails(opts ListOptions, interval time.Duration) {
+
+	opts.NoPager = true
+	running := make(map[string]bool)
+	for first := true; ; first = false {
+		cfg := jmgrInit()
+		opts.changed = make(map[string]bool)
+		for _, jail := range cfg.Jails {
+			if was, ok := running[jail.Name]; ok && !first && was != (jail.Jid > 0) {
+				opts.changed[jail.Name] = true
+			}
+			running[jail.Name] = jail.Jid > 0
+		}
+
+		fmt.Print("\033[H\033[2J") // clear screen
+		fmt.Printf("Every %s, %s\n\n", interval, time.Now().Format("2006-01-02 15:04:05"))
+		reportJails(opts, &cfg)
+		time.Sleep(interval)
+	}
+}
+
+// match return true if the jail passes the filters in opts
+func (opts ListOptions) match(jail Jail) bool {
+
+	switch {
+	case opts.Runs && jail.Jid == 0:
+		return false
+	case opts.State == "running" && jail.Jid == 0, opts.State == "stopped" && jail.Jid > 0:
+		return false
+	case len(opts.Boot) > 0 && !strings.EqualFold(opts.Boot, jail.OnBoot):
+		return false
+	case len(opts.Release) > 0 && opts.Release != jail.OsVersion:
+		return false
+	case len(opts.Tag) > 0 && !slices.Contains(jail.Meta.Tags, opts.Tag):
+		return false
+	}
+	if len(opts.Name) > 0 {
+		if ok, _ := filepath.Match(opts.Name, jail.Name); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// check return an error for unknown filter or sort values
+func (opts ListOptions) check() error {
+
+	if _, err := filepath.Match(opts.Name, ""); err != nil {
+		return fmt.Errorf("bad -name %s: %w", opts.Name, err)
+	}
+	if !slices.Contains([]string{"", "running", "stopped"}, opts.State) {
+		return fmt.Errorf("unknown -state %s, use running or stopped", opts.State)
+	}
+	if !slices.Contains([]string{"", "yes", "no"}, strings.ToLower(opts.Boot)) {
+		return fmt.Errorf("unknown -boot %s, use yes or no", opts.Boot)
+	}
+	if !slices.Contains([]string{"", "name", "jid", "used"}, opts.Sort) {
+		return fmt.Errorf("unknown -sort %s, use name, jid or used", opts.Sort)
+	}
+	return nil
+}
+
+// jailColumn is a column of the jails listing, Key is used in YAML and -columns
+type jailColumn struct {
+	Key   string
+	Label string
+	Value func(Jail) string
+}
+
+// columns for -columns, the first 8 are the columns of the wide jails listing
+var jailColumns = []jailColumn{
+	{"jid", "Jid", func(j Jail) string { return strconv.Itoa(j.Jid) }},
+	{"name", "Name", func(j Jail) string { return j.Name }},
+	{"ipv4", "IP Address", func(j Jail) string { return j.Ipv4 }},
+	{"path", "Path", func(j Jail) string { return j.Path }},
+	{"config", "Config", func(j Jail) string { return j.ConfigPath }},
+	{"osversion", "OS Version", func(j Jail) string { return j.OsVersion }},
+	{"boot", "Boot", func(j Jail) string { return j.OnBoot }},
+	{"uptime", "Uptime", func(j Jail) string { return jailUptime(j) }},
+	{"hostname", "Hostname", func(j Jail) string { return j.Hostname }},
+	{"dataset", "ZFS Dataset", func(j Jail) string { return j.Dataset }},
+	{"used", "Used", func(j Jail) string { return zfs.Used(j.Dataset) }},
+	{"tags", "Tags", func(j Jail) string { return strings.Join(j.Meta.Tags, ",") }},
+	{"description", "Description", func(j Jail) string { return j.Meta.Description }},
+	{"health", "Health", func(j Jail) string {
+		if j.Health != nil {
+			return j.Health.Status
+		}
+		if j.Meta.Health == nil {
+			return ""
+		}
+		return jailHealth(j).Status
+	}},
+}
+
+// selectColumns return the jailColumns for the comma separated keys, in that order
+func selectColumns(keys string) ([]jailColumn, error) {
+
+	var columns []jailColumn
+	for _, key := range strings.Split(keys, ",") {
+		i := slices.IndexFunc(jailColumns, func(c jailColumn) bool { return c.Key == strings.TrimSpace(key) })
+		if i < 0 {
+			var known []string
+			for _, c := range jailColumns {
+				known = append(known, c.Key)
+			}
+			return nil, fmt.Errorf("unknown column %s, use: %s", key, strings.Join(known, ","))
+		}
+		columns = append(columns, jailColumns[i])
+	}
+	return columns, nil
+}
+
+// rctlUsage return the rctl(8) resource usage of a running jail, ex: memoryuse, cputime, pcpu. Empty if racct is not enabled
+func rctlUsage(name string) map[string]int64 {
+
+	usage := make(map[string]int64)
+	b, err := system.Run("/usr/bin/rctl", []string{"-u", "jail:" + name})
+	if err != nil {
+		return usage
+	}
+	for _, line := range strings.Fields(string(b)) {
+		if key, value, ok := strings.Cut(line, "="); ok {
+			if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+				usage[key] = n
+			}
+		}
+	}
+	return usage
+}
+
+// JailStats live resource usage of a running jail, -1 if unknown
+type JailStats struct {
+	Name   string  `json:"name"`
+	Jid    int     `json:"jid"`
+	Cpu    float64 `json:"cpu"`    // percent of one cpu
+	Memory int64   `json:"memory"` // resident bytes
+	Swap   int64   `json:"swap"`   // bytes
+	Procs  int64   `json:"procs"`
+	Files  int64   `json:"files"`  // open files
+	Read   int64   `json:"read"`   // disk read bytes per second
+	Write  int64   `json:"write"`  // disk write bytes per second
+	Source string  `json:"source"` // rctl, or ps if resource accounting is disabled
+}
+
+// jailStats return the usage from rctl(8), or from ps(1) without swap and open files if kern.racct.enable=0
+func jailStats(jail Jail) JailStats {
+
+	st := JailStats{Name: jail.Name, Jid: jail.Jid, Source: "rctl"}
+	if usage := rctlUsage(jail.Name); len(usage) > 0 {
+		st.Cpu = float64(usage["pcpu"])
+		st.Memory, st.Swap = usage["memoryuse"], usage["swapuse"]
+		st.Procs, st.Files = usage["maxproc"], usage["openfiles"]
+		st.Read, st.Write = usage["readbps"], usage["writebps"]
+		return st
+	}
+
+	st.Source, st.Swap, st.Files, st.Read, st.Write = "ps", -1, -1, -1, -1
+	b, err := system.Run("/bin/ps", []string{"-J", strconv.Itoa(jail.Jid), "-o", "pcpu=,rss="})
+	if err != nil {
+		return st
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		cpu, _ := strconv.ParseFloat(fields[0], 64)
+		rss, _ := strconv.ParseInt(fields[1], 10, 64)
+		st.Cpu += cpu
+		st.Memory += rss * 1024
+		st.Procs++
+	}
+	return st
+}
+
+// JailUsage usage versus limits of a jail, the rctl(8) usage of a running jail and the dataset, 0 if unknown or no limit
+type JailUsage struct {
+	Rctl         bool  `json:"rctl"`                   // resource accounting is enabled and the jail running
+	Memory       int64 `json:"memory"`                 // rctl memoryuse, bytes
+	MemoryMax    int64 `json:"memorymax,omitempty"`    // memoryuse limit
+	Cpu          int64 `json:"cpu"`                    // rctl pcpu, percent of one cpu
+	CpuMax       int64 `json:"cpumax,omitempty"`       // pcpu limit
+	Procs        int64 `json:"procs"`                  // rctl maxproc
+	ProcsMax     int64 `json:"procsmax,omitempty"`     // maxproc limit
+	DatasetUsed  int64 `json:"datasetused,omitempty"`  // bytes used by the dataset and its snapshots
+	DatasetQuota int64 `json:"datasetquota,omitempty"` // ZFS quota
+}
+
+// jailUsage return the usage and the limits of the jail
+func jailUsage(jail Jail) JailUsage {
+
+	u := JailUsage{
+		MemoryMax: limitAmount(jail.Limits(), "memoryuse"),
+		CpuMax:    limitAmount(jail.Limits(), "pcpu"),
+		ProcsMax:  limitAmount(jail.Limits(), "maxproc"),
+	}
+	if jail.Runs() {
+		if usage := rctlUsage(jail.Name); len(usage) > 0 {
+			u.Rctl = true
+			u.Memory, u.Cpu, u.Procs = usage["memoryuse"], usage["pcpu"], usage["maxproc"]
+		}
+	}
+	if len(jail.Dataset) > 0 {
+		if b, err := system.Run("/sbin/zfs", []string{"list", "-Hp", "-o", "used,quota", jail.Dataset}); err == nil {
+			if f := strings.Fields(string(b)); len(f) == 2 {
+				u.DatasetUsed, _ = strconv.ParseInt(f[0], 10, 64)
+				u.DatasetQuota, _ = strconv.ParseInt(f[1], 10, 64)
+			}
+		}
+	}
+	return u
+}
+
+// limitAmount return the amount of the last rctl rule of resource, ex: memoryuse:deny=4g is 4294967296. 0 if no rule
+func limitAmount(rules []string, resource string) int64 {
+
+	var amount int64
+	for _, rule := range rules {
+		if !strings.HasPrefix(rule, resource+":") {
+			continue
+		}
+		_, value, _ := strings.Cut(rule, "=")
+		value, _, _ = strings.Cut(value, "/")
+		mult := int64(1)
+		if i := strings.IndexAny(value, "kmgtKMGT"); i > 0 {
+			mult = 1 << (10 * (strings.IndexByte("kmgt", strings.ToLower(value)[i]) + 1))
+			value = value[:i]
+		}
+		if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+			amount = n * mult
+		}
+	}
+	return amount
+}
+
+// ofLimit return " of <limit> (n%)", empty if there is no limit
+func ofLimit(used int64, limit int64, format func(int64) string) string {
+
+	if limit <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(" of %s (%d%%)", format(limit), used*100/limit)
+}
+
+// fmtCount return n as a string, see ofLimit
+func fmtCount(n int64) string {
+	return strconv.FormatInt(n, 10)
+}
+
+// jailStarted return when the jail was started. The time recorded by jmgr start/restart if the jail still has that jid,
+// else the age of its oldest process. False if not running
+func jailStarted(jail Jail) (time.Time, bool) {
+
+	if jail.Jid == 0 {
+		return time.Time{}, false
+	}
+	if jail.Meta.StartedJid == jail.Jid {
+		if t, err := time.Parse(time.RFC3339, jail.Meta.Started); err == nil {
+			return t, true
+		}
+	}
+
+	b, err := system.Run("/bin/ps", []string{"-o", "etimes=", "-J", strconv.Itoa(jail.Jid)})
+	if err != nil {
+		return time.Time{}, false
+	}
+	// with security.bsd.see_other_uids=0 a regular user does not see the jail processes
+	secs := -1
+	for _, f := range strings.Fields(string(b)) {
+		if n, err := strconv.Atoi(f); err == nil && n > secs {
+			secs = n
+		}
+	}
+	if secs < 0 {
+		return time.Time{}, false
+	}
+	return time.Now().Add(-time.Duration(secs) * time.Second).Truncate(time.Second), true
+}
+
+// jailUptime return how long the jail is running, ex: 3d4h5m. Empty if not running, unknown if it can't be determined
+func jailUptime(jail Jail) string {
+
+	started, ok := jailStarted(jail)
+	if !ok {
+		if jail.Jid > 0 {
+			return "unknown"
+		}
+		return ""
+	}
+	d := time.Since(started)
+	days := int(d.Hours()) / 24
+	d -= time.Duration(days) * 24 * time.Hour
+	if days > 0 {
+		return fmt.Sprintf("%dd%dh%dm", days, int(d.Hours()), int(d.Minutes())%60)
+	}
+	return fmt.Sprintf("%dh%dm", int(d.Hours()), int(d.Minutes())%60)
+}
+
+// recordStart store the start time and jid of a jail started by jmgr in its metadata, or clear it and store the stop time when stopped
+// in the JailMetaDir of the profile and environment of the caller
+func recordStart(jail *Jail) {
+
+	cfg := jmgrSettings()
+
+	meta, err := cfg.readMeta(jail.Name)
+	if err != nil {
+		return
+	}
+	meta.Started, meta.StartedJid, meta.Stopped = "", 0, time.Now().Format(time.RFC3339)
+	if jail.Jid > 0 {
+		meta.Started, meta.StartedJid, meta.Stopped = time.Now().Format(time.RFC3339), jail.Jid, ""
+	}
+	if cfg.writeMeta(jail.Name, meta) == nil {
+		jail.Meta.Started, jail.Meta.StartedJid, jail.Meta.Stopped = meta.Started, meta.StartedJid, meta.Stopped
+	}
+}
+
+// exportJails print the jails as CSV (header with the column labels) or as a YAML list (column keys)
+func exportJails(output string, columns []jailColumn, jails []Jail) {
+
+	switch output {
+	case "csv":
+		w := csv.NewWriter(os.Stdout)
+		var row []string
+		for _, c := range columns {
+			row = append(row, c.Label)
+		}
+		w.Write(row)
+		for _, jail := range jails {
+			row = row[:0]
+			for _, c := range columns {
+				row = append(row, c.Value(jail))
+			}
+			w.Write(row)
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			fatal(err)
+		}
+
+	case "yaml":
+		list := []yaml.MapSlice{}
+		for _, jail := range jails {
+			var m yaml.MapSlice
+			for _, c := range columns {
+				m = append(m, yaml.MapItem{Key: c.Key, Value: c.Value(jail)})
+			}
+			list = append(list, m)
+		}
+		b, err := yaml.Marshal(list)
+		if err != nil {
+			fatal(err)
+		}
+		fmt.Print(string(b))
+	}
+}
+
+// page write b to stdout, through $PAGER (default less, with LESS=FRX like git) if stdout is a terminal and b is
+// longer than the terminal
+func page(b []byte, noPager bool) {
+
+	fd := int(os.Stdout.Fd())
+	_, height, err := term.GetSize(fd)
+	if noPager || err != nil || !term.IsTerminal(fd) || bytes.Count(b, []byte("\n")) < height {
+		os.Stdout.Write(b)
+		return
+	}
+
+	pager := os.Getenv("PAGER")
+	if len(pager) == 0 {
+		pager = "less"
+	}
+	cmd := exec.Command(system.Tool("/bin/sh"), "-c", pager)
+	cmd.Stdin = bytes.NewReader(b)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = os.Environ()
+	if _, ok := os.LookupEnv("LESS"); !ok {
+		cmd.Env = append(cmd.Env, "LESS=FRX")
+	}
+	if err := cmd.Start(); err != nil {
+		os.Stdout.Write(b)
+		return
+	}
+	cmd.Wait()
+}
+
+// formatJails print each jail with the Go template format (text/template), one line per jail
+func formatJails(format string, jails []Jail) {
+
+	tmpl, err := template.New("format").Parse(format)
+	if err != nil {
+		fatal(fmt.Errorf("Bad -format: %w", err))
+	}
+	for _, jail := range jails {
+		err = tmpl.Execute(os.Stdout, jail)
+		if err != nil {
+			fatal(fmt.Errorf("Bad -format: %w", err))
+		}
+		fmt.Println()
+	}
+}
+
+// Start or Stop a jail
+type StartStop struct{}
+
+func (StartStop) Name() string     { return "start" }
+func (StartStop) Synopsis() string { return "Start, stop or restart jails." }
+func (StartStop) Usage() string {
+	return `start [-wait | -wait-tcp '[address:]port' | -wait-cmd 'command'] [-timeout 'duration'] [-all | -tag 'tag'] ['jail name' 'glob' '@tag' ... ]
+stop [-timeout 'duration' [-kill]] [-all | -tag 'tag'] ['jail name' 'glob' '@tag' ... ]
+restart [-timeout 'duration' [-kill]] [-all | -tag 'tag'] ['jail name' 'glob' '@tag' ... ]`
+}
+
+// stopOpts the -timeout and -kill of stop and restart, see lifecycle.Stop
+var stopOpts lifecycle.StopOptions // set by StartStop.Run
+
+func (StartStop) Run(args []string) {
+
+	action := args[0]
+
+	fset := newFlagSet(args[0])
+	all := fset.Bool("all", false, "Start or Stop all jails.")
+	tag := fset.String("tag", "", "Start or Stop all jails with the tag.")
+	timeout := fset.Duration("timeout", 0, "Stop or restart: kill the jail processes left after this time with -kill, else fail. Default: wait. Start: the -wait timeout, default 1m")
+	kill := fset.Bool("kill", false, "Stop or restart: after -timeout, default 30s, send SIGTERM then SIGKILL to the processes left, jail(8) then finishes the stop.")
+	wait := fset.Bool("wait", false, "Start: wait until the healthcheck of the jail passes.")
+	waitTCP := fset.String("wait-tcp", "", "Start: wait until the TCP port, or address:port, of the jail accepts a connection.")
+	waitCmd := fset.String("wait-cmd", "", "Start: wait until the command run in the jail exits 0.")
+	fset.Parse(args[1:])
+	args = fset.Args()
+
+	waitFor := *wait || len(*waitTCP) > 0 || len(*waitCmd) > 0
+	if *timeout < 0 || (action == "start" && *kill) || (action != "start" && waitFor) ||
+		(len(*waitTCP) > 0 && len(*waitCmd) > 0) || (action == "start" && *timeout > 0 && !waitFor) {
+		help()
+	}
+	if action == "start" {
+		if *timeout == 0 {
+			*timeout = healthWait
+		}
+	} else {
+		if *kill && *timeout == 0 {
+			*timeout = 30 * time.Second
+		}
+		stopOpts = lifecycle.StopOptions{Timeout: *timeout, Kill: *kill, Out: os.Stdout}
+	}
+
+	if system.NotRoot() {
+		fatal(jmgrError(exitNeedRoot, "Need root to start/stop/restart jails.", hintRoot))
+	}
+
+	var cfg Jmgr = jmgrInit()
+
+	var order []Jail
+	var missing error
+	if *all || len(*tag) > 0 {
+		// by Priority from the jail settings and after the jails they depend on, stopped in reverse
+		var jails []Jail
+		for _, jail := range cfg.Jails {
+			if len(*tag) > 0 && !slices.Contains(jail.Meta.Tags, strings.TrimPrefix(*tag, "@")) {
+				continue
+			}
+			if len(jail.Parent) == 0 {
+				jails = append(jails, jail)
+			}
+		}
+		slices.SortStableFunc(jails, func(a, b Jail) int { return a.Settings.Priority - b.Settings.Priority })
+		var err error
+		order, err = cfg.startOrder(jails)
+		if err != nil {
+			fatal(jmgrError(exitConfig, err.Error(), "See the dependencies with: jmgr depend 'jail name'"))
+		}
+		if err := startstopOrdered(action, order); err != nil {
+			fatal(err)
+		}
+
+	} else {
+		// globs and '@tag' select the jails, listed to confirm
+		args, expanded, err := cfg.selectJails(args, "")
+		if err != nil {
+			fatal(err)
+		}
+		if expanded {
+			confirmSelection(action, args)
+		}
+		var jails []Jail
+		for i := range args {
+			if cfg.exist(args[i]) {
+				jail := cfg.jail(args[i])
+				if len(jail.Parent) > 0 {
+					fmt.Println(jail.Name + " is a child of " + jail.Parent + ", skipped.")
+				} else {
+					jails = append(jails, jail)
+				}
+			} else {
+				// the other jails first, then exit not found
+				missing = errNoJail(args[i])
+			}
+		}
+		// the named jails after the named jails they depend on, stopped in reverse
+		order, err = cfg.startOrder(jails)
+		if err != nil {
+			fatal(jmgrError(exitConfig, err.Error(), "See the dependencies with: jmgr depend 'jail name'"))
+		}
+		if action == "stop" {
+			slices.Reverse(order)
+		}
+		for i := range order {
+			started := action == "restart" || (action == "start" && !order[i].Runs())
+			if err := startstop(action, &order[i]); err != nil {
+				fatal(err)
+			}
+			if started && i < len(order)-1 {
+				startDelay(order[i])
+			}
+		}
+	}
+
+	// the started jails are ready, the healthcheck of the jail or the -wait-tcp or -wait-cmd probe passes
+	if waitFor {
+		for _, jail := range order {
+			if !jail.Runs() {
+				continue
+			}
+			if len(*waitTCP) > 0 || len(*waitCmd) > 0 {
+				jail.Meta.Health = &Healthcheck{TCP: *waitTCP, Command: *waitCmd}
+			} else if jail.Meta.Health == nil {
+				fatal(jmgrError(exitNotFound, "Jail "+jail.Name+" has no healthcheck to wait for.", "Define one with: jmgr health -tcp 'port' "+jail.Name))
+			}
+			if err := waitHealthy(jail, *timeout); err != nil {
+				fatal(err)
+			}
+			fmt.Println("Jail " + jail.Name + " is ready.")
+		}
+	}
+	if missing != nil {
+		fatal(missing)
+	}
+}
+
+// healthWait how long start -all waits for the healthcheck of a jail that other jails depend on, the default -wait timeout
+const healthWait = time.Minute
+
+// startstopOrdered start the jails in order, a jail that a later jail depends on must pass its healthcheck first.
+// Stop them in reverse order, restart stops the running jails in reverse order and starts them again in order
+func startstopOrdered(action string, order []Jail) error {
+
+	running := make(map[string]bool)
+	if action != "start" {
+		for i := len(order) - 1; i >= 0; i-- {
+			running[order[i].Name] = order[i].Runs()
+			if err := startstop("stop", &order[i]); err != nil {
+				return err
+			}
+		}
+		if action == "stop" {
+			return nil
+		}
+	}
+
+	for i := range order {
+		if action == "restart" && !running[order[i].Name] {
+			continue
+		}
+		started := !order[i].Runs() || action == "restart"
+		if err := startstop("start", &order[i]); err != nil {
+			return err
+		}
+		if started && i < len(order)-1 {
+			startDelay(order[i])
+		}
+		if order[i].Meta.Health == nil {
+			continue
+		}
+		for _, dependent := range order[i+1:] {
+			if slices.Contains(dependent.Meta.Depends, order[i].Name) {
+				fmt.Println("Wait for " + order[i].Name + " to be healthy, " + dependent.Name + " depends on it.")
+				if err := waitHealthy(order[i], healthWait); err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// startDelay wait the start delay of the jail, before the next jail starts
+func startDelay(jail Jail) {
+
+	if delay := jail.StartDelay(); delay > 0 {
+		fmt.Println("Wait " + delay.String() + ", the start delay of " + jail.Name + ".")
+		time.Sleep(delay)
+	}
+}
+
+// waitHealthy wait until the healthcheck of the running jail passes, an error after timeout
+func waitHealthy(jail Jail, timeout time.Duration) error {
+
+	deadline := time.Now().Add(timeout)
+	for {
+		st := jailHealth(jail)
+		if st.Status == "healthy" {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return jmgrError(exitTimeout, jail.Name+" is not healthy after "+timeout.String()+": "+st.Detail, "See: jmgr health "+jail.Name)
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// Destroy jail or snapshot
+type Destroy struct{}
+
+func (Destroy) Name() string     { return "destroy" }
+func (Destroy) Synopsis() string { return "Destroy a jail or a snapshot." }
+func (Destroy) Usage() string {
+	return `destroy [-f] [-r] [-n] [-archive 'directory'] [-with-children] [-tag 'tag'] ['jail name' 'glob' '@tag' ... ]
+destroy -stopped [-f] [-r] [-n] -all | -tag 'tag' | 'jail name' 'glob' '@tag' ...
+destroy -keep-data [-suffix 'suffix'] [-f] [-n] [-with-children] 'jail name' ...
+destroy [-f] [-n] [-older-than 'age'] ['snapshot name' 'jail@glob' ... ]
+destroy [-f] [-n] -older-than 'age' 'jail name' ...`
+}
+
+func (Destroy) Run(args []string) {
+
+	fset := newFlagSet(args[0])
+	force := fset.Bool("f", false, "Destroy jail[s] without prompting for confirmation.")
+	recursive := fset.Bool("r", false, "Destroy jail[s] including their snapshots.")
+	tag := fset.String("tag", "", "Destroy the jails with the tag.")
+	dryRun := fset.Bool("n", false, "Dry run, list what would be destroyed or affected, nothing is changed.")
+	archive := fset.String("archive", "", "Archive the jail in the directory before it is destroyed: zfs send or tar, config and metadata.")
+	withChildren := fset.Bool("with-children", false, "Destroy a jail with child jails, the children are stopped first, deepest first.")
+	olderThan := fset.String("older-than", "", "Destroy only the snapshots older than age, ex: 30d, a jail name selects its snapshots.")
+	stopped := fset.Bool("stopped", false, "Destroy only the stopped jails selected, always listed and confirmed first.")
+	all := fset.Bool("all", false, "With -stopped, select all jails, standby replicas are skipped.")
+	keepData := fset.Bool("keep-data", false, "Remove the jail definition but keep its dataset or filesystem for jmgr adopt.")
+	suffix := fset.String("suffix", "", "With -keep-data, rename the dataset or filesystem kept with the suffix, ex: -retired.")
+	fset.Parse(args[1:])
+	args = fset.Args()
+
+	var age time.Duration
+	if len(*olderThan) > 0 {
+		var err error
+		if age, err = parseAge(*olderThan); err != nil {
+			fatal(jmgrError(exitUsage, "Bad -older-than "+*olderThan+".", "An age, ex: 12h, 30d or 2w"))
+		}
+	}
+
+	if len(*archive) > 0 {
+		if fi, err := os.Stat(*archive); err != nil || !fi.IsDir() {
+			fatal(jmgrError(exitNotFound, "No archive directory "+*archive+".", "Create it first, ex: mkdir -p "+*archive))
+		}
+	}
+
+	if len(args) == 0 && len(*tag) == 0 && !*all {
+		help()
+	}
+	if *all && (!*stopped || len(args) > 0 || len(*tag) > 0) {
+		fatal(jmgrError(exitUsage, "-all only selects the jails of destroy -stopped, without jail names or -tag.", "See: jmgr help destroy"))
+	}
+
+	if system.NotRoot() && !*dryRun {
+		fatal(jmgrError(exitNeedRoot, "Need root to destroy a jail or snapshot.", hintRoot))
+	}
+
+	cfg := jmgrInit()
+	// each jail a glob or tag selects is listed and confirmed below
+	args, _, err := cfg.selectJails(args, *tag)
+	if err != nil {
+		fatal(err)
+	}
+	// snapshot globs and -older-than, listed and confirmed at once
+	args, snaps, err := cfg.selectSnapshots(args, age)
+	if err != nil {
+		fatal(err)
+	}
+	if len(snaps) > 0 {
+		destroySnapshots(snaps, *force, *dryRun)
+	}
+	if *stopped {
+		args = cfg.stoppedJails(args, *all)
+		if len(args) == 0 {
+			fmt.Println("No stopped jail to destroy.")
+			return
+		}
+		// the list is always confirmed, -f only skips the confirmation of each jail
+		if !*dryRun {
+			confirmSelection("destroy the stopped jails", args)
+		}
+	}
+	// none is destroyed if one is protected
+	for _, target := range args {
+		if jail := cfg.jail(target); cfg.exist(target) && jail.Meta.Protected && !*dryRun {
+			fatal(errProtected(&jail, "destroy"))
+		}
+	}
+	for index := range args {
+		target := args[index]
+		if cfg.exist(target) {
+			jail := cfg.jail(target)
+
+			if len(jail.Parent) > 0 {
+				fatal(jmgrError(exitConflict, "Jail "+jail.Name+" is a child of "+jail.Parent+", Can't continue.", "Manage it from the parent jail "+jail.Parent+"."))
+			}
+
+			if jail.ConfigPath == "/etc/jail.conf" {
+				fatal(jmgrError(exitConflict, "Jail configuration is in "+jail.ConfigPath+". Remove this jail manually.", ""))
+			}
+
+			children := jailChildren(&jail)
+			if len(children) > 0 && !*withChildren && !*dryRun {
+				var names []string
+				for _, child := range children {
+					names = append(names, child.Name)
+				}
+				fatal(jmgrError(exitConflict, "Jail "+jail.Name+" has child jails: "+strings.Join(names, ", ")+", Can't continue.",
+					"Destroy it with its children: jmgr destroy -with-children "+jail.Name))
+			}
+
+			kept := ""
+			if *keepData {
+				kept = jail.Path + *suffix
+				if len(jail.Dataset) > 0 {
+					kept = jail.Dataset + *suffix
+				}
+				if err := cfg.canRetire(&jail, kept); err != nil && !*dryRun {
+					fatal(err)
+				}
+			}
+
+			plan := cfg.destroyPlan(&jail, *recursive, kept)
+			if len(*archive) > 0 {
+				plan = append(plan, "Archive: "+filepath.Join(*archive, jail.Name+"-'time'")+", the jail is archived first")
+			}
+			if *dryRun {
+				fmt.Println("Dry run, destroy " + jail.Name + ":")
+				for _, line := range plan {
+					fmt.Println("  " + line)
+				}
+				continue
+			}
+
+			if !*force {
+				for _, line := range plan {
+					fmt.Println(line)
+				}
+				askExitOnNo("Destroy this jail (yes/No)? ")
+			}
+
+			// does jail have zfs.Snapshot(s) ? checked before it is stopped
+			if !*keepData && !*recursive && len(jail.Dataset) > 0 {
+				b, err := system.Run("/sbin/zfs", []string{"list", "-H", "-t", "snapshot", "-o", "name", jail.Dataset})
+				if err != nil {
+					fatal(err)
+				}
+				if len(strings.TrimSpace(string(b))) > 0 {
+					fatal(jmgrError(exitConflict, "Jail "+jail.Name+" has zfs.Snapshot(s), Can't continue.", "Destroy all snapshots first, or use: jmgr destroy -r "+jail.Name))
+				}
+			}
+
+			if err := stopChildren(children); err != nil {
+				fatal(err)
+			}
+			if jail.Runs() {
+				err := startstop("stop", &jail)
+				if err != nil {
+					fatal(err)
+				}
+
+				time.Sleep(500 * time.Millisecond)
+			}
+
+			// the last copy, nothing is destroyed if it fails
+			var archived string
+			if len(*archive) > 0 {
+				archived, err = cfg.archiveJail(&jail, *archive, *keepData)
+				if err != nil {
+					fatal(err)
+				}
+				fmt.Println("Jail " + jail.Name + " archived in " + archived)
+			}
+
+			if *keepData {
+				if err := cfg.retireJail(&jail, kept); err != nil {
+					fatal(err)
+				}
+				fmt.Println("Jail " + jail.Name + " data kept in " + kept + ", re-adopt it with: jmgr adopt " + jail.Name)
+			} else if len(jail.Dataset) > 0 {
+				if *recursive {
+					cmd := exec.Command(system.Tool("/sbin/zfs"), []string{"destroy", "-r", "-f", jail.Dataset}...)
+					cmd.Stdout = os.Stdout
+					cmd.Stderr = os.Stderr
+					cmd.Stdin = os.Stdin
+					err := cmd.Run()
+					if err != nil {
+						fmt.Println("Error:", err)
+					}
+
+				} else {
+					cmd := exec.Command(system.Tool("/sbin/zfs"), []string{"destroy", jail.Dataset}...)
+					cmd.Stdout = os.Stdout
+					cmd.Stderr = os.Stderr
+					cmd.Stdin = os.Stdin
+					err := cmd.Run()
+					if err != nil {
+						fatal(err)
+					}
+
+				}
+			} else {
+
+				_, err := system.Run("/bin/chflags", []string{"-R", "0", jail.Path})
+				if err != nil {
+					fatal(err)
+				}
+
+				system.Run("/bin/rm", []string{"-rf", jail.Path})
+				if err != nil {
+					fatal(err)
+				}
+
+			}
+
+			if jail.OnBoot == "Yes" {
+				var d EnableDisable
+				d.Run([]string{"disable", jail.Name})
+			}
+
+			_, err := system.Run("/bin/rm", []string{jail.ConfigPath})
+			if err != nil {
+				fatal(fmt.Errorf("Destroy(): %w", err))
+			}
+
+			err = cfg.removeMeta(jail.Name)
+			if err != nil {
+				fatal(fmt.Errorf("Destroy(): %w", err))
+			}
+			if *keepData {
+				// pf.conf: anchor "jmgr/*", the rules of the jail
+				system.Run("/sbin/pfctl", []string{"-a", "jmgr/" + jail.Name, "-F", "all"})
+				archived = strings.TrimSpace(archived + " kept " + kept)
+			}
+			event("destroy", jail.Name, archived)
+
+		} else {
+
+			rgx := regexp.MustCompile(".*@.*")
+			match := rgx.FindStringSubmatch(target)
+			if match == nil {
+				fatal(jmgrError(exitNotFound, "Name: "+target+" is not a jail or snapshot.", "See the jails with: jmgr jails"))
+			}
+
+			cmd := exec.Command(system.Tool("/sbin/zfs"), "list", target)
+			_, err := cmd.Output()
+			if err != nil {
+				fatal(jmgrError(exitNotFound, "Can't find snapshot: "+target, "See the snapshots with: jmgr 'jail name'"))
+			}
+
+			fmt.Println("Snapshot:", target)
+			clones := zfs.Clones(target)
+			for _, clone := range clones {
+				fmt.Println("Clone:", clone+", blocks the destroy, promote it first, see: jmgr promote")
+			}
+			if *dryRun {
+				continue
+			}
+			if !*force {
+				askExitOnNo("Destroy this snapshot (yes/No)? ")
+			}
+
+			_, err = system.Run("/sbin/zfs", []string{"destroy", target})
+			if err != nil {
+				fatal(err)
+			}
+		}
+	}
+}
+
+// GC find and remove the debris of aborted creates and manual deletions
+type GC struct{}
+
+func (GC) Name() string { return "gc" }
+func (GC) Synopsis() string {
+	return "Find and remove orphan datasets, configs, metadata, jail_list entries and pf anchors."
+}
+func (GC) Usage() string { return "gc [-f] [-n]" }
+
+// Orphan a leftover of a jail that does not exist, found by gc
+type Orphan struct {
+	Kind   string       `json:"kind"` // dataset, config, metadata, jail_list or anchor
+	Name   string       `json:"name"` // dataset, file, jail or anchor name
+	Reason string       `json:"reason"`
+	clean  func() error // remove it
+	ask    bool         // confirmed even with -f, a dataset is data
+}
+
+func (GC) Run(args []string) {
+
+	fset := newFlagSet(args[0])
+	force := fset.Bool("f", false, "Remove the orphans without prompting for confirmation.")
+	dryRun := fset.Bool("n", false, "Dry run, list the orphans, nothing is changed.")
+	fset.Parse(args[1:])
+	if fset.NArg() > 0 {
+		help()
+	}
+
+	if system.NotRoot() && !*dryRun {
+		fatal(jmgrError(exitNeedRoot, "Need root to remove orphans.", hintRoot))
+	}
+
+	cfg := jmgrInit()
+	orphans := cfg.orphans()
+	if len(orphans) == 0 {
+		fmt.Println("No orphans.")
+	}
+	var removed []Orphan
+	for _, o := range orphans {
+		fmt.Printf("%s: %s, %s\n", o.Kind, o.Name, o.Reason)
+		if *dryRun {
+			continue
+		}
+		if *force && o.ask {
+			fmt.Println("Not removed with -f, run jmgr gc without -f to confirm it.")
+			continue
+		}
+		if !*force && !askYes("Remove "+o.Kind+" "+o.Name+" (yes/No)? ") {
+			continue
+		}
+		if err := o.clean(); err != nil {
+			fmt.Println("Error:", err)
+			continue
+		}
+		removed = append(removed, o)
+		event("gc", o.Name, o.Kind)
+	}
+	if *dryRun {
+		printJSON(orphans)
+	} else {
+		printJSON(removed)
+	}
+}
+
+// orphans return the datasets under ZFSdataSet without a jail, the jail configs in JailsConfD whose filesystem or dataset is
+// missing, the metadata files and the jail_list entries without a jail and the pf anchors jmgr/<name> without a jail.
+// Standby replicas and unmounted datasets are not orphans, the dataset of a jail is found by its mountpoint too
+func (cfg *Jmgr) orphans() []Orphan {
+
+	var orphans []Orphan
+	var kept []string
+	for _, r := range cfg.retiredJails() {
+		kept = append(kept, r.Kept)
+	}
+	mountpoints := make(map[string]string) // dataset mountpoint by dataset, mounted or not
+	if len(cfg.ZFSdataSet) > 0 {
+		b, _ := system.Run("/sbin/zfs", []string{"list", "-H", "-o", "name,mountpoint", "-d", "1", cfg.ZFSdataSet})
+		for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+			if dataset, mountpoint, ok := strings.Cut(line, "\t"); ok {
+				mountpoints[dataset] = mountpoint
+			}
+		}
+		for dataset, mountpoint := range mountpoints {
+			name, ok := strings.CutPrefix(dataset, cfg.ZFSdataSet+"/")
+			if !ok || cfg.exist(name) || slices.Contains(kept, dataset) || slices.ContainsFunc(cfg.Jails, func(j Jail) bool {
+				return j.Dataset == dataset || (len(j.Path) > 0 && j.Path == mountpoint)
+			}) {
+				continue
+			}
+			orphans = append(orphans, Orphan{"dataset", dataset, "no jail config", func() error {
+				_, err := system.Run("/sbin/zfs", []string{"destroy", "-r", dataset})
+				return err
+			}, true})
+		}
+		slices.SortFunc(orphans, func(a, b Orphan) int { return strings.Compare(a.Name, b.Name) })
+	}
+
+	for _, jail := range cfg.Jails {
+		if len(jail.Parent) > 0 || !strings.HasPrefix(jail.ConfigPath, cfg.JailsConfD+"/") || jail.Runs() || len(jail.Meta.StandbyOf) > 0 {
+			continue
+		}
+		reason := ""
+		if len(jail.Dataset) > 0 {
+			if _, err := system.Run("/sbin/zfs", []string{"list", "-H", jail.Dataset}); err != nil {
+				reason = "dataset " + jail.Dataset + " is missing"
+			}
+		} else if _, err := os.Stat(jail.Path); err != nil {
+			reason = "path " + jail.Path + " is missing"
+		}
+		if len(reason) == 0 || cfg.hasDataset(jail, mountpoints) {
+			continue
+		}
+		name, config := jail.Name, jail.ConfigPath
+		orphans = append(orphans, Orphan{"config", config, reason, func() error {
+			if err := os.Remove(config); err != nil {
+				return err
+			}
+			if list := jailList(); slices.Contains(list, name) {
+				if err := setJailList(slices.DeleteFunc(list, func(n string) bool { return n == name })); err != nil {
+					return err
+				}
+			}
+			return cfg.removeMeta(name)
+		}, false})
+	}
+
+	files, _ := filepath.Glob(filepath.Join(cfg.JailMetaDir, "*.yml"))
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".yml")
+		if cfg.exist(name) {
+			continue
+		}
+		orphans = append(orphans, Orphan{"metadata", file, "no jail " + name, func() error {
+			return cfg.removeMeta(name)
+		}, false})
+	}
+
+	for _, name := range jailList() {
+		if cfg.exist(name) {
+			continue
+		}
+		orphans = append(orphans, Orphan{"jail_list", name, "no jail " + name + " in rc.conf jail_list", func() error {
+			return setJailList(slices.DeleteFunc(jailList(), func(n string) bool { return n == name }))
+		}, false})
+	}
+
+	// pf.conf: anchor "jmgr/*"
+	b, _ := system.Run("/sbin/pfctl", []string{"-a", "jmgr", "-sA"})
+	for _, anchor := range strings.Fields(string(b)) {
+		name, ok := strings.CutPrefix(anchor, "jmgr/")
+		if !ok || cfg.exist(name) {
+			continue
+		}
+		orphans = append(orphans, Orphan{"anchor", anchor, "no jail " + name + ", pf rules loaded", func() error {
+			_, err := system.Run("/sbin/pfctl", []string{"-a", anchor, "-F", "all"})
+			return err
+		}, false})
+	}
+	return orphans
+}
+
+// hasDataset the jail has a dataset that is not mounted, ex: a standby replica received with zfs receive -u or a dataset
+// unmounted for maintenance: ZFSdataSet/<name> exists, or a dataset under ZFSdataSet has the jail path as mountpoint
+func (cfg *Jmgr) hasDataset(jail Jail, mountpoints map[string]string) bool {
+
+	if len(cfg.ZFSdataSet) == 0 {
+		return false
+	}
+	if _, err := system.Run("/sbin/zfs", []string{"list", "-H", "-o", "name", cfg.ZFSdataSet + "/" + jail.Name}); err == nil {
+		return true
+	}
+	for _, mountpoint := range mountpoints {
+		if mountpoint == jail.Path {
+			return true
+		}
+	}
+	return false
+}
+
+// Adopt a jail retired with 'destroy -keep-data' again
+type Adopt struct{}
+
+func (Adopt) Name() string     { return "adopt" }
+func (Adopt) Synopsis() string { return "Adopt a jail destroyed with -keep-data again, or list them." }
+func (Adopt) Usage() string    { return "adopt ['jail name']" }
+
+func (Adopt) Run(args []string) {
+
+	fset := newFlagSet(args[0])
+	fset.Parse(args[1:])
+	args = fset.Args()
+	if len(args) > 1 {
+		help()
+	}
+
+	cfg := jmgrInit()
+	retired := cfg.retiredJails()
+	if len(args) == 0 {
+		if jsonOutput {
+			printJSON(retired)
+			return
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tKEPT\tRETIRED")
+		for _, r := range retired {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", r.Name, r.Kept, r.Retired)
+		}
+		w.Flush()
+		return
+	}
+
+	if system.NotRoot() {
+		fatal(jmgrError(exitNeedRoot, "Need root to adopt a jail.", hintRoot))
+	}
+	name := args[0]
+	i := slices.IndexFunc(retired, func(r RetiredJail) bool { return r.Name == name })
+	if i < 0 {
+		fatal(jmgrError(exitNotFound, "No retired jail "+name+".", "See the retired jails with: jmgr adopt"))
+	}
+	r := retired[i]
+	if cfg.exist(name) {
+		fatal(jmgrError(exitConflict, "Jail "+name+" exists, Can't continue.", "Destroy or rename it first."))
+	}
+	if _, err := os.Stat(r.Config); err == nil {
+		fatal(jmgrError(exitConflict, r.Config+" exists, Can't continue.", "Remove it first."))
+	}
+
+	conf, err := os.ReadFile(filepath.Join(cfg.retiredDir(), name+".conf"))
+	if err != nil {
+		fatal(fmt.Errorf("Adopt(): %w", err))
+	}
+	if err := moveData(r.Kept, r.Data); err != nil {
+		fatal(err)
+	}
+	if err := os.WriteFile(r.Config, conf, 0644); err != nil {
+		fatal(fmt.Errorf("Adopt(): %w", err))
+	}
+	if err := cfg.writeMeta(name, r.Meta); err != nil {
+		fatal(err)
+	}
+	os.Remove(filepath.Join(cfg.retiredDir(), name+".conf"))
+	os.Remove(filepath.Join(cfg.retiredDir(), name+".yml"))
+
+	fmt.Println("Jail " + name + " adopted from " + r.Kept + ", start on boot with: jmgr enable " + name)
+	event("create", name, "adopted from "+r.Kept)
+}
+
+// Create a snapshot for dataset, or a snapshot with the same name and time for all jails in a group (@tag)
+type Snapshot struct{}
+
+func (Snapshot) Name() string { return "snapshot" }
+func (Snapshot) Synopsis() string {
+	return "Snapshot a jail, or all jails with a tag at the same time."
+}
+func (Snapshot) Usage() string {
+	return `snapshot 'jail name' ['glob' ... ]
+snapshot [-q] '@tag' ['label']
+snapshot [-q] -tag 'tag' ['label']`
+}
+
+func (Snapshot) Run(args []string) {
+
+	fset := newFlagSet(args[0])
+	quiesce := fset.Bool("q", false, "Group snapshot, run the PreSnapshot/PostSnapshot scripts for each jail around the snapshot.")
+	tag := fset.String("tag", "", "Group snapshot of the jails with the tag, the same as '@tag'.")
+	fset.Parse(args[1:])
+	args = append([]string{args[0]}, fset.Args()...)
+	if len(*tag) > 0 {
+		args = append([]string{args[0], "@" + strings.TrimPrefix(*tag, "@")}, args[1:]...)
+	}
+
+	if len(args) >= 2 && strings.HasPrefix(args[1], "@") {
+		if system.NotRoot() {
+			fatal(jmgrError(exitNeedRoot, "Need root to create snapshots.", hintRoot))
+		}
+		cfg := jmgrInit()
+		label := ""
+		if len(args) > 2 {
+			label = args[2]
+		}
+		snaps, err := cfg.groupSnapshot(strings.TrimPrefix(args[1], "@"), label, *quiesce)
+		if err != nil {
+			fatal(err)
+		}
+		for _, snap := range snaps {
+			fmt.Println("Snapshot:", snap, "Created.")
+		}
+		event("snapshot", args[1], strings.Join(snaps, " "))
+		printJSON(map[string][]string{"snapshots": snaps})
+		return
+	}
+
+	// a snapshot of each jail a glob selects, listed to confirm
+	if len(args) > 2 || (len(args) == 2 && isPattern(args[1])) {
+		if system.NotRoot() {
+			fatal(jmgrError(exitNeedRoot, "Need root to create snapshots.", hintRoot))
+		}
+		cfg := jmgrInit()
+		names, expanded, err := cfg.selectJails(args[1:], "")
+		if err != nil {
+			fatal(err)
+		}
+		if expanded {
+			confirmSelection("snapshot", names)
+		}
+		var snaps []string
+		for _, name := range names {
+			if !cfg.exist(name) {
+				fatal(errNoJail(name))
+			}
+			jail := cfg.jail(name)
+			if len(jail.Parent) > 0 {
+				fmt.Println(jail.Name + " is a child of " + jail.Parent + ", skipped.")
+				continue
+			}
+			if len(jail.Dataset) == 0 {
+				fmt.Println("Jail", jail.Name, "does not support zfs snapshot, skipped.")
+				continue
+			}
+			snap, err := zfs.Snapshot(jail.Dataset)
+			if err != nil {
+				fatal(err)
+			}
+			fmt.Println("Snapshot:", snap, "Created.")
+			event("snapshot", jail.Name, snap)
+			snaps = append(snaps, snap)
+		}
+		printJSON(map[string][]string{"snapshots": snaps})
+		return
+	}
+
+	_, jail, err := verifyArgs(2, 1, true, true, args)
+	if err != nil {
+		fatal(err)
+	}
+
+	if len(jail.Parent) > 0 {
+		fatal(jmgrError(exitConflict, "Jail "+jail.Name+" is a child of "+jail.Parent+", Can't continue.", "Manage it from the parent jail "+jail.Parent+"."))
+	}
+
+	if len(jail.Dataset) > 0 {
+		snap, err := zfs.Snapshot(jail.Dataset)
+		if err != nil {
+			fatal(err)
+		}
+		event("snapshot", jail.Name, snap)
+		printJSON(map[string][]string{"snapshots": {snap}})
+	} else {
+		fatal(jmgrError(exitConflict, "Jail "+jail.Name+" does not support zfs snapshot.", ""))
+	}
+}
+
+// Promote a thin cloned jail, it no longer depends on the source jail snapshot
+type Promote struct{}
+
+func (Promote) Name() string { return "promote" }
+func (Promote) Synopsis() string {
+	return "Promote a thin clone, it no longer depends on the source jail snapshot."
+}
+func (Promote) Usage() string { return "promote 'jail name'" }
+
+func (Promote) Run(args []string) {
+
+	fset := newFlagSet(args[0])
+	fset.Parse(args[1:])
+	args = append([]string{args[0]}, fset.Args()...)
+
+	_, jail, err := verifyArgs(2, 1, true, true, args)
+	if err != nil {
+		fatal(err)
+	}
+
+	if len(jail.Dataset) == 0 {
+		fatal(jmgrError(exitConflict, "Jail "+jail.Name+" is not on ZFS.", ""))
+	}
+
+	origin, err := zfs.Origin(jail.Dataset)
+	if err != nil {
+		fatal(err)
+	}
+	if len(origin) == 0 {
+		fatal(jmgrError(exitConflict, "Jail "+jail.Name+" is not a thin clone.", ""))
+	}
+
+	_, err = system.Run("/sbin/zfs", []string{"promote", jail.Dataset})
+	if err != nil {
+		fatal(err)
+	}
+	fmt.Println("Jail", jail.Name, "promoted, origin was", origin)
+}
+
+// Rollback jail to a given snapshot
+type Rollback struct{}
+
+func (Rollback) Name() string     { return "rollback" }
+func (Rollback) Synopsis() string { return "Roll back a jail, or all jails with a tag, to a snapshot." }
+func (Rollback) Usage() string {
+	return `rollback 'jail name' 'latest snapshot name'
+rollback [-f] [-r] '@tag' 'label'`
+}
+
+func (Rollback) Run(args []string) {
+
+	fset := newFlagSet(args[0])
+	force := fset.Bool("f", false, "Group rollback without prompting for confirmation.")
+	recursive := fset.Bool("r", false, "Group rollback, destroy snapshots later than 'label'.")
+	fset.Parse(args[1:])
+	args = append([]string{args[0]}, fset.Args()...)
+
+	if len(args) == 3 && strings.HasPrefix(args[1], "@") {
+		if system.NotRoot() {
+			fatal(jmgrError(exitNeedRoot, "Need root to rollback jails.", hintRoot))
+		}
+		cfg := jmgrInit()
+		err := cfg.groupRollback(strings.TrimPrefix(args[1], "@"), args[2], *force, *recursive)
+		if err != nil {
+			fatal(err)
+		}
+		event("rollback", args[1], args[2])
+		return
+	}
+
+	_, jail, err := verifyArgs(3, 1, true, true, args)
+	if err != nil {
+		fatal(err)
+	}
+
+	if len(jail.Parent) > 0 {
+		fatal(jmgrError(exitConflict, "Jail "+jail.Name+" is a child of "+jail.Parent+", Can't continue.", "Manage it from the parent jail "+jail.Parent+"."))
+	}
+	if jail.Meta.Protected {
+		fatal(errProtected(jail, "rollback"))
+	}
+
+	snapshot := args[2]
+	latestSnap, err := zfs.LatestSnapshot(jail.Dataset)
+	if err != nil {
+		fatal(jmgrError(exitNotFound, "No snapshots found for jail "+jail.Name+", can't continue.", "Create one with: jmgr snapshot "+jail.Name))
+	}
+
+	if snapshot != latestSnap {
+		fatal(jmgrError(exitConflict, "Snapshot: "+snapshot+" is not the latest snapshot for this jail.", "See 'jmgr "+jail.Name+"', use 'jmgr destroy snapshot'."))
+	}
+
+	askExitOnNo("Rollback jail: " + jail.Name + " to snapshot: " + snapshot + " (yes/No)? ")
+
+	if jail.Runs() {
+
+		askExitOnNo("Jail is running, stop" + jail.Name + "(yes/No)? ")
+		startstop("stop", jail)
+	}
+
+	_, err = system.Run("/sbin/zfs", []string{"rollback", snapshot})
+	if err != nil {
+		fatal(err)
+	}
+	event("rollback", jail.Name, snapshot)
+}
+
+// freebsd update os || upgrade pkgs || upgrade freebsd release
+type Update struct{}
+
+func (Update) Name() string { return "update" }
+func (Update) Synopsis() string {
+	return "Patch the base system, upgrade packages or upgrade the FreeBSD release of jails."
+}
+func (Update) Usage() string {
+	return `update [-f] patch 'jail name'
+update [-f] base 'jail name'
+update [-f] pkgs 'jail name'
+update [-f] -all|-tag 'tag' [-parallel 'n'] patch|base|pkgs
+update [-f] [-parallel 'n'] patch|base|pkgs 'glob'|'@tag'
+update [-f] -all|-tag 'tag' [-parallel 'n'] [-v 'FreeBSD Release'] rel
+update [-f] [-parallel 'n'] [-v 'FreeBSD Release'] rel 'glob'|'@tag'
+update [-parallel 'n'] check ['jail name' 'glob' '@tag' ... ]
+update [-f] clean
+update [-v 'FreeBSD Release'] rel 'jail name'
+update [-f] -resume|-abort rel 'jail name'
+update -l`
+}
+
+func (Update) Run(args []string) {
+
+	fset := newFlagSet(args[0])
+	force := fset.Bool("f", false, "Update jail without prompting for confirmation.")
+	list := fset.Bool("l", false, "List available releases")
+	version := fset.String("v", "", "Freebsd Release, ex: 13.4-RELEASE, if not defined jail is created with host release.")
+	all := fset.Bool("all", false, "Update all jails (patch, base, pkgs or rel), children are skipped.")
+	parallel := fset.Int("parallel", 1, "Number of jails to update in parallel (with -all).")
+	resume := fset.Bool("resume", false, "Resume an interrupted or failed release upgrade (rel).")
+	abort := fset.Bool("abort", false, "Abort a release upgrade, roll back to the pre-upgrade snapshot (rel).")
+	noVerify := fset.Bool("noverify", false, "Do not verify the jail after the update.")
+	tag := fset.String("tag", "", "Update the jails with the tag, as -all.")
+	fset.Parse(args[1:])
+	args = fset.Args()
+
+	if *list {
+		err := printRel()
+		if err != nil {
+			fatal(fmt.Errorf("Update() get avaliable releases failed: %w", err))
+		}
+		exit(0)
+	}
+
+	if *all || len(*tag) > 0 {
+		if len(args) != 1 || *parallel < 1 {
+			help()
+		}
+		switch args[0] {
+		case "patch", "base", "pkgs":
+			updateAll(args[0], strings.TrimPrefix(*tag, "@"), "", *force, *parallel, !*noVerify)
+		case "rel":
+			updateRelAll(*version, strings.TrimPrefix(*tag, "@"), "", *force, *parallel, !*noVerify)
+		default:
+			help()
+		}
+		return
+	}
+
+	// a glob or '@tag' updates the jails it selects, as -tag
+	if len(args) == 2 && isPattern(args[1]) && !*resume && !*abort {
+		if *parallel < 1 {
+			help()
+		}
+		tag, glob := "", args[1]
+		if name, ok := strings.CutPrefix(glob, "@"); ok {
+			tag, glob = name, ""
+		}
+		switch args[0] {
+		case "patch", "base", "pkgs":
+			updateAll(args[0], tag, glob, *force, *parallel, !*noVerify)
+		case "rel":
+			updateRelAll(*version, tag, glob, *force, *parallel, !*noVerify)
+		default:
+			help()
+		}
+		return
+	}
+
+	if len(args) > 0 && args[0] == "clean" {
+		updateClean(*force)
+		return
+	}
+
+	if len(args) > 0 && args[0] == "check" {
+		if *parallel < 1 {
+			help()
+		}
+		cfg := jmgrInit()
+		names, _, err := cfg.selectJails(args[1:], "")
+		if err != nil {
+			fatal(err)
+		}
+		updateCheck(names, *parallel)
+		return
+	}
+
+	cfg, jail, err := verifyArgs(2, 1, true, true, args)
+	if err != nil {
+		fatal(err)
+	}
+
+	if len(jail.Parent) > 0 {
+		fatal(jmgrError(exitConflict, "Jail "+jail.Name+" is a child of "+jail.Parent+", Can't continue.", "Manage it from the parent jail "+jail.Parent+"."))
+	}
+
+	if jail.Meta.Hold {
+		fatal(jmgrError(exitConflict, "Jail "+jail.Name+" is on hold.", "Release it with: jmgr hold -d "+jail.Name))
+	}
+
+	switch args[0] {
+
+	case "patch", "base":
+
+		if !*force {
+			askExitOnNo("Update FreeBSD on: " + jail.Name + ", filesystem: " + jail.Path + ", ZFS dataset: " + jail.Dataset + " (yes/No)?")
+		}
+
+		var snap string
+		if len(jail.Dataset) > 0 {
+			if *force || askYes("Create snapshot before continue (yes/No)?") {
+				snap, err = zfs.Snapshot(jail.Dataset)
+				if err != nil {
+					fatal(fmt.Errorf("Update() patch snapshot fail: %w", err))
+				}
+			}
+		}
+		release := regexp.MustCompile(`-p[0-9]+$`).ReplaceAllString(jail.OsVersion, "")
+
+		// pkgbase jails are updated with pkg, others with freebsd-update
+		if cfg.isPkgBase(jail) {
+			s := spinner.StartNew("Update FreeBSD base packages on jail " + jail.Name)
+			err = cfg.updatePkgBase(jail)
+			s.Stop()
+		} else {
+			err = cfg.updateOs(jail)
+		}
+		if err != nil {
+			event("update-failed", jail.Name, "patch: "+err.Error())
+			fatal(fmt.Errorf("Patch update failed: %w", err))
+		}
+		fmt.Println("/ Update FreeBSD on jail " + jail.Name + " completed.")
+
+		if !*noVerify && !reportVerify(jail, verifyUpdate(jail, release, true), snap) {
+			event("update-failed", jail.Name, "patch: verify failed")
+			exit(1)
+		}
+		event("update-complete", jail.Name, "patch")
+
+	case "rel":
+
+		var osVersion string
+		if len(*version) > 1 {
+			osVersion = *version
+		} else {
+			osVersion, err = hostVersion()
+			if err != nil {
+				fatal(fmt.Errorf("Create(): %w", err))
+			}
+		}
+
+		if cfg.isPkgBase(jail) {
+			fatal(jmgrError(exitConflict, jail.Name+" is a pkgbase jail.", "Change the release in the "+cfg.PkgBaseRepo+" repository and run: jmgr update base "+jail.Name))
+		}
+
+		up := jail.Meta.Upgrade
+		switch {
+		case (*resume || *abort) && up == nil:
+			fatal(jmgrError(exitConflict, "No release upgrade in progress on "+jail.Name+".", ""))
+		case *resume:
+			fmt.Println("Resume upgrade of " + jail.Name + " from " + up.From + " to " + up.To + ", completed phase: " + up.Done)
+			err := cfg.upgradeRel(jail, up.To, up.Snapshot, true)
+			if err != nil {
+				event("update-failed", jail.Name, "rel "+up.To+": "+err.Error())
+				fatal(fmt.Errorf("Upgrade Release failed: %w", err))
+			}
+			fmt.Println("FreeBSD upgrade completed.")
+			if !*noVerify && !reportVerify(jail, verifyUpdate(jail, up.To, true), up.Snapshot) {
+				event("update-failed", jail.Name, "rel "+up.To+": verify failed")
+				exit(1)
+			}
+			event("update-complete", jail.Name, "rel "+up.To)
+			return
+		case *abort:
+			if len(up.Snapshot) == 0 {
+				fatal(jmgrError(exitNotFound, "No pre-upgrade snapshot of "+jail.Name+", can't roll back.", ""))
+			}
+			if !*force {
+				askExitOnNo("Abort upgrade of " + jail.Name + " to " + up.To + ", roll back to " + up.Snapshot + " (yes/No)? ")
+			}
+			running := jail.Runs()
+			if err := startstop("stop", jail); err != nil {
+				fatal(err)
+			}
+			if _, err := system.Run("/sbin/zfs", []string{"rollback", "-r", up.Snapshot}); err != nil {
+				fatal(err)
+			}
+			jail.Meta.Upgrade = nil
+			if err := cfg.writeMeta(jail.Name, jail.Meta); err != nil {
+				fatal(err)
+			}
+			if running {
+				if err := startstop("start", jail); err != nil {
+					fatal(err)
+				}
+			}
+			fmt.Println("Upgrade of " + jail.Name + " aborted, rolled back to " + up.Snapshot + ".")
+			return
+		case up != nil:
+			fatal(jmgrError(exitConflict, "Upgrade of "+jail.Name+" to "+up.To+" in progress.", "Use 'jmgr update rel -resume "+jail.Name+"' or -abort."))
+		}
+
+		rgx := regexp.MustCompile(osVersion)
+		match := rgx.FindStringSubmatch(jail.OsVersion)
+		if len(match) > 0 {
+			fatal(jmgrError(exitConflict, jail.Name+" already at release "+osVersion+".", ""))
+		}
+
+		askExitOnNo("Upgrade " + jail.Name + " FreeBSD from: " + jail.OsVersion + " to: " + osVersion + " (yes/No)?")
+
+		var snap string
+		if len(jail.Dataset) > 0 {
+			if askYes("Create snapshot before continue (yes/No)?") {
+				snap, err = zfs.Snapshot(jail.Dataset)
+				if err != nil {
+					fatal(err)
+				}
+			}
+		}
+
+		err := cfg.upgradeRel(jail, osVersion, snap, true)
+		if err != nil {
+			event("update-failed", jail.Name, "rel "+osVersion+": "+err.Error())
+			fatal(fmt.Errorf("Upgrade Release failed: %w", err))
+		}
+		fmt.Println("FreeBSD upgrade completed.")
+		if !*noVerify && !reportVerify(jail, verifyUpdate(jail, osVersion, true), snap) {
+			event("update-failed", jail.Name, "rel "+osVersion+": verify failed")
+			exit(1)
+		}
+		event("update-complete", jail.Name, "rel "+osVersion)
+
+	case "pkgs":
+
+		if !*force {
+			askExitOnNo("Upgrade all installed packages on: " + jail.Name + " (yes/No)?")
+		}
+
+		if jail.Jid == 0 {
+			if !*force {
+				askExitOnNo("Start (needed for pkg update) " + jail.Name + " (yes/No)?")
+			}
+
+			err := startstop("start", jail)
+			if err != nil {
+				fatal(fmt.Errorf("Upgrade Pkgs: %w", err))
+			}
+		}
+
+		var snap string
+		if len(jail.Dataset) > 1 {
+
+			if *force || askYes("Create snapshot before continue (yes/No)?") {
+				snap, err = zfs.Snapshot(jail.Dataset)
+				if err != nil {
+					fatal(fmt.Errorf("Update pkgs Snapshot fail: %w", err))
+				} else {
+					fmt.Println("Snapshot: ", snap, " Created.")
+				}
+			}
+		}
+
+		err := cfg.writePkgRepos(jail.Path, jail.Meta.PkgRepos)
+		if err != nil {
+			fatal(err)
+		}
+
+		if !hasPkg(jail) {
+			if !*force {
+				askExitOnNo("pkg is not installed in " + jail.Name + ", bootstrap pkg (yes/No)? ")
+			}
+			s := spinner.StartNew("Bootstrap pkg in " + jail.Name)
+			err = pkgBootstrap(jail)
+			s.Stop()
+			if err != nil {
+				fatal(err)
+			}
+			fmt.Println("/ Bootstrap completed.")
+		}
+
+		err = upgradePkg(jail)
+		if err != nil {
+			fmt.Println("upgradePkg() returned:", err.Error())
+			event("update-failed", jail.Name, "pkgs: "+err.Error())
+		}
+
+		release := regexp.MustCompile(`-p[0-9]+$`).ReplaceAllString(jail.OsVersion, "")
+		if !*noVerify && !reportVerify(jail, verifyUpdate(jail, release, true), snap) {
+			event("update-failed", jail.Name, "pkgs: verify failed")
+			exit(1)
+		}
+		if err == nil {
+			event("update-complete", jail.Name, "pkgs")
+		}
+
+	default:
+		help()
+	}
+}
+
+// SyncDefinitions pull jail definitions, templates and metadata (not data) from a remote jmgr host
+type SyncDefinitions struct{}
+
+func (SyncDefinitions) Name() string { return "sync-definitions" }
+func (SyncDefinitions) Synopsis() string {
+	return "Pull jail definitions, templates and metadata from a remote jmgr host."
+}
+func (SyncDefinitions) Usage() string { return "sync-definitions [-f] [-n] 'user@host'" }
+
+func (SyncDefinitions) Run(args []string) {
+
+	fset := newFlagSet(args[0])
+	force := fset.Bool("f", false, "Overwrite local files that differ without prompting for confirmation.")
+	dryRun := fset.Bool("n", false, "Dry run, only report what would be changed.")
+	fset.Parse(args[1:])
+	args = fset.Args()
+
+	if len(args) != 1 || args[0] == "help" || args[0] == "-h" {
+		help()
+	}
+	remote := args[0]
+
+	if !*dryRun && system.NotRoot() {
+		fatal(jmgrError(exitNeedRoot, "Need root to sync jail definitions.", hintRoot))
+	}
+
+	var cfg Jmgr = jmgrInit()
+
+	rcfg, err := remoteConfig(remote)
+	if err != nil {
+		fatal(err)
+	}
+
+	if !filepath.IsAbs(rcfg.JmgrConfig) || !filepath.IsAbs(rcfg.JailsConfD) {
+		fatal(jmgrError(exitConfig, "jmgr config on "+remote+" is not ok.", "Run 'jmgr config' on "+remote+" to see the problems reported."))
+	}
+
+	stage, err := os.MkdirTemp("", "jmgr-sync-")
+	if err != nil {
+		fatal(fmt.Errorf("SyncDefinitions(): %w", err))
+	}
+	defer os.RemoveAll(stage)
+
+	// remote dir -> local dir, the remote jmgr.conf is host specific and never copied
+	syncDirs := []struct {
+		remote, local, skip string
+	}{
+		{rcfg.JailsConfD, cfg.JailsConfD, ""},
+		{filepath.Dir(rcfg.JmgrConfig), filepath.Dir(config.File()), filepath.Base(rcfg.JmgrConfig)},
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for i, d := range syncDirs {
+		dst := filepath.Join(stage, strconv.Itoa(i))
+		if err := os.MkdirAll(dst, 0755); err != nil {
+			fatal(fmt.Errorf("SyncDefinitions(): %w", err))
+		}
+
+		s := spinner.StartNew("Fetching " + remote + ":" + d.remote)
+		err = sshTar(remote, d.remote, dst)
+		s.Stop()
+		if err != nil {
+			fatal(fmt.Errorf("SyncDefinitions(): %w", err))
+		}
+		fmt.Println("/ Fetched " + remote + ":" + d.remote)
+
+		err = filepath.WalkDir(dst, func(path string, e os.DirEntry, err error) error {
+			if err != nil || e.IsDir() {
+				return err
+			}
+			rel, _ := filepath.Rel(dst, path)
+			if rel == d.skip {
+				return nil
+			}
+			status, err := syncFile(path, filepath.Join(d.local, rel), *force, *dryRun)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(w, "%s\t%s\n", status, filepath.Join(d.local, rel))
+			return nil
+		})
+		if err != nil {
+			fatal(fmt.Errorf("SyncDefinitions(): %w", err))
+		}
+	}
+	w.Flush()
+}
+
+// Standby replicate jails to a warm standby host and activate replicas on failover
+type Standby struct{}
+
+func (Standby) Name() string { return "standby" }
+func (Standby) Synopsis() string {
+	return "Replicate jails to a warm standby host and activate them on failover."
+}
+func (Standby) Usage() string {
+	return `standby setup [-f] 'user@host' 'jail name' ['jail name2' ... ]
+standby run [-interval 'duration'] ['jail name' ... ]
+standby status
+standby remove 'jail name' ['jail name2' ... ]
+standby activate [-f] 'jail name'`
+}
+
+func (Standby) Run(args []string) {
+
+	fset := newFlagSet(args[0])
+	force := fset.Bool("f", false, "Run without prompting for confirmation.")
+	interval := fset.Duration("interval", 0, "Replicate continuously with this interval, ex: 5m. Default run once.")
+
+	if len(args) < 2 || strings.HasPrefix(args[1], "-") {
+		fset.Parse(args[1:]) // -h
+		help()
+	}
+	action := args[1]
+	fset.Parse(args[2:])
+	args = fset.Args()
+
+	if action != "status" && system.NotRoot() {
+		fatal(jmgrError(exitNeedRoot, "Need root to manage standby replication.", hintRoot))
+	}
+
+	var cfg Jmgr = jmgrInit()
+
+	switch action {
+
+	case "setup":
+		if len(args) < 2 {
+			help()
+		}
+		remote := args[0]
+
+		rcfg, err := remoteConfig(remote)
+		if err != nil {
+			fatal(err)
+		}
+
+		for _, name := range args[1:] {
+			if !cfg.exist(name) {
+				fatal(errNoJail(name))
+			}
+			jail := cfg.jail(name)
+			if len(jail.Dataset) == 0 {
+				fatal(jmgrError(exitConflict, "Jail "+name+" is not on ZFS, standby replication needs a ZFS dataset.", ""))
+			}
+			if len(jail.Meta.Standby) > 0 && jail.Meta.Standby != remote {
+				fatal(jmgrError(exitConflict, "Jail "+name+" is already replicated to "+jail.Meta.Standby+".", "Run 'jmgr standby remove "+name+"' first."))
+			}
+		}
+
+		if !*force {
+			askExitOnNo("Replicate " + strings.Join(args[1:], ", ") + " to " + remote + " (yes/No)? ")
+		}
+
+		for _, name := range args[1:] {
+			jail := cfg.jail(name)
+			jail.Meta.Standby = remote
+			err := cfg.replicate(&jail, rcfg)
+			if err != nil {
+				fatal(err)
+			}
+		}
+
+	case "run":
+		for {
+			rcfgs := make(map[string]Jmgr)
+			for _, jail := range cfg.Jails {
+				if len(jail.Meta.Standby) == 0 || (len(args) > 0 && !slices.Contains(args, jail.Name)) {
+					continue
+				}
+
+				rcfg, ok := rcfgs[jail.Meta.Standby]
+				if !ok {
+					var err error
+					rcfg, err = remoteConfig(jail.Meta.Standby)
+					if err != nil {
+						fmt.Println("Jail "+jail.Name+":", err.Error())
+						continue
+					}
+					rcfgs[jail.Meta.Standby] = rcfg
+				}
+
+				err := cfg.replicate(&jail, rcfg)
+				if err != nil {
+					fmt.Println("Jail "+jail.Name+":", err.Error())
+				}
+			}
+
+			if *interval == 0 {
+				break
+			}
+			time.Sleep(*interval)
+			cfg = jmgrInit()
+		}
+
+	case "status":
+		var rowsFmt string = "%s\t%s\t%s\t%s\n"
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, rowsFmt, "Name", "Standby", "Last sync", "Lag")
+		for _, jail := range cfg.Jails {
+			switch {
+			case len(jail.Meta.Standby) > 0:
+				fmt.Fprintf(w, rowsFmt, jail.Name, jail.Meta.Standby, jail.Meta.StandbySynced, standbyLag(jail.Meta.StandbySynced))
+			case len(jail.Meta.StandbyOf) > 0:
+				fmt.Fprintf(w, rowsFmt, jail.Name, "replica of "+jail.Meta.StandbyOf, jail.Meta.StandbySynced, standbyLag(jail.Meta.StandbySynced))
+			}
+		}
+		w.Flush()
+
+	case "remove":
+		if len(args) < 1 {
+			help()
+		}
+		for _, name := range args {
+			if !cfg.exist(name) {
+				fatal(errNoJail(name))
+			}
+			jail := cfg.jail(name)
+			if len(jail.Meta.StandbySnapshot) > 0 {
+				_, err := system.Run("/sbin/zfs", []string{"destroy", jail.Meta.StandbySnapshot})
+				if err != nil {
+					fmt.Println("Jail "+jail.Name+":", err.Error())
+				}
+			}
+			jail.Meta.Standby = ""
+			jail.Meta.StandbySnapshot = ""
+			jail.Meta.StandbySynced = ""
+			err := cfg.writeMeta(jail.Name, jail.Meta)
+			if err != nil {
+				fatal(err)
+			}
+			fmt.Println("Jail", jail.Name, "is no longer replicated.")
+		}
+
+	case "activate":
+		if len(args) != 1 {
+			help()
+		}
+		if !cfg.exist(args[0]) {
+			fatal(errNoJail(args[0]))
+		}
+		jail := cfg.jail(args[0])
+		if len(jail.Meta.StandbyOf) == 0 {
+			fatal(jmgrError(exitConflict, "Jail "+jail.Name+" is not a standby replica.", ""))
+		}
+
+		if !*force {
+			fmt.Println("Jail", jail.Name, "replica of", jail.Meta.StandbyOf, "last sync", jail.Meta.StandbySynced)
+			askExitOnNo("Make sure " + jail.Name + " is stopped on " + jail.Meta.StandbyOf + ". Activate (yes/No)? ")
+		}
+
+		dataset := cfg.ZFSdataSet + "/" + jail.Name
+		if b, err := system.Run("/sbin/zfs", []string{"get", "-H", "-o", "value", "mounted", dataset}); err == nil && string(bytes.TrimRight(b, "\n")) != "yes" {
+			_, err := system.Run("/sbin/zfs", []string{"mount", dataset})
+			if err != nil {
+				fatal(err)
+			}
+		}
+
+		primary := jail.Meta.StandbyOf
+		jail.Meta.StandbyOf = ""
+		err := cfg.writeMeta(jail.Name, jail.Meta)
+		if err != nil {
+			fatal(err)
+		}
+
+		err = startstop("start", &jail)
+		if err != nil {
+			fatal(err)
+		}
+		fmt.Println("Jail", jail.Name, "activated, replication from "+primary+" is refused from now on.")
+		fmt.Println("On " + primary + " stop replicating it with: jmgr standby remove " + jail.Name)
+
+	default:
+		help()
+	}
+}
+
+// Tag list, add or remove (-d) jail tags or dependencies. A tag groups jails, ex: 'jmgr snapshot @tag'
+type Tag struct{}
+
+func (Tag) Name() string { return "tag" }
+func (Tag) Synopsis() string {
+	return "List, add or remove jail tags, dependencies or pkg repositories."
+}
+func (Tag) Usage() string {
+	return `tag [-d] 'jail name' ['tag' 'tag2' ... ]
+depend [-d] 'jail name' ['jail name it depends on' ... ]
+repo [-d] 'jail name' ['repository' 'repository2' ... ]`
+}
+
+func (Tag) Run(args []string) {
+
+	fset := newFlagSet(args[0])
+	remove := fset.Bool("d", false, "Remove the tag[s] or dependencies from the jail.")
+	fset.Parse(args[1:])
+	args = append([]string{args[0]}, fset.Args()...)
+
+	needRoot := len(args) > 2
+	cfg, jail, err := verifyArgs(2, 1, needRoot, true, args)
+	if err != nil {
+		fatal(err)
+	}
+
+	list := &jail.Meta.Tags
+	switch args[0] {
+	case "depend":
+		list = &jail.Meta.Depends
+	case "repo":
+		list = &jail.Meta.PkgRepos
+	}
+
+	for _, item := range args[2:] {
+		switch args[0] {
+		case "tag":
+			item = strings.TrimPrefix(item, "@")
+			if !validTag(item) {
+				fatal(jmgrError(exitUsage, "Not a valid tag: "+item+".", "Use letters, digits, '-' and '_'."))
+			}
+		case "depend":
+			if !*remove && !cfg.exist(item) {
+				fatal(errNoJail(item))
+			}
+			if item == jail.Name {
+				fatal(jmgrError(exitUsage, "Jail "+item+" can't depend on itself.", ""))
+			}
+		case "repo":
+			if _, ok := cfg.PkgRepos[item]; !*remove && !ok {
+				fatal(jmgrError(exitConfig, "No pkg repository "+item+" in PkgRepos in the jmgr config.", "See the repositories with: jmgr config get PkgRepos"))
+			}
+		}
+		if *remove {
+			*list = slices.DeleteFunc(*list, func(t string) bool { return t == item })
+		} else if !slices.Contains(*list, item) {
+			*list = append(*list, item)
+		}
+	}
+
+	if needRoot {
+		slices.Sort(*list)
+		if args[0] == "depend" {
+			i := cfg.jIndex(jail.Name)
+			cfg.Jails[i].Meta.Depends = *list
+			if _, err := cfg.startOrder(cfg.Jails); err != nil {
+				fatal(err)
+			}
+		}
+		err = cfg.writeMeta(jail.Name, jail.Meta)
+		if err != nil {
+			fatal(err)
+		}
+		if args[0] == "repo" {
+			err = cfg.writePkgRepos(jail.Path, *list)
+			if err != nil {
+				fatal(err)
+			}
+		}
+	}
+	fmt.Println(jail.Name+":", strings.Join(*list, " "))
+}
+
+// Publish export a jail as a layered OCI image and push it to a registry
+type Publish struct{}
+
+func (Publish) Name() string     { return "publish" }
+func (Publish) Synopsis() string { return "Publish a jail as an OCI image to a registry." }
+func (Publish) Usage() string    { return "publish [-f] [-insecure] 'jail name' 'registry/name:tag'" }
+
+func (Publish) Run(args []string) {
+
+	fset := newFlagSet(args[0])
+	force := fset.Bool("f", false, "Publish without prompting for confirmation.")
+	insecure := fset.Bool("insecure", false, "Use http instead of https to the registry.")
+	fset.Parse(args[1:])
+	args = append([]string{args[0]}, fset.Args()...)
+
+	cfg, jail, err := verifyArgs(3, 1, true, true, args)
+	if err != nil {
+		fatal(err)
+	}
+
+	if len(jail.Parent) > 0 {
+		fatal(jmgrError(exitConflict, "Jail "+jail.Name+" is a child of "+jail.Parent+", Can't continue.", "Manage it from the parent jail "+jail.Parent+"."))
+	}
+
+	ref, err := parseImageRef(args[2], *insecure)
+	if err != nil {
+		fatal(err)
+	}
+
+	if !*force {
+		askExitOnNo("Publish " + jail.Name + " as " + ref.String() + " (yes/No)? ")
+	}
+	if len(jail.Dataset) == 0 && jail.Runs() && !*force {
+		askExitOnNo("Jail " + jail.Name + " is running, publish anyway (yes/No)? ")
+	}
+
+	// fatal exits without the deferred cleanup, publish returns first
+	if err := publish(cfg, jail, ref); err != nil {
+		fatal(err)
+	}
+	fmt.Println("Jail", jail.Name, "published as", ref.String())
+}
+
+// publish push the jail as an image to ref, from a snapshot if possible, else from the (preferably stopped) jail
+// filesystem. The snapshot and the layer files are removed on every return
+func publish(cfg *Jmgr, jail *Jail, ref *imageRef) error {
+
+	root := jail.Path
+	if len(jail.Dataset) > 0 {
+		snap, err := zfs.SnapshotPrefix(jail.Dataset, "jmgr-publish-")
+		if err != nil {
+			return err
+		}
+		defer system.Run("/sbin/zfs", []string{"destroy", snap})
+		root = jail.Path + "/.zfs/snapshot/" + strings.SplitN(snap, "@", 2)[1]
+	}
+
+	layers, err := buildLayers(root, cfg.OsMediaDir)
+	for _, l := range layers {
+		defer os.Remove(l.File)
+	}
+	if err != nil {
+		return err
+	}
+
+	config, err := imageConfig(cfg, jail, layers)
+	if err != nil {
+		return err
+	}
+	return ref.push(layers, config)
+}
+
+// Pull a published jail image to the local image store, only layers not already in the store are downloaded
+type Pull struct{}
+
+func (Pull) Name() string     { return "pull" }
+func (Pull) Synopsis() string { return "Pull a jail image to the local image store." }
+func (Pull) Usage() string    { return "pull [-insecure] 'registry/name:tag'" }
+
+func (Pull) Run(args []string) {
+
+	fset := newFlagSet(args[0])
+	insecure := fset.Bool("insecure", false, "Use http instead of https to the registry.")
+	fset.Parse(args[1:])
+	args = fset.Args()
+
+	if len(args) != 1 || args[0] == "help" || args[0] == "-h" {
+		help()
+	}
+
+	if system.NotRoot() {
+		fatal(jmgrError(exitNeedRoot, "Need root to pull images.", hintRoot))
+	}
+
+	var cfg Jmgr = jmgrInit()
+
+	ref, err := parseImageRef(args[0], *insecure)
+	if err != nil {
+		fatal(err)
+	}
+
+	_, config, err := ref.pull(cfg.imageStore())
+	if err != nil {
+		fatal(err)
+	}
+	fmt.Println("Image", ref.String(), "OS version", config.Config.Labels["org.jmgr.osversion"], "pulled.")
+}
+
+// Apply converge the jails on this host to the desired state in a manifest
+type Apply struct{}
+
+func (Apply) Name() string     { return "apply" }
+func (Apply) Synopsis() string { return "Converge the jails on this host to a manifest." }
+func (Apply) Usage() string    { return "apply [-f] [-n] [-json] -manifest 'jails.yml'" }
+
+func (Apply) Run(args []string) {
+
+	fset := newFlagSet(args[0])
+	force := fset.Bool("f", false, "Apply without prompting for confirmation.")
+	dryRun := fset.Bool("n", false, "Dry run, only report the changes.")
+	wantJson := fset.Bool("json", false, "Print the result in JSON format.")
+	file := fset.String("manifest", "", "Manifest (YAML) with the desired jails.")
+	fset.Parse(args[1:])
+
+	if len(*file) == 0 {
+		help()
+	}
+
+	if !*dryRun && system.NotRoot() {
+		fatal(jmgrError(exitNeedRoot, "Need root to apply a manifest.", hintRoot))
+	}
+
+	manifest, err := readManifest(*file)
+	if err != nil {
+		fatal(err)
+	}
+
+	if !*force && !*dryRun {
+		askExitOnNo("Apply " + *file + " (yes/No)? ")
+	}
+
+	result := converge(manifest, *dryRun)
+
+	if jsonOutput {
+		printJSON(result)
+	} else if *wantJson {
+		b, err := json.Marshal(result)
+		if err != nil {
+			fatal(fmt.Errorf("Problem with JSON encode: %w", err))
+		}
+		fmt.Println(string(b[:]))
+	} else {
+		for _, c := range result.Changes {
+			fmt.Println(c)
+		}
+		for _, e := range result.Errors {
+			fmt.Println("Error:", e)
+		}
+		if len(result.Changes) == 0 && len(result.Errors) == 0 {
+			fmt.Println("No changes.")
+		}
+	}
+
+	if len(result.Errors) > 0 {
+		exit(1)
+	}
+}
+
+// Fleet apply a manifest to many hosts over ssh and aggregate the results
+type Fleet struct{}
+
+func (Fleet) Name() string     { return "fleet" }
+func (Fleet) Synopsis() string { return "Apply a manifest to many jmgr hosts over ssh." }
+func (Fleet) Usage() string {
+	return "fleet apply [-f] [-n] [-parallel 'n'] -hosts 'hosts.yml' -manifest 'jails.yml'"
+}
+
+func (Fleet) Run(args []string) {
+
+	fset := newFlagSet(args[0])
+	force := fset.Bool("f", false, "Apply without prompting for confirmation.")
+	dryRun := fset.Bool("n", false, "Dry run, only report the changes.")
+	hostsFile := fset.String("hosts", "", "Hosts (YAML), 'Hosts: [ user@host, ... ]'.")
+	file := fset.String("manifest", "", "Manifest (YAML) with the desired jails.")
+	parallel := fset.Int("parallel", 4, "Number of hosts to apply in parallel.")
+
+	if len(args) < 2 || args[1] != "apply" {
+		fset.Parse(args[1:]) // -h
+		help()
+	}
+	fset.Parse(args[2:])
+
+	if len(*hostsFile) == 0 || len(*file) == 0 || *parallel < 1 {
+		help()
+	}
+
+	var hosts struct {
+		Hosts []string `yaml:"Hosts"`
+	}
+	b, err := os.ReadFile(*hostsFile)
+	if err != nil {
+		fatal(err)
+	}
+	if err := yaml.UnmarshalStrict(b, &hosts); err != nil {
+		fatal(jmgrError(exitConfig, "Problem decoding "+*hostsFile+": "+err.Error(), ""))
+	}
+	if len(hosts.Hosts) == 0 {
+		fatal(jmgrError(exitConfig, "No hosts in "+*hostsFile, ""))
+	}
+
+	// validate the manifest before it is sent anywhere
+	if _, err := readManifest(*file); err != nil {
+		fatal(err)
+	}
+	manifest, err := os.ReadFile(*file)
+	if err != nil {
+		fatal(err)
+	}
+
+	if !*force && !*dryRun {
+		askExitOnNo("Apply " + *file + " to " + strconv.Itoa(len(hosts.Hosts)) + " hosts (yes/No)? ")
+	}
+
+	remoteArgs := []string{"jmgr", "apply", "-f", "-json"}
+	if *dryRun {
+		remoteArgs = append(remoteArgs, "-n")
+	}
+
+	results := make([]ApplyResult, len(hosts.Hosts))
+	sem := make(chan struct{}, *parallel)
+	var wg sync.WaitGroup
+	for i, host := range hosts.Hosts {
+		wg.Add(1)
+		go func(i int, host string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i] = remoteApply(host, manifest, remoteArgs)
+		}(i, host)
+	}
+	wg.Wait()
+
+	var failed int
+	var rowsFmt string = "%s\t%s\t%d\t%s\n"
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", "Host", "Status", "Changes", "Errors")
+	for _, r := range results {
+		status := "ok"
+		if len(r.Errors) > 0 {
+			status = "failed"
+			failed++
+		}
+		fmt.Fprintf(w, rowsFmt, r.Host, status, len(r.Changes), strings.Join(r.Errors, "; "))
+	}
+	w.Flush()
+
+	if failed > 0 {
+		fatal(jmgrError(exitCommand, strconv.Itoa(failed)+" of "+strconv.Itoa(len(results))+" hosts failed.", ""))
+	}
+}
+
+// Pkg run package operations in a jail with pkg -j, a stopped jail is started for the operation and stopped again
+type Pkg struct{}
+
+func (Pkg) Name() string     { return "pkg" }
+func (Pkg) Synopsis() string { return "Run a pkg command in a jail." }
+func (Pkg) Usage() string {
+	return "pkg [-f] 'jail name' install|delete|search|info|query|... [pkg arguments ...]"
+}
+
+func (Pkg) Run(args []string) {
+
+	fset := newFlagSet(args[0])
+	force := fset.Bool("f", false, "Start a stopped jail and install/delete without prompting for confirmation.")
+	fset.Parse(args[1:])
+	args = fset.Args()
+
+	_, jail, err := verifyArgs(2, 0, true, true, args)
+	if err != nil {
+		fatal(err)
+	}
+
+	if len(jail.Parent) > 0 {
+		fatal(jmgrError(exitConflict, "Jail "+jail.Name+" is a child of "+jail.Parent+", Can't continue.", "Manage it from the parent jail "+jail.Parent+"."))
+	}
+
+	pkgArgs := []string{"-j", jail.Name, args[1]}
+	switch args[1] {
+	case "install", "delete", "remove", "upgrade", "autoremove":
+		if *force {
+			pkgArgs = append(pkgArgs, "-y")
+		}
+	case "search", "info", "query", "version", "which", "audit", "clean", "update", "check", "lock", "unlock":
+	default:
+		fatal(jmgrError(exitUsage, "pkg "+args[1]+" is not supported.", "Use: install, delete, remove, upgrade, autoremove, search, info, query, version, which, audit, clean, update, check, lock or unlock"))
+	}
+	pkgArgs = append(pkgArgs, args[2:]...)
+
+	started := false
+	if !jail.Runs() {
+		if !*force {
+			askExitOnNo("Start (needed for pkg) " + jail.Name + " (yes/No)? ")
+		}
+		err := startstop("start", jail)
+		if err != nil {
+			fatal(fmt.Errorf("Pkg: %w", err))
+		}
+		started = true
+	}
+
+	if !hasPkg(jail) {
+		if *force || askYes("pkg is not installed in "+jail.Name+", bootstrap pkg (yes/No)? ") {
+			s := spinner.StartNew("Bootstrap pkg in " + jail.Name)
+			err = pkgBootstrap(jail)
+			s.Stop()
+			if err != nil {
+				fmt.Println(err.Error())
+			} else {
+				fmt.Println("/ Bootstrap completed.")
+			}
+		}
+	}
+
+	err = system.RunStdin("/usr/sbin/pkg", pkgArgs)
+	if started {
+		if err := startstop("stop", jail); err != nil {
+			fmt.Println("Pkg: stop " + jail.Name + ": " + err.Error())
+		}
+	}
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			exit(exitErr.ExitCode())
+		}
+		fatal(fmt.Errorf("pkg finished with error: %w", err))
+	}
+}
+
+// Status summary of the host and its jails, ex: for the login message or a cron report
+type Status struct{}
+
+func (Status) Name() string     { return "status" }
+func (Status) Synopsis() string { return "Summary of the host and its jails." }
+func (Status) Usage() string    { return "status [-updates] [-snapshot-age 'age']" }
+
+// HostStatus the result of 'jmgr status'
+type HostStatus struct {
+	Release    string            `json:"release"`          // host FreeBSD release
+	Eol        string            `json:"eol,omitempty"`    // EOL or EOL soon and the date, empty if supported or unknown
+	Pool       string            `json:"pool,omitempty"`   // ZFS pool of ZFSdataSet, or the file system of JailsHome
+	Size       int64             `json:"size"`             // bytes
+	Free       int64             `json:"free"`             // bytes
+	Health     string            `json:"health,omitempty"` // pool health, ex: ONLINE
+	Jails      int               `json:"jails"`            // children are not counted
+	Running    int               `json:"running"`
+	Stopped    int               `json:"stopped"`
+	Enabled    int               `json:"enabled"`            // start at boot
+	Updates    []UpdateCheck     `json:"updates"`            // jails with a pending base patch or package upgrades, nil if unknown
+	EolJails   map[string]string `json:"eol_jails"`          // jail -> EOL or EOL soon
+	Snapshot   string            `json:"snapshot,omitempty"` // time (RFC3339) of the newest snapshot of all jails
+	NoSnapshot []string          `json:"no_snapshot"`        // ZFS jails without a snapshot in -snapshot-age
+	BootFailed []string          `json:"boot_failed"`        // enabled, not running and not stopped by jmgr since boot
+}
+
+func (Status) Run(args []string) {
+
+	fset := newFlagSet(args[0])
+	updates := fset.Bool("updates", false, "Check the jails for pending updates now, default the last check of jmgr daemon.")
+	age := fset.String("snapshot-age", "7d", "List the ZFS jails without a newer snapshot.")
+	fset.Parse(args[1:])
+	maxAge, err := parseAge(*age)
+	if fset.NArg() > 0 || err != nil {
+		help()
+	}
+
+	cfg := jmgrInit()
+	st := HostStatus{EolJails: map[string]string{}}
+	st.Release, _ = hostVersion()
+	eol, _ := cfg.eolTable(true)
+	if status, date := eolStatus(st.Release, eol); len(status) > 0 {
+		st.Eol = status + " " + date
+	}
+	st.Pool, st.Size, st.Free, st.Health = cfg.poolSpace()
+
+	boot := bootTime()
+	var newest time.Time
+	var jails []Jail
+	for _, jail := range cfg.Jails {
+		if len(jail.Parent) > 0 {
+			continue
+		}
+		jails = append(jails, jail)
+		st.Jails++
+		if jail.Runs() {
+			st.Running++
+		} else {
+			st.Stopped++
+		}
+		if jail.OnBoot == "Yes" {
+			st.Enabled++
+			stopped, err := time.Parse(time.RFC3339, jail.Meta.Stopped)
+			if !jail.Runs() && len(jail.Meta.StandbyOf) == 0 && (err != nil || stopped.Before(boot)) {
+				st.BootFailed = append(st.BootFailed, jail.Name)
+			}
+		}
+		if status, _ := eolStatus(jail.OsVersion, eol); len(status) > 0 {
+			st.EolJails[jail.Name] = status
+		}
+		if len(jail.Dataset) > 0 {
+			created, ok := zfs.NewestSnapshot(jail.Dataset)
+			if created.After(newest) {
+				newest = created
+			}
+			if !ok || time.Since(created) > maxAge {
+				st.NoSnapshot = append(st.NoSnapshot, jail.Name)
+			}
+		}
+	}
+	if !newest.IsZero() {
+		st.Snapshot = newest.Format(time.RFC3339)
+	}
+
+	// the pending updates are checked by jmgr daemon -metrics, or now
+	var checks map[string]UpdateCheck
+	if *updates {
+		if system.NotRoot() {
+			fatal(errNeedRoot)
+		}
+		checks = make(map[string]UpdateCheck)
+		s := spinner.StartNew("Check for updates on " + strconv.Itoa(len(jails)) + " jails")
+		for i := range jails {
+			checks[jails[i].Name] = cfg.checkJail(&jails[i])
+		}
+		s.Stop()
+	} else {
+		checks = daemonUpdates()
+	}
+	if checks != nil {
+		st.Updates = []UpdateCheck{}
+		for _, jail := range jails {
+			if c, ok := checks[jail.Name]; ok && (len(c.Patch) > 0 || c.Pkgs > 0) {
+				st.Updates = append(st.Updates, c)
+			}
+		}
+	}
+
+	if jsonOutput {
+		printJSON(st)
+		return
+	}
+	printStatus(st, maxAge)
+}
+
+// printStatus print the host status, one line per topic
+func printStatus(st HostStatus, maxAge time.Duration) {
+
+	host, _ := os.Hostname()
+	release := st.Release
+	if len(st.Eol) > 0 {
+		release += " (" + st.Eol + ")"
+	}
+	fmt.Println(host + ": FreeBSD " + release)
+	if st.Size > 0 {
+		line := "Pool " + st.Pool + ": " + fmtBytes(st.Free) + " free of " + fmtBytes(st.Size)
+		if len(st.Health) > 0 {
+			line += ", " + st.Health
+		}
+		fmt.Println(line)
+	}
+	fmt.Printf("Jails: %d, running %d, stopped %d, enabled %d\n", st.Jails, st.Running, st.Stopped, st.Enabled)
+	if len(st.BootFailed) > 0 {
+		fmt.Println("Failed to start at boot: " + strings.Join(st.BootFailed, ", "))
+	}
+
+	switch {
+	case st.Updates == nil:
+		fmt.Println("Pending updates: unknown, see: jmgr status -updates")
+	case len(st.Updates) == 0:
+		fmt.Println("Pending updates: none")
+	default:
+		var pending []string
+		for _, u := range st.Updates {
+			var what []string
+			if len(u.Patch) > 0 {
+				what = append(what, u.Patch)
+			}
+			if u.Pkgs > 0 {
+				what = append(what, strconv.Itoa(u.Pkgs)+" pkgs")
+			}
+			pending = append(pending, u.Name+" ("+strings.Join(what, ", ")+")")
+		}
+		fmt.Println("Pending updates: " + strings.Join(pending, ", "))
+	}
+
+	if len(st.EolJails) > 0 {
+		var eol []string
+		for name, status := range st.EolJails {
+			eol = append(eol, name+" ("+status+")")
+		}
+		slices.Sort(eol)
+		fmt.Println("End of life: " + strings.Join(eol, ", "))
+	}
+
+	if t, err := time.Parse(time.RFC3339, st.Snapshot); err == nil {
+		fmt.Println("Last snapshot: " + fmtAge(time.Since(t)) + " ago")
+	}
+	if len(st.NoSnapshot) > 0 {
+		fmt.Println("No snapshot in " + fmtAge(maxAge) + ": " + strings.Join(st.NoSnapshot, ", "))
+	}
+}
+
+// poolSpace return the ZFS pool of ZFSdataSet with its size, free bytes and health, or the file system of JailsHome
+func (cfg *Jmgr) poolSpace() (string, int64, int64, string) {
+
+	if cfg.useZFS {
+		pool, _, _ := strings.Cut(cfg.ZFSdataSet, "/")
+		b, err := system.Run("/sbin/zpool", []string{"list", "-Hp", "-o", "size,free,health", pool})
+		if f := strings.Fields(string(b)); err == nil && len(f) == 3 {
+			size, _ := strconv.ParseInt(f[0], 10, 64)
+			free, _ := strconv.ParseInt(f[1], 10, 64)
+			return pool, size, free, f[2]
+		}
+		return pool, 0, 0, ""
+	}
+	// Filesystem 1024-blocks Used Avail Capacity Mounted on
+	b, err := system.Run("/bin/df", []string{"-k", cfg.JailsHome})
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	if f := strings.Fields(lines[len(lines)-1]); err == nil && len(lines) == 2 && len(f) >= 6 {
+		size, _ := strconv.ParseInt(f[1], 10, 64)
+		free, _ := strconv.ParseInt(f[3], 10, 64)
+		return f[len(f)-1], size * 1024, free * 1024, ""
+	}
+	return cfg.JailsHome, 0, 0, ""
+}
+
+// bootTime return when the host booted, kern.boottime, zero time if unknown
+func bootTime() time.Time {
+
+	// { sec = 1718000000, usec = 123456 } Mon Jun 10 08:13:20 2024
+	b, err := system.Run("/sbin/sysctl", []string{"-n", "kern.boottime"})
+	if err != nil {
+		return time.Time{}
+	}
+	m := regexp.MustCompile(`sec = ([0-9]+)`).FindSubmatch(b)
+	if m == nil {
+		return time.Time{}
+	}
+	secs, _ := strconv.ParseInt(string(m[1]), 10, 64)
+	return time.Unix(secs, 0)
+}
+
+// daemonUpdates return the pending updates by jail from jmgr daemon, nil if it is not running or has not checked them
+func daemonUpdates() map[string]UpdateCheck {
+
+	socket := daemonSocket
+	if len(socket) == 0 {
+		socket = defaultSocket
+	}
+	client, err := jsonrpc.Dial("unix", socket)
+	if err != nil {
+		return nil
+	}
+	defer client.Close()
+	var updates map[string]UpdateCheck
+	if err := client.Call("Jmgr.Updates", struct{}{}, &updates); err != nil || len(updates) == 0 {
+		return nil
+	}
+	return updates
+}
+
+// Check a jail as a monitoring plugin (Nagios, Icinga, Zabbix): one line status and exit 0 OK, 1 WARNING, 2 CRITICAL, 3 UNKNOWN
+type Check struct{}
+
+func (Check) Name() string { return "check" }
+func (Check) Synopsis() string {
+	return "Monitoring plugin: check the state, snapshot age and vulnerabilities of a jail."
+}
+func (Check) Usage() string {
+	return "check -jail 'jail name' [-expect running|stopped] [-warn-snapshot-age 'age'] [-max-snapshot-age 'age'] [-warn-pending-vulns 'n'] [-max-pending-vulns 'n']"
+}
+
+func (Check) Run(args []string) {
+
+	fset := newFlagSet(args[0])
+	name := fset.String("jail", "", "Jail to check.")
+	expect := fset.String("expect", "", "CRITICAL if the jail is not running or stopped.")
+	warnAge := fset.String("warn-snapshot-age", "", "WARNING if the newest snapshot is older, ex: 1d")
+	maxAge := fset.String("max-snapshot-age", "", "CRITICAL if the newest snapshot is older, ex: 7d")
+	warnVulns := fset.Int("warn-pending-vulns", -1, "WARNING if more packages are vulnerable, pkg audit.")
+	maxVulns := fset.Int("max-pending-vulns", -1, "CRITICAL if more packages are vulnerable, pkg audit.")
+	fset.Parse(args[1:])
+
+	codes := []string{"OK", "WARNING", "CRITICAL", "UNKNOWN"}
+	code := 0
+	var msgs, perf []string
+	exit := func() {
+		status := "JMGR " + codes[code] + " - " + *name + ": " + strings.Join(msgs, ", ")
+		if len(perf) > 0 {
+			status += " | " + strings.Join(perf, " ")
+		}
+		fmt.Println(status)
+		printJSON(map[string]any{"status": codes[code], "code": code, "messages": msgs})
+		exit(code)
+	}
+	// raise the status, UNKNOWN does not hide a CRITICAL
+	rank := []int{0, 1, 3, 2}
+	raise := func(c int, msg string) {
+		if rank[c] > rank[code] {
+			code = c
+		}
+		msgs = append(msgs, msg)
+	}
+
+	if len(*name) == 0 && fset.NArg() == 1 {
+		*name = fset.Arg(0)
+	}
+	if len(*name) == 0 || !slices.Contains([]string{"", "running", "stopped"}, *expect) {
+		raise(3, "Syntax: jmgr "+Check{}.Usage())
+		exit()
+	}
+	var warn, crit time.Duration
+	for _, a := range []struct {
+		d *time.Duration
+		s string
+	}{{&warn, *warnAge}, {&crit, *maxAge}} {
+		if len(a.s) > 0 {
+			d, err := parseAge(a.s)
+			if err != nil {
+				raise(3, err.Error())
+				exit()
+			}
+			*a.d = d
+		}
+	}
+
+	cfg := jmgrInit()
+	if !cfg.exist(*name) {
+		raise(2, "does not exist")
+		exit()
+	}
+	jail := cfg.Jails[cfg.jIndex(*name)]
+
+	state := "stopped"
+	if jail.Runs() {
+		state = "running"
+	}
+	if len(*expect) > 0 && state != *expect {
+		raise(2, state+", expected "+*expect)
+	} else {
+		msgs = append(msgs, state)
+	}
+
+	if warn > 0 || crit > 0 {
+		created, ok := zfs.NewestSnapshot(jail.Dataset)
+		switch {
+		case len(jail.Dataset) == 0:
+			raise(3, "not on ZFS, no snapshots")
+		case !ok && crit > 0:
+			raise(2, "no snapshots")
+		case !ok:
+			raise(1, "no snapshots")
+		default:
+			age := time.Since(created)
+			perf = append(perf, fmt.Sprintf("snapshot_age=%ds;%s;%s", int(age.Seconds()), perfSeconds(warn), perfSeconds(crit)))
+			msg := "newest snapshot " + fmtAge(age) + " ago"
+			if crit > 0 && age > crit {
+				raise(2, msg+" > "+*maxAge)
+			} else if warn > 0 && age > warn {
+				raise(1, msg+" > "+*warnAge)
+			} else {
+				msgs = append(msgs, msg)
+			}
+		}
+	}
+
+	if *warnVulns >= 0 || *maxVulns >= 0 {
+		r := AuditResult{Status: "failed", Error: "need root capabilites to perform this task"}
+		if !system.NotRoot() {
+			r = auditJail(&jail, false)
+		}
+		switch r.Status {
+		case "failed":
+			raise(3, "pkg audit: "+r.Error)
+		case "skipped":
+			msgs = append(msgs, "vulnerabilities not checked, "+r.Error)
+		default:
+			n := len(r.Vulnerable)
+			perf = append(perf, fmt.Sprintf("vulns=%d;%s;%s", n, perfInt(*warnVulns), perfInt(*maxVulns)))
+			msg := strconv.Itoa(n) + " vulnerable packages"
+			if *maxVulns >= 0 && n > *maxVulns {
+				raise(2, msg)
+			} else if *warnVulns >= 0 && n > *warnVulns {
+				raise(1, msg)
+			} else {
+				msgs = append(msgs, msg)
+			}
+		}
+	}
+	exit()
+}
+
+// perfSeconds a performance data threshold in seconds, empty if not set
+func perfSeconds(d time.Duration) string {
+
+	if d <= 0 {
+		return ""
+	}
+	return strconv.Itoa(int(d.Seconds()))
+}
+
+// perfInt a performance data threshold, empty if not set
+func perfInt(n int) string {
+
+	if n < 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+// Audit run pkg audit in all or the named jails, exit 1 if vulnerable packages are found
+type Audit struct{}
+
+func (Audit) Name() string { return "audit" }
+func (Audit) Synopsis() string {
+	return "Audit the installed packages of jails for known vulnerabilities."
+}
+func (Audit) Usage() string { return "audit [-start] [-json] ['jail name' 'jail name2' ... ]" }
+
+func (Audit) Run(args []string) {
+
+	fset := newFlagSet(args[0])
+	start := fset.Bool("start", false, "Start stopped jails for the audit and stop them again.")
+	jsonOut := fset.Bool("json", false, "Print the result in JSON format.")
+	fset.Parse(args[1:])
+	names := fset.Args()
+
+	if system.NotRoot() {
+		fatal(errNeedRoot)
+	}
+
+	var cfg Jmgr = jmgrInit()
+	for _, name := range names {
+		if !cfg.exist(name) {
+			fatal(errNoJail(name))
+		}
+	}
+
+	var results []AuditResult
+	var s *spinner.Spinner
+	if !*jsonOut {
+		s = spinner.StartNew("Audit installed packages")
+	}
+	for _, jail := range cfg.Jails {
+		if len(jail.Parent) > 0 || (len(names) > 0 && !slices.Contains(names, jail.Name)) {
+			continue
+		}
+		results = append(results, auditJail(&jail, *start))
+	}
+	if s != nil {
+		s.Stop()
+		fmt.Println("/ Completed.")
+	}
+
+	var vulnerable, failed int
+	for _, r := range results {
+		switch r.Status {
+		case "vulnerable":
+			vulnerable++
+		case "failed":
+			failed++
+		}
+	}
+
+	if jsonOutput {
+		printJSON(results)
+	} else if *jsonOut {
+		b, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			fatal(err)
+		}
+		fmt.Println(string(b))
+	} else {
+		var rowsFmt string = "%s\t%s\t%s\t%s\n"
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, rowsFmt, "Name", "Status", "Vulnerable Packages", "Error")
+		for _, r := range results {
+			fmt.Fprintf(w, rowsFmt, r.Name, r.Status, strings.Join(r.Vulnerable, " "), r.Error)
+		}
+		w.Flush()
+	}
+
+	if vulnerable > 0 || failed > 0 {
+		exit(1)
+	}
+}
+
+// auditJail pkg audit -F for one jail, stopped jails are skipped unless start
+func auditJail(jail *Jail, start bool) AuditResult {
+
+	r := AuditResult{Name: jail.Name, Status: "ok"}
+
+	if !jail.Runs() {
+		if !start {
+			r.Status = "skipped"
+			r.Error = "not running"
+			return r
+		}
+		if err := startstop("start", jail); err != nil {
+			r.Status = "failed"
+			r.Error = err.Error()
+			return r
+		}
+		defer startstop("stop", jail)
+	}
+
+	// -q prints only the names of vulnerable packages, exit 1 if there are any
+	b, err := exec.Command(system.Tool("/usr/sbin/pkg"), "-j", jail.Name, "audit", "-F", "-q").Output()
+	var exitErr *exec.ExitError
+	if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == 1) {
+		r.Status = "failed"
+		r.Error = err.Error()
+		return r
+	}
+
+	r.Vulnerable = strings.Fields(string(b))
+	if len(r.Vulnerable) > 0 {
+		r.Status = "vulnerable"
+	} else if err != nil {
+		// exit 1 and no package names, pkg audit failed, ex: the vulnerability database could not be fetched
+		r.Status = "failed"
+		r.Error = strings.TrimSpace(string(exitErr.Stderr))
+		if len(r.Error) == 0 {
+			r.Error = err.Error()
+		}
+	}
+	return r
+}
+
+// PkgCache enable or disable the shared pkg cache, PkgCacheDir nullfs mounted on /var/cache/pkg, for a jail
+type PkgCache struct{}
+
+func (PkgCache) Name() string     { return "pkgcache" }
+func (PkgCache) Synopsis() string { return "Enable or disable the shared pkg cache for a jail." }
+func (PkgCache) Usage() string    { return "pkgcache [-d] 'jail name'" }
+
+func (PkgCache) Run(args []string) {
+
+	fset := newFlagSet(args[0])
+	remove := fset.Bool("d", false, "Disable the shared pkg cache for the jail.")
+	fset.Parse(args[1:])
+	args = append([]string{args[0]}, fset.Args()...)
+
+	cfg, jail, err := verifyArgs(2, 1, true, true, args)
+	if err != nil {
+		fatal(err)
+	}
+
+	if len(cfg.PkgCacheDir) == 0 {
+		fatal(jmgrError(exitConfig, "PkgCacheDir is not set in the jmgr config.", "Set it with: jmgr config set PkgCacheDir 'directory'"))
+	}
+	if fi, err := os.Stat(cfg.PkgCacheDir); err != nil || !fi.IsDir() {
+		fatal(jmgrError(exitConfig, "PkgCacheDir "+cfg.PkgCacheDir+" is not a directory.", "Create it first, ex: mkdir -p "+cfg.PkgCacheDir))
+	}
+	if !strings.HasPrefix(jail.ConfigPath, cfg.JailsConfD) {
+		fatal(jmgrError(exitConflict, "Jail "+jail.Name+" is not configured in "+cfg.JailsConfD+", can't continue.", ""))
+	}
+
+	b, err := os.ReadFile(jail.ConfigPath)
+	if err != nil {
+		fatal(err)
+	}
+	conf := templates.SetPkgCacheMount(string(b), cfg.pkgCacheMount(jail.Path, !*remove))
+
+	if !*remove {
+		if err := os.MkdirAll(jail.Path+"/var/cache/pkg", 0755); err != nil {
+			fatal(err)
+		}
+	}
+	if err := os.WriteFile(jail.ConfigPath, []byte(conf), 0666); err != nil {
+		fatal(err)
+	}
+
+	jail.Meta.PkgCache = !*remove
+	if err := cfg.writeMeta(jail.Name, jail.Meta); err != nil {
+		fatal(err)
+	}
+
+	state := "enabled"
+	if *remove {
+		state = "disabled"
+	}
+	fmt.Println("Shared pkg cache", state, "for", jail.Name+", restart the jail to apply.")
+}
+
+// Verify compare the jail base system with the release checksums, freebsd-update IDS or pkg check for pkgbase jails
+type Verify struct{}
+
+func (Verify) Name() string     { return "verify" }
+func (Verify) Synopsis() string { return "Report modified base system files in a jail." }
+func (Verify) Usage() string    { return "verify [-etc] 'jail name'" }
+
+func (Verify) Run(args []string) {
+
+	fset := newFlagSet(args[0])
+	etc := fset.Bool("etc", false, "Include /etc, local configuration changes are reported too.")
+	fset.Parse(args[1:])
+	args = append([]string{args[0]}, fset.Args()...)
+
+	cfg, jail, err := verifyArgs(2, 1, true, true, args)
+	if err != nil {
+		fatal(err)
+	}
+
+	if len(jail.Parent) > 0 {
+		fatal(jmgrError(exitConflict, "Jail "+jail.Name+" is a child of "+jail.Parent+", Can't continue.", "Manage it from the parent jail "+jail.Parent+"."))
+	}
+
+	var out []byte
+	s := spinner.StartNew("Verify the base system of " + jail.Name + " (" + jail.OsVersion + ")")
+	if cfg.isPkgBase(jail) {
+		// pkg check exits 1 on checksum mismatches
+		out, err = exec.Command(system.Tool("/usr/sbin/pkg"), "-r", jail.Path, "check", "-s", "-x", "^FreeBSD-").CombinedOutput()
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			err = nil
+		}
+	} else {
+		var workdir string
+		workdir, err = cfg.updateWorkdir(jail.OsVersion)
+		if err == nil {
+			unlock := lockWorkdir(workdir)
+			out, err = system.Run("/usr/bin/env", []string{
+				"UNAME_r=" + jail.OsVersion, "PAGER=/bin/cat",
+				"/usr/sbin/freebsd-update", "-b", jail.Path, "-d", workdir,
+				"--currently-running", jail.OsVersion, "IDS"})
+			unlock()
+		}
+	}
+	s.Stop()
+	fmt.Println("/ Completed.")
+	if err != nil {
+		fatal(fmt.Errorf("Verify failed: %w", err))
+	}
+
+	// freebsd-update: '/bin/sh has SHA256 hash ..., but should have ...', pkg: 'FreeBSD-runtime-15.0: checksum mismatch for /bin/sh'
+	var modified [][2]string
+	for _, line := range strings.Split(string(out), "\n") {
+		var file, problem string
+		if pkg, rest, ok := strings.Cut(line, ": checksum mismatch for "); ok {
+			file, problem = rest, "checksum mismatch ("+pkg+")"
+		} else if f, rest, ok := strings.Cut(line, " "); ok && strings.HasPrefix(f, "/") && strings.Contains(rest, "should") {
+			file, problem = f, rest
+		} else {
+			continue
+		}
+		file = "/" + strings.TrimPrefix(strings.TrimPrefix(file, jail.Path), "/")
+		if !*etc && strings.HasPrefix(file, "/etc/") {
+			continue
+		}
+		modified = append(modified, [2]string{file, problem})
+	}
+
+	type modifiedFile struct {
+		File    string `json:"file"`
+		Problem string `json:"problem"`
+	}
+	files := []modifiedFile{}
+	for _, m := range modified {
+		files = append(files, modifiedFile{m[0], m[1]})
+	}
+
+	if len(modified) == 0 {
+		fmt.Println("No modified system files in " + jail.Name + ".")
+		printJSON(files)
+		return
+	}
+
+	var rowsFmt string = "%s\t%s\n"
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, rowsFmt, "File", "Problem")
+	for _, m := range modified {
+		fmt.Fprintf(w, rowsFmt, m[0], m[1])
+	}
+	w.Flush()
+	jsonResult = files
+	fatal(jmgrError(exitError, strconv.Itoa(len(modified))+" modified system files in "+jail.Name+".", ""))
+}
+
+// Completion emit a bash, zsh or fish completion script, the scripts call the hidden 'jmgr __complete'
+type Completion struct{}
+
+func (Completion) Name() string     { return "completion" }
+func (Completion) Synopsis() string { return "Print a shell completion script." }
+func (Completion) Usage() string    { return "completion bash|zsh|fish" }
+
+func (Completion) Run(args []string) {
+
+	fset := newFlagSet(args[0])
+	fset.Parse(args[1:])
+	args = fset.Args()
+
+	if len(args) != 1 {
+		help()
+	}
+
+	switch args[0] {
+	case "bash":
+		fmt.Print(`# bash completion for jmgr(8), jmgr completion bash > /usr/local/etc/bash_completion.d/jmgr
+_jmgr() {
+	local IFS=$'\n'
+	COMPREPLY=( $(jmgr __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null) )
+}
+complete -F _jmgr jmgr
+`)
+	case "zsh":
+		fmt.Print(`#compdef jmgr
+# zsh completion for jmgr(8), jmgr completion zsh > /usr/local/share/zsh/site-functions/_jmgr
+_jmgr() {
+	local -a completions
+	completions=("${(@f)$(jmgr __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+	compadd -a completions
+}
+compdef _jmgr jmgr
+`)
+	case "fish":
+		fmt.Print(`# fish completion for jmgr(8), jmgr completion fish > ~/.config/fish/completions/jmgr.fish
+function __jmgr_complete
+	set -l tokens (commandline -opc)
+	jmgr __complete $tokens[2..-1] (commandline -ct) 2>/dev/null
+end
+complete -c jmgr -f -a '(__jmgr_complete)'
+`)
+	default:
+		fatal(jmgrError(exitUsage, "Unknown shell "+args[0]+".", "Use bash, zsh or fish."))
+	}
+}
+
+// GenMan print a mdoc jmgr(8) generated from the subcommands Name, Synopsis, Usage and flags
+type GenMan struct{}
+
+func (GenMan) Name() string     { return "gen-man" }
+func (GenMan) Synopsis() string { return "Print a jmgr(8) manual page generated from the subcommands." }
+func (GenMan) Usage() string    { return "gen-man" }
+
+func (GenMan) Run(args []string) {
+
+	fset := newFlagSet(args[0])
+	fset.Parse(args[1:])
+
+	// one entry per provider, ex: start, stop and restart are StartStop
+	var names []string
+	seen := make(map[string]bool)
+	for _, p := range SubC {
+		if !seen[p.Name()] {
+			seen[p.Name()] = true
+			names = append(names, p.Name())
+		}
+	}
+	slices.Sort(names)
+
+	fmt.Print(`.\" Generated by jmgr gen-man, do not edit.
+.Dd ` + time.Now().Format("January 2, 2006") + `
+.Dt JMGR 8
+.Os
+.Sh NAME
+.Nm jmgr
+.Nd yet another jail management tool
+.Sh SYNOPSIS
+.Nm
+.Op Fl json
+.Ar subcommand
+.Op Ar options
+.Op Ar arguments
+.Nm
+.Op Fl json
+.Ar jail
+.Sh DESCRIPTION
+.Nm
+is a FreeBSD jail management tool wich uses userland commands for basic administration of jails.
+With
+.Fl json
+before the subcommand, the output is one JSON object on stdout, {"result": ..., "error": "..."}.
+.Nm
+.Cm help
+.Ar subcommand
+prints the usage of a subcommand.
+.Sh SUBCOMMANDS
+.Bl -tag -width Ds
+`)
+	for _, name := range names {
+		p := SubC[name]
+		fmt.Printf(".It Cm %s\n%s\n.Bd -literal -offset indent\n", name, mdocText(p.Synopsis()))
+		for _, line := range strings.Split(p.Usage(), "\n") {
+			if !strings.HasPrefix(line, " ") {
+				line = "jmgr " + line
+			}
+			fmt.Println(mdocText(line))
+		}
+		fmt.Println(".Ed")
+		if flags := providerFlags(name); len(flags) > 0 {
+			fmt.Println(".Bl -tag -width Ds")
+			for _, f := range flags {
+				typ, usage := flag.UnquoteUsage(f)
+				fmt.Print(".It Fl " + f.Name)
+				if len(typ) > 0 {
+					fmt.Print(" Ar " + typ)
+				}
+				fmt.Println()
+				fmt.Println(mdocText(usage))
+			}
+			fmt.Println(".El")
+		}
+	}
+	fmt.Print(`.El
+.Sh FILES
+.Bl -tag -width Ds
+.It Pa /usr/local/etc/jmgr/jmgr.conf
+.Nm
+configuration, see
+.Nm
+.Cm config .
+.El
+.Sh SEE ALSO
+.Xr jail.conf 5 ,
+.Xr freebsd-update 8 ,
+.Xr jail 8 ,
+.Xr jexec 8 ,
+.Xr jls 8 ,
+.Xr pkg 8 ,
+.Xr zfs 8
+`)
+}
+
+// mdocText escape backslashes and a leading . or ' (a mdoc macro line)
+func mdocText(text string) string {
+
+	text = strings.ReplaceAll(text, "\\", "\\e")
+	if strings.HasPrefix(text, ".") || strings.HasPrefix(text, "'") {
+		text = "\\&" + text
+	}
+	return text
+}
+
+// collectFlags is set by providerFlags, newFlagSet -h then collects the flags and ends the goroutine
+var collectFlags *[]*flag.Flag
+
+// providerFlags return the flags of subcommand name, Run(name -h) in a goroutine that ends in newFlagSet -h
+func providerFlags(name string) []*flag.Flag {
+
+	var flags []*flag.Flag
+	collectFlags = &flags
+	defer func() { collectFlags = nil }()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		SubC[name].Run([]string{name, "-h"})
+	}()
+	<-done
+	return flags
+}
+
+// completeOut and completeWord are set by complete, newFlagSet -h then prints the flag names starting with completeWord
+var completeOut *os.File
+var completeWord string
+
+// complete print the completions for the last of words (the word at the cursor, may be empty), one per line.
+// Subcommands, flags, jail names, @tags and snapshot names.
+func complete(words []string) {
+
+	out := os.Stdout
+	if null, err := os.Open(os.DevNull); err == nil {
+		os.Stdout = null // jmgrInit warnings
+	}
+
+	for len(words) > 1 && (words[0] == "-json" || words[0] == "--json" || words[0] == "-json-errors" || words[0] == "-y" || words[0] == "--yes" || words[0] == "-profile" || words[0] == "-socket") {
+		if (words[0] == "-profile" || words[0] == "-socket") && len(words) > 2 {
+			words = words[1:]
+		} else if words[0] == "-socket" {
+			return // file name
+		} else if words[0] == "-profile" {
+			// profile name
+			cfg := config.Settings{JmgrConfig: config.File()}
+			cfg.Read()
+			for name := range cfg.Profiles {
+				if strings.HasPrefix(name, words[1]) {
+					fmt.Fprintln(out, name)
+				}
+			}
+			return
+		}
+		words = words[1:]
+	}
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	cur := words[len(words)-1]
+	prev := words[:len(words)-1]
+	sub := ""
+	if len(prev) > 0 {
+		sub = prev[0]
+	}
+
+	// flags, Run parses its flags first, see Provider
+	if len(sub) > 0 && strings.HasPrefix(cur, "-") {
+		if p, ok := SubC[sub]; ok {
+			completeOut, completeWord = out, cur
+			p.Run([]string{sub, "-h"})
+		}
+		return
+	}
+
+	cfg := jmgrInit()
+	var jails, tags, groups, snaps []string
+	for _, jail := range cfg.Jails {
+		jails = append(jails, jail.Name)
+		for _, tag := range jail.Meta.Tags {
+			tags = append(tags, tag)
+			groups = append(groups, "@"+tag)
+		}
+		// rollback 'jail name' 'snapshot name', only the snapshots of the jail
+		if sub == "rollback" && len(prev) > 1 && prev[1] != jail.Name {
+			continue
+		}
+		for _, snap := range jail.Snapshots {
+			if len(snap) > 0 {
+				snaps = append(snaps, snap)
+			}
+		}
+	}
+
+	var subcommands []string
+	for k := range SubC {
+		subcommands = append(subcommands, k)
+	}
+	subcommands = append(subcommands, plugins()...)
+
+	var candidates []string
+	switch {
+	case len(prev) == 0:
+		candidates = append(append(subcommands, "help", "-json", "-json-errors", "-y", "-profile", "-socket"), jails...)
+	case sub == "help":
+		candidates = subcommands
+	case sub == "completion":
+		candidates = []string{"bash", "zsh", "fish"}
+	case sub == "destroy":
+		candidates = append(jails, snaps...)
+	case sub == "rollback" && len(prev) == 2 && !strings.HasPrefix(prev[1], "@"):
+		candidates = snaps
+	case sub == "rollback" || sub == "snapshot":
+		candidates = append(jails, groups...)
+	case sub == "update" && len(prev) == 1:
+		candidates = append([]string{"patch", "base", "pkgs", "rel", "check", "clean"}, jails...)
+	case sub == "standby" && len(prev) == 1:
+		candidates = []string{"setup", "run", "status", "remove", "activate"}
+	case sub == "fleet" && len(prev) == 1:
+		candidates = []string{"apply"}
+	case sub == "config" && len(prev) == 1:
+		candidates = []string{"get", "set"}
+	case sub == "config" && len(prev) == 2:
+		t := reflect.TypeOf(config.Settings{})
+		for i := 0; i < t.NumField(); i++ {
+			if len(t.Field(i).Tag.Get("yaml")) > 0 && t.Field(i).Type.Kind() == reflect.String {
+				candidates = append(candidates, t.Field(i).Name)
+			}
+		}
+	case sub == "config" || sub == "init":
+	case sub == "tag" && len(prev) > 1:
+		candidates = tags
+	case sub == "limits" && len(prev) == 2:
+		candidates = []string{"show", "set", "unset"}
+	case sub == "limits" && len(prev) > 2:
+		for _, r := range rctlResources {
+			candidates = append(candidates, r+"=")
+		}
+	default:
+		candidates = jails
+	}
+
+	slices.Sort(candidates)
+	for _, c := range slices.Compact(candidates) {
+		if strings.HasPrefix(c, cur) {
+			fmt.Fprintln(out, c)
+		}
+	}
+}
+
+// default jmgr configuration, templates and scripts, installed by 'jmgr init'
+var defaultConf = jmgr.DefaultConfig
+
+// Init bootstrap a host for jmgr, the config file, templates, /etc/jail.conf.d, the jails home and rc.conf
+type Init struct{}
+
+func (Init) Name() string { return "init" }
+func (Init) Synopsis() string {
+	return "Bootstrap the host: config file, templates, jail.conf.d, jails home (ZFS dataset) and rc.conf."
+}
+func (Init) Usage() string {
+	return "init [-f] [-n] [-dataset 'ZFS dataset' [-mountpoint 'directory'] | -home 'directory'] [-iface 'interface name']"
+}
+
+func (Init) Run(args []string) {
+
+	fset := newFlagSet(args[0])
+	force := fset.Bool("f", false, "Do not ask, use the flags and defaults.")
+	dryRun := fset.Bool("n", false, "Dry run, only report what would be done.")
+	dataset := fset.String("dataset", "", "ZFS dataset home for jails, created if missing. Default: <first zpool>/jails")
+	mountpoint := fset.String("mountpoint", "/usr/local/jails", "Mountpoint of a new ZFS dataset.")
+	home := fset.String("home", "", "Directory home for jails without ZFS, ex: /usr/local/jails")
+	iface := fset.String("iface", "", "Default jail interface. Default: the interface of the default route")
+	fset.Parse(args[1:])
+
+	if fset.NArg() > 0 {
+		fatal(jmgrError(exitUsage, "Syntax: jmgr "+Init{}.Usage(), "See: jmgr help"))
+	}
+	if system.NotRoot() && !*dryRun {
+		fatal(errNeedRoot)
+	}
+
+	ask := func(question string, value string) string {
+		if *force || *dryRun {
+			return value
+		}
+		return askValue(question, value)
+	}
+
+	// defaults from an existing config
+	file := config.File()
+	dir := filepath.Dir(file)
+	_, err := os.Stat(file)
+	newConfig := os.IsNotExist(err)
+	old := Jmgr{Settings: config.Settings{JmgrConfig: file}}
+	if !newConfig {
+		old.jmgrConfigfileReader()
+	}
+
+	if len(*dataset) == 0 && len(*home) == 0 {
+		*dataset = old.ZFSdataSet
+		if newConfig && len(*dataset) == 0 {
+			*dataset = defaultDataset()
+		}
+		if len(*dataset) > 0 {
+			*dataset = ask("ZFS dataset home for jails, '-' for no ZFS", *dataset)
+		}
+		if len(*dataset) == 0 || *dataset == "-" {
+			*dataset = ""
+			if len(old.JailsHome) == 0 {
+				old.JailsHome = "/usr/local/jails"
+			}
+			*home = ask("Directory home for jails", old.JailsHome)
+		}
+	}
+	if len(*iface) == 0 {
+		if len(old.JailIface) == 0 {
+			old.JailIface = defaultIface()
+		}
+		*iface = ask("Default jail interface", old.JailIface)
+	}
+
+	// kernel support
+	warnings, err := initCheck(len(*dataset) > 0)
+	if err != nil {
+		fatal(err)
+	}
+	for _, w := range warnings {
+		fmt.Println("Note: " + w)
+	}
+
+	type step struct {
+		what string
+		do   func() error
+	}
+	var steps []step
+
+	if newConfig {
+		steps = append(steps, step{"Create " + file, func() error {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return err
+			}
+			b, err := defaultConf.ReadFile("usr/local/etc/jmgr/jmgr.conf")
+			if err != nil {
+				return err
+			}
+			if format := config.Format(file); format != "yaml" {
+				var sample config.Settings
+				if err := yaml.Unmarshal(b, &sample); err != nil {
+					return err
+				}
+				if b, err = config.Encode(sample, format); err != nil {
+					return err
+				}
+			}
+			return os.WriteFile(file, b, 0644)
+		}})
+	}
+
+	// templates and scripts next to the config file, keep existing files
+	fs.WalkDir(defaultConf, "usr/local/etc/jmgr", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || d.Name() == "jmgr.conf" {
+			return err
+		}
+		target := filepath.Join(dir, strings.TrimPrefix(path, "usr/local/etc/jmgr/"))
+		if _, err := os.Stat(target); err == nil {
+			return nil
+		}
+		steps = append(steps, step{"Create " + target, func() error {
+			b, err := defaultConf.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			mode := fs.FileMode(0644)
+			if strings.HasSuffix(target, ".sh") {
+				mode = 0755
+			}
+			return os.WriteFile(target, b, mode)
+		}})
+		return nil
+	})
+
+	steps = append(steps, step{"Create /etc/jail.conf.d", func() error {
+		return os.MkdirAll("/etc/jail.conf.d", 0755)
+	}})
+
+	// jails home
+	if len(*dataset) > 0 {
+		if _, err := system.Run("/sbin/zfs", []string{"list", "-H", *dataset}); err != nil {
+			steps = append(steps, step{"Create ZFS dataset " + *dataset + " mounted on " + *mountpoint, func() error {
+				_, err := system.Run("/sbin/zfs", []string{"create", "-p", "-o", "mountpoint=" + *mountpoint, *dataset})
+				return err
+			}})
+		}
+	} else {
+		steps = append(steps, step{"Create jails home " + *home, func() error {
+			return os.MkdirAll(*home, 0755)
+		}})
+	}
+
+	// settings, the jails home of a dataset is its mountpoint
+	steps = append(steps, step{"Set ZFSdataSet, JailsHome, OsMediaDir and JailIface in " + file, func() error {
+		jailsHome := *home
+		if len(*dataset) > 0 {
+			b, err := system.Run("/sbin/zfs", []string{"get", "-H", "-o", "value", "mountpoint", *dataset})
+			if err != nil {
+				return err
+			}
+			jailsHome = strings.TrimSpace(string(b))
+		}
+		media := filepath.Join(jailsHome, "media")
+		if err := os.MkdirAll(media, 0755); err != nil {
+			return err
+		}
+		settings := [][2]string{{"ZFSdataSet", *dataset}, {"JailsHome", jailsHome}, {"OsMediaDir", media}, {"JailIface", *iface}}
+		if dir != "/usr/local/etc/jmgr" {
+			settings = append(settings, [][2]string{
+				{"JailConfTemplate", filepath.Join(dir, "jail.conf.template")},
+				{"JailTemplateDir", filepath.Join(dir, "templates")},
+				{"JailMetaDir", filepath.Join(dir, "meta")},
+				{"PostInstall", filepath.Join(dir, "postinstall.sh")}}...)
+		}
+		for _, kv := range settings {
+			if err := config.Set(file, kv[0], kv[1]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}})
+
+	steps = append(steps, step{"Enable jails in rc.conf, jail_enable=YES", func() error {
+		_, err := system.Run("/usr/sbin/sysrc", []string{"jail_enable=YES"})
+		return err
+	}})
+
+	for _, s := range steps {
+		fmt.Println(s.what)
+		if *dryRun {
+			continue
+		}
+		if err := s.do(); err != nil {
+			fatal(fmt.Errorf("Init() %s: %w", s.what, err))
+		}
+	}
+	if *dryRun {
+		return
+	}
+
+	cfg := jmgrInit()
+	if cfg.badConfig {
+		fatal(jmgrError(exitConfig, "The configuration is not complete.", "See: jmgr config"))
+	}
+	fmt.Println("Host ready for jails, see: jmgr config. Create a jail with: jmgr create 'jail name'")
+}
+
+// initCheck verify that the kernel supports jails, return notes about optional features
+func initCheck(useZFS bool) ([]string, error) {
+
+	var notes []string
+
+	b, err := system.Run("/sbin/sysctl", []string{"-n", "security.jail.jailed"})
+	if err != nil {
+		return nil, fmt.Errorf("initCheck() %w", err)
+	}
+	if strings.TrimSpace(string(b)) == "1" {
+		return nil, errors.New("jmgr init must run on the host, not in a jail")
+	}
+	if _, err := os.Stat(system.Tool("/usr/sbin/jail")); err != nil {
+		return nil, errors.New("/usr/sbin/jail is missing, jails are not supported on this host")
+	}
+	if useZFS {
+		if _, err := system.Run("/sbin/kldstat", []string{"-q", "-m", "zfs"}); err != nil {
+			return nil, errors.New("ZFS is not loaded, load it with 'kldload zfs' and add zfs_enable=YES to rc.conf, or use -home")
+		}
+	}
+	b, err = system.Run("/sbin/sysctl", []string{"-n", "kern.features.vimage"})
+	if err != nil || strings.TrimSpace(string(b)) != "1" {
+		notes = append(notes, "kernel without VIMAGE, vnet jails are not supported")
+	}
+	b, err = system.Run("/sbin/sysctl", []string{"-n", "kern.racct.enable"})
+	if err != nil || strings.TrimSpace(string(b)) != "1" {
+		notes = append(notes, "resource accounting is disabled, set kern.racct.enable=1 in /boot/loader.conf to limit jail resources")
+	}
+	return notes, nil
+}
+
+// defaultDataset return <first zpool>/jails, empty if there is no zpool
+func defaultDataset() string {
+
+	b, err := system.Run("/sbin/zpool", []string{"list", "-H", "-o", "name"})
+	if err != nil {
+		return ""
+	}
+	pools := strings.Fields(string(b))
+	if len(pools) == 0 {
+		return ""
+	}
+	return pools[0] + "/jails"
+}
+
+// defaultIface return the interface of the default route, or the first interface that is up and not loopback
+func defaultIface() string {
+
+	if b, err := system.Run("/sbin/route", []string{"-n", "get", "default"}); err == nil {
+		for _, line := range strings.Split(string(b), "\n") {
+			if k, v, ok := strings.Cut(strings.TrimSpace(line), ":"); ok && k == "interface" {
+				return strings.TrimSpace(v)
+			}
+		}
+	}
+	ifaces, _ := net.Interfaces()
+	for _, i := range ifaces {
+		if i.Flags&net.FlagUp != 0 && i.Flags&net.FlagLoopback == 0 {
+			return i.Name
+		}
+	}
+	return ""
+}
+
+// Maintenance put a jail on hold or set its maintenance window, update -all skips held jails and jails outside their window
+type Maintenance struct{}
+
+func (Maintenance) Name() string     { return "hold" }
+func (Maintenance) Synopsis() string { return "Put a jail on hold or set its maintenance window." }
+func (Maintenance) Usage() string {
+	return `hold [-d] 'jail name'
+window [-d] 'jail name' ['daily|Mon,Tue.. HH:MM-HH:MM']`
+}
+
+func (Maintenance) Run(args []string) {
+
+	fset := newFlagSet(args[0])
+	remove := fset.Bool("d", false, "Release the hold or remove the maintenance window.")
+	fset.Parse(args[1:])
+	args = append([]string{args[0]}, fset.Args()...)
+
+	cfg, jail, err := verifyArgs(2, 1, true, true, args)
+	if err != nil {
+		fatal(err)
+	}
+
+	switch args[0] {
+	case "hold":
+		jail.Meta.Hold = !*remove
+	case "window":
+		switch {
+		case *remove:
+			jail.Meta.Window = ""
+		case len(args) > 2:
+			window := strings.Join(args[2:], " ")
+			if _, err := inWindow(window, time.Now()); err != nil {
+				fatal(err)
+			}
+			jail.Meta.Window = window
+		}
+	}
+
+	if *remove || args[0] == "hold" || len(args) > 2 {
+		if err := cfg.writeMeta(jail.Name, jail.Meta); err != nil {
+			fatal(err)
+		}
+	}
+	fmt.Println(jail.Name+":", "hold:", jail.Meta.Hold, "window:", jail.Meta.Window)
+}
+
+// Protect a jail, destroy and rollback refuse a protected jail, even with -f
+type Protect struct{}
+
+func (Protect) Name() string { return "protect" }
+func (Protect) Synopsis() string {
+	return "Protect a jail from destroy and rollback, or remove the protection."
+}
+func (Protect) Usage() string {
+	return `protect 'jail name'
+unprotect 'jail name'`
+}
+
+func (Protect) Run(args []string) {
+
+	fset := newFlagSet(args[0])
+	fset.Parse(args[1:])
+	args = append([]string{args[0]}, fset.Args()...)
+
+	cfg, jail, err := verifyArgs(2, 1, true, true, args)
+	if err != nil {
+		fatal(err)
+	}
+
+	jail.Meta.Protected = args[0] == "protect"
+	if err := cfg.writeMeta(jail.Name, jail.Meta); err != nil {
+		fatal(err)
+	}
+	fmt.Println(jail.Name+":", "protected:", jail.Meta.Protected)
+	printJSON(map[string]any{"jail": jail.Name, "protected": jail.Meta.Protected})
+}
+
+// errProtected the error of a command the protection of the jail refuses
+func errProtected(jail *Jail, action string) error {
+	return jmgrError(exitConflict, "Jail "+jail.Name+" is protected, can't "+action+" it.", "Remove the protection with: jmgr unprotect "+jail.Name)
+}
+
+// Describe set or remove (-d) the description of a jail
+type Describe struct{}
+
+func (Describe) Name() string     { return "describe" }
+func (Describe) Synopsis() string { return "Set or remove the description of a jail." }
+func (Describe) Usage() string    { return "describe [-d] 'jail name' ['description']" }
+
+func (Describe) Run(args []string) {
+
+	fset := newFlagSet(args[0])
+	remove := fset.Bool("d", false, "Remove the description.")
+	fset.Parse(args[1:])
+	args = append([]string{args[0]}, fset.Args()...)
+
+	cfg, jail, err := verifyArgs(2, 1, true, true, args)
+	if err != nil {
+		fatal(err)
+	}
+
+	if *remove || len(args) > 2 {
+		jail.Meta.Description = strings.Join(args[2:], " ")
+		if err := cfg.writeMeta(jail.Name, jail.Meta); err != nil {
+			fatal(err)
+		}
+	}
+	fmt.Println(jail.Name+":", jail.Meta.Description)
+}
+
+// Limits set, remove and show the persistent rctl(8) rules of a jail, see JailMeta.Limits
+type Limits struct{}
+
+func (Limits) Name() string     { return "limits" }
+func (Limits) Synopsis() string { return "Set, unset or show the rctl resource limits of a jail." }
+func (Limits) Usage() string {
+	return `limits 'jail name' [show]
+limits 'jail name' set 'resource=amount' ['resource:action=amount' ...]
+limits 'jail name' unset 'resource' ['resource:action' ...]`
+}
+
+// rctlRule a rctl(8) rule without the subject, ex: memoryuse:deny=2g or readbps:throttle=10m
+var rctlRule = regexp.MustCompile(`^[a-z]+:[a-z]+=[0-9]+[kmgtKMGT]?(/[a-z]+)?$`)
+
+// rctlResources the rctl(8) resources
+var rctlResources = []string{"cputime", "datasize", "stacksize", "coredumpsize", "memoryuse", "memorylocked", "maxproc",
+	"openfiles", "vmemoryuse", "pseudoterminals", "swapuse", "nthr", "msgqqueued", "msgqsize", "nmsgq", "nsem", "nsemop",
+	"nshm", "shmsize", "wallclock", "pcpu", "readbps", "writebps", "readiops", "writeiops"}
+
+// LimitRule a rule of 'jmgr limits show', with the usage of the resource
+type LimitRule struct {
+	Rule   string `json:"rule"`
+	Source string `json:"source"` // settings (jmgr.conf) or limits ('jmgr limits')
+	Active bool   `json:"active"` // rctl has a rule for resource:action
+	Usage  int64  `json:"usage"`
+}
+
+func (Limits) Run(args []string) {
+
+	fset := newFlagSet(args[0])
+	fset.Parse(args[1:])
+	args = append([]string{args[0]}, fset.Args()...)
+
+	action := "show"
+	if len(args) > 2 {
+		action = args[2]
+	}
+
+	cfg, jail, err := verifyArgs(2, 1, action != "show", true, args)
+	if err != nil {
+		fatal(err)
+	}
+
+	switch action {
+	case "show":
+		if len(args) > 3 {
+			help()
+		}
+
+	case "set":
+		if len(args) < 4 {
+			help()
+		}
+		for _, arg := range args[3:] {
+			rule, err := limitRule(arg)
+			if err != nil {
+				fatal(err)
+			}
+			// one rule per resource:action, the new amount replace the old
+			key, _, _ := strings.Cut(rule, "=")
+			jail.Meta.Limits = slices.DeleteFunc(jail.Meta.Limits, func(r string) bool { return strings.HasPrefix(r, key+"=") })
+			jail.Meta.Limits = append(jail.Meta.Limits, rule)
+		}
+
+	case "unset":
+		if len(args) < 4 {
+			help()
+		}
+		for _, arg := range args[3:] {
+			n := len(jail.Meta.Limits)
+			jail.Meta.Limits = slices.DeleteFunc(jail.Meta.Limits, func(r string) bool {
+				return strings.HasPrefix(r, arg+":") || strings.HasPrefix(r, arg+"=")
+			})
+			if n == len(jail.Meta.Limits) {
+				fatal(jmgrError(exitNotFound, "No limit "+arg+" set on "+jail.Name+".", "See the limits with: jmgr limits "+jail.Name))
+			}
+		}
+
+	default:
+		help()
+	}
+
+	if action != "show" {
+		if err := cfg.writeMeta(jail.Name, jail.Meta); err != nil {
+			fatal(err)
+		}
+		if jail.Runs() {
+			if err := lifecycle.ApplyLimits(jail); err != nil {
+				fatal(err)
+			}
+		}
+	}
+
+	// the rules and the live usage
+	var rules []LimitRule
+	active, _ := system.Run("/usr/bin/rctl", []string{"jail:" + jail.Name})
+	usage := rctlUsage(jail.Name)
+	for i, rule := range jail.Limits() {
+		source := "limits"
+		if i < len(jail.Settings.Limits) {
+			source = "settings"
+		}
+		key, _, _ := strings.Cut(rule, "=")
+		resource, _, _ := strings.Cut(rule, ":")
+		rules = append(rules, LimitRule{
+			Rule:   rule,
+			Source: source,
+			Active: bytes.Contains(active, []byte("jail:"+jail.Name+":"+key+"=")),
+			Usage:  usage[resource],
+		})
+	}
+
+	if jsonOutput {
+		printJSON(map[string]any{"jail": jail.Name, "rules": rules, "usage": usage})
+		return
+	}
+	if len(rules) == 0 {
+		fmt.Println(jail.Name + ": no limits")
+		return
+	}
+	f := "%s\t%s\t%s\t%s\n"
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, f, "Rule", "Source", "Active", "Usage")
+	for _, r := range rules {
+		state := "no"
+		if r.Active {
+			state = "yes"
+		}
+		fmt.Fprintf(w, f, r.Rule, r.Source, state, strconv.FormatInt(r.Usage, 10))
+	}
+	w.Flush()
+}
+
+// rctlIO the disk I/O resources, the only action rctl(8) has for them is throttle
+var rctlIO = []string{"readbps", "writebps", "readiops", "writeiops"}
+
+// limitRule return the rctl rule of resource=amount, the default action is deny, throttle for disk I/O,
+// ex: memoryuse=4G -> memoryuse:deny=4G, writebps=50m -> writebps:throttle=50m
+func limitRule(arg string) (string, error) {
+
+	resource, amount, ok := strings.Cut(arg, "=")
+	if !ok {
+		return "", jmgrError(exitUsage, "Not a limit: "+arg, "Use resource=amount, ex: memoryuse=4G")
+	}
+	if !strings.Contains(resource, ":") {
+		if slices.Contains(rctlIO, resource) {
+			resource += ":throttle"
+		} else {
+			resource += ":deny"
+		}
+	}
+	rule := resource + "=" + amount
+	if err := checkRule(rule); err != nil {
+		return "", jmgrError(exitUsage, "Not a rctl rule: "+arg+", "+err.Error(), "Use resource=amount, ex: memoryuse=4G maxproc=500 pcpu=200 writebps=50m")
+	}
+	return rule, nil
+}
+
+// checkRule return an error if rule is not a rctl rule without the subject, throttles other than disk I/O or denies disk I/O
+func checkRule(rule string) error {
+
+	if !rctlRule.MatchString(rule) {
+		return errors.New("use resource:action=amount, ex: memoryuse:deny=2g")
+	}
+	name, action, _ := strings.Cut(strings.Split(rule, "=")[0], ":")
+	switch {
+	case !slices.Contains(rctlResources, name):
+		return fmt.Errorf("unknown resource %s", name)
+	case action == "throttle" && !slices.Contains(rctlIO, name):
+		return errors.New("throttle is the action for readbps, writebps, readiops and writeiops only")
+	case action == "deny" && slices.Contains(rctlIO, name):
+		return fmt.Errorf("%s can't deny, use throttle", name)
+	}
+	return nil
+}
+
+// Cpuset pin a jail to cpus with cpuset(1), the cpu list is kept in JailMeta.Cpuset and applied at every start
+type Cpuset struct{}
+
+func (Cpuset) Name() string     { return "cpuset" }
+func (Cpuset) Synopsis() string { return "Pin a jail to a list of CPUs, or show the CPUs of a jail." }
+func (Cpuset) Usage() string    { return "cpuset [-l 'cpu list' | -d] 'jail name'" }
+
+// cpuList a cpuset(1) cpu list, ex: 0-3 or 0,2,4-7
+var cpuList = regexp.MustCompile(`^[0-9]+(-[0-9]+)?(,[0-9]+(-[0-9]+)?)*$`)
+
+func (Cpuset) Run(args []string) {
+
+	fset := newFlagSet(args[0])
+	list := fset.String("l", "", "CPU list, ex: 0-3 or 0,2,4-7.")
+	remove := fset.Bool("d", false, "Remove the CPU list, the jail may use all CPUs.")
+	fset.Parse(args[1:])
+	// jmgr cpuset 'jail name' -l 0-3
+	if fset.NArg() > 1 {
+		name := fset.Arg(0)
+		fset.Parse(fset.Args()[1:])
+		if fset.NArg() > 0 {
+			help()
+		}
+		args = []string{args[0], name}
+	} else {
+		args = append([]string{args[0]}, fset.Args()...)
+	}
+
+	set := len(*list) > 0 || *remove
+	cfg, jail, err := verifyArgs(2, 1, set, true, args)
+	if err != nil {
+		fatal(err)
+	}
+
+	if set {
+		if len(*list) > 0 && !cpuList.MatchString(*list) {
+			fatal(jmgrError(exitUsage, "Not a cpu list: "+*list, "Use ex: -l 0-3 or -l 0,2,4-7"))
+		}
+		if *remove {
+			*list = ""
+		}
+		if jail.Runs() {
+			cpus := *list
+			if *remove {
+				cpus = "all"
+			}
+			if err := lifecycle.ApplyCpuset(jail, cpus); err != nil {
+				fatal(err)
+			}
+		}
+		jail.Meta.Cpuset = *list
+		if err := cfg.writeMeta(jail.Name, jail.Meta); err != nil {
+			fatal(err)
+		}
+	}
+
+	// the cpus the jail may use now
+	var current string
+	if jail.Runs() {
+		b, err := system.Run("/usr/bin/cpuset", []string{"-g", "-j", strconv.Itoa(jail.Jid)})
+		if err != nil {
+			fatal(err)
+		}
+		_, current, _ = strings.Cut(strings.TrimSpace(string(b)), "mask: ")
+	}
+
+	printJSON(map[string]string{"jail": jail.Name, "cpuset": jail.Meta.Cpuset, "current": current})
+	if !jsonOutput {
+		cpus := jail.Meta.Cpuset
+		if len(cpus) == 0 {
+			cpus = "all"
+		}
+		fmt.Println(jail.Name+":", cpus)
+		if len(current) > 0 {
+			fmt.Println("Current:", current)
+		}
+	}
+}
+
+// Stats live resource usage of the running jails
+type Stats struct{}
+
+func (Stats) Name() string { return "stats" }
+func (Stats) Synopsis() string {
+	return "Show CPU, memory, swap, processes and open files of the running jails."
+}
+func (Stats) Usage() string {
+	return "stats [-sort name|cpu|mem|swap|procs|files|read|write] [-tag 'tag'] [-history 'age'] ['jail name' 'jail name2' ...]"
+}
+
+func (Stats) Run(args []string) {
+
+	fset := newFlagSet(args[0])
+	sortBy := fset.String("sort", "name", "Sort by name, cpu, mem, swap, procs, files, read or write, the largest first.")
+	tag := fset.String("tag", "", "Only the jails with the tag.")
+	history := fset.String("history", "", "Min, avg and max over the last age, ex: 24h or 7d, from the samples of 'jmgr daemon'.")
+	fset.Parse(args[1:])
+
+	cfg := jmgrInit()
+	for _, name := range fset.Args() {
+		if !cfg.exist(name) {
+			fatal(errNoJail(name))
+		}
+	}
+
+	if len(*history) > 0 {
+		since, err := parseAge(*history)
+		if err != nil {
+			fatal(jmgrError(exitUsage, err.Error(), ""))
+		}
+		jails := fset.Args()
+		if len(*tag) > 0 {
+			for _, jail := range cfg.Jails {
+				if slices.Contains(jail.Meta.Tags, strings.TrimPrefix(*tag, "@")) && (fset.NArg() == 0 || slices.Contains(fset.Args(), jail.Name)) {
+					jails = append(jails, jail.Name)
+				}
+			}
+			if len(jails) == 0 {
+				jails = []string{""} // no jail has the tag
+			}
+		}
+		summaries, err := statsHistory(jails, since)
+		if err != nil {
+			fatal(err)
+		}
+		if jsonOutput {
+			printJSON(summaries)
+			return
+		}
+		printHistory(os.Stdout, summaries)
+		return
+	}
+
+	stats := []JailStats{}
+	for _, jail := range cfg.Jails {
+		if !jail.Runs() || (fset.NArg() > 0 && !slices.Contains(fset.Args(), jail.Name)) {
+			continue
+		}
+		if len(*tag) > 0 && !slices.Contains(jail.Meta.Tags, strings.TrimPrefix(*tag, "@")) {
+			continue
+		}
+		stats = append(stats, jailStats(jail))
+	}
+	if err := sortStats(stats, *sortBy); err != nil {
+		fatal(err)
+	}
+
+	if jsonOutput {
+		printJSON(stats)
+		return
+	}
+	printStats(os.Stdout, stats)
+}
+
+// HistorySummary the min, avg and max of the samples of a jail, see statsHistory
+type HistorySummary struct {
+	Name    string     `json:"name"`
+	Samples int        `json:"samples"`
+	From    string     `json:"from"` // time of the first sample
+	Cpu     [3]float64 `json:"cpu"`  // min, avg, max
+	Memory  [3]float64 `json:"memory"`
+	Read    [3]float64 `json:"read"` // -1 if unknown
+	Write   [3]float64 `json:"write"`
+	Dataset [3]float64 `json:"dataset"`
+}
+
+// statsHistory return the summaries of the samples of the jails, all if none, in the last since, sorted by name.
+// The samples are kept by 'jmgr daemon' on the -socket, default /var/run/jmgr.sock
+func statsHistory(jails []string, since time.Duration) ([]HistorySummary, error) {
+
+	socket := daemonSocket
+	if len(socket) == 0 {
+		socket = defaultSocket
+	}
+	client, err := jsonrpc.Dial("unix", socket)
+	if err != nil {
+		return nil, jmgrError(exitError, "jmgr daemon: "+err.Error(), "The history is sampled by jmgr daemon, see: jmgr service install -enable jmgrd")
+	}
+	defer client.Close()
+	var history map[string][]HistorySample
+	if err := client.Call("Jmgr.History", HistoryArgs{Jails: jails, Since: since}, &history); err != nil {
+		return nil, fmt.Errorf("jmgr daemon: %w", err)
+	}
+
+	summaries := []HistorySummary{}
+	for name, samples := range history {
+		summaries = append(summaries, HistorySummary{
+			Name:    name,
+			Samples: len(samples),
+			From:    samples[0].Time.Format(time.RFC3339),
+			Cpu:     minAvgMax(samples, func(s HistorySample) float64 { return s.Cpu }),
+			Memory:  minAvgMax(samples, func(s HistorySample) float64 { return float64(s.Memory) }),
+			Read:    minAvgMax(samples, func(s HistorySample) float64 { return float64(s.Read) }),
+			Write:   minAvgMax(samples, func(s HistorySample) float64 { return float64(s.Write) }),
+			Dataset: minAvgMax(samples, func(s HistorySample) float64 { return float64(s.Dataset) }),
+		})
+	}
+	slices.SortFunc(summaries, func(a, b HistorySummary) int { return cmp.Compare(a.Name, b.Name) })
+	return summaries, nil
+}
+
+// minAvgMax return the min, avg and max of the value of the samples, at least one
+func minAvgMax(samples []HistorySample, value func(s HistorySample) float64) [3]float64 {
+
+	m := [3]float64{value(samples[0]), 0, value(samples[0])}
+	for _, s := range samples {
+		v := value(s)
+		m[0], m[1], m[2] = min(m[0], v), m[1]+v, max(m[2], v)
+	}
+	m[1] /= float64(len(samples))
+	return m
+}
+
+// printHistory print the summaries as a table, a row per jail and usage, '-' for unknown
+func printHistory(out io.Writer, summaries []HistorySummary) {
+
+	f := "%s\t%s\t%s\t%s\t%s\t%s\n"
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, f, "Name", "Usage", "Min", "Avg", "Max", "Samples")
+	for _, sum := range summaries {
+		for _, row := range []struct {
+			label  string
+			m      [3]float64
+			format func(float64) string
+		}{
+			{"CPU%", sum.Cpu, func(v float64) string { return strconv.FormatFloat(v, 'f', 1, 64) }},
+			{"Memory", sum.Memory, func(v float64) string { return fmtBytes(int64(v)) }},
+			{"Read/s", sum.Read, func(v float64) string { return fmtBytes(int64(v)) }},
+			{"Write/s", sum.Write, func(v float64) string { return fmtBytes(int64(v)) }},
+			{"Dataset", sum.Dataset, func(v float64) string { return fmtBytes(int64(v)) }},
+		} {
+			values := []string{"-", "-", "-"}
+			if row.m[0] >= 0 {
+				values = []string{row.format(row.m[0]), row.format(row.m[1]), row.format(row.m[2])}
+			}
+			since := ""
+			if row.label == "CPU%" {
+				since = strconv.Itoa(sum.Samples) + " since " + sum.From
+			}
+			fmt.Fprintf(w, f, sum.Name, row.label, values[0], values[1], values[2], since)
+		}
+	}
+	w.Flush()
+}
+
+// sortStats by name, or the largest first by cpu, mem, swap, procs or files
+func sortStats(stats []JailStats, by string) error {
+
+	key := map[string]func(s JailStats) float64{
+		"cpu":   func(s JailStats) float64 { return s.Cpu },
+		"mem":   func(s JailStats) float64 { return float64(s.Memory) },
+		"swap":  func(s JailStats) float64 { return float64(s.Swap) },
+		"procs": func(s JailStats) float64 { return float64(s.Procs) },
+		"files": func(s JailStats) float64 { return float64(s.Files) },
+		"read":  func(s JailStats) float64 { return float64(s.Read) },
+		"write": func(s JailStats) float64 { return float64(s.Write) },
+	}
+	if by == "name" {
+		slices.SortFunc(stats, func(a, b JailStats) int { return cmp.Compare(a.Name, b.Name) })
+		return nil
+	}
+	f, ok := key[by]
+	if !ok {
+		return jmgrError(exitUsage, "Unknown sort "+by+", use name, cpu, mem, swap, procs, files, read or write.", "")
+	}
+	slices.SortStableFunc(stats, func(a, b JailStats) int { return cmp.Compare(f(b), f(a)) })
+	return nil
+}
+
+// printStats print the stats as a table, '-' for unknown
+func printStats(out io.Writer, stats []JailStats) {
+
+	unknown := func(n int64, s string) string {
+		if n < 0 {
+			return "-"
+		}
+		return s
+	}
+	f := "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n"
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, f, "JID", "Name", "CPU%", "Memory", "Swap", "Procs", "Files", "Read/s", "Write/s")
+	for _, s := range stats {
+		fmt.Fprintf(w, f, strconv.Itoa(s.Jid), s.Name, strconv.FormatFloat(s.Cpu, 'f', 1, 64), fmtBytes(s.Memory),
+			unknown(s.Swap, fmtBytes(s.Swap)), strconv.FormatInt(s.Procs, 10), unknown(s.Files, strconv.FormatInt(s.Files, 10)),
+			unknown(s.Read, fmtBytes(s.Read)), unknown(s.Write, fmtBytes(s.Write)))
+	}
+	w.Flush()
+}
+
+// Top redraw the stats of the running jails every interval, with keys to stop or restart the selected jail
+type Top struct{}
+
+func (Top) Name() string { return "top" }
+func (Top) Synopsis() string {
+	return "Continuously updating CPU, memory and network view of the running jails."
+}
+func (Top) Usage() string {
+	return "top [-interval 'duration'] [-sort name|cpu|mem|swap|procs|files] [-tag 'tag']"
+}
+
+// netSample bytes received and sent by a vnet jail at a time, see jailNetBytes
+type netSample struct {
+	in, out int64
+	at      time.Time
+}
+
+func (Top) Run(args []string) {
+
+	fset := newFlagSet(args[0])
+	interval := fset.Duration("interval", 2*time.Second, "Time between the refreshes, ex: 5s.")
+	sortBy := fset.String("sort", "cpu", "Sort by name, cpu, mem, swap, procs or files, the largest first.")
+	tag := fset.String("tag", "", "Only the jails with the tag.")
+	fset.Parse(args[1:])
+
+	fd := int(os.Stdin.Fd())
+	if jsonOutput || !term.IsTerminal(fd) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		fatal(jmgrError(exitUsage, "jmgr top needs a terminal.", "Use: jmgr stats"))
+	}
+	if err := sortStats(nil, *sortBy); err != nil {
+		fatal(err)
+	}
+	jmgrInit() // config problems are reported before the terminal is in raw mode
+
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		fatal(err)
+	}
+	defer term.Restore(fd, state)
+	defer fmt.Print("\033[H\033[2J")
+
+	// keys, the arrows as k and j
+	keys := make(chan byte)
+	go func() {
+		b := make([]byte, 3)
+		for {
+			n, err := os.Stdin.Read(b)
+			if err != nil {
+				close(keys)
+				return
+			}
+			if n == 3 && b[0] == 27 && b[1] == '[' && (b[2] == 'A' || b[2] == 'B') {
+				keys <- map[byte]byte{'A': 'k', 'B': 'j'}[b[2]]
+				continue
+			}
+			for _, k := range b[:n] {
+				keys <- k
+			}
+		}
+	}()
+
+	var selected, status, confirm string
+	samples := make(map[string]netSample)
+	for {
+		cfg := jmgrInit()
+		var jails []Jail
+		stats := []JailStats{}
+		for _, jail := range cfg.Jails {
+			if jail.Runs() && (len(*tag) == 0 || slices.Contains(jail.Meta.Tags, strings.TrimPrefix(*tag, "@"))) {
+				jails = append(jails, jail)
+				stats = append(stats, jailStats(jail))
+			}
+		}
+		sortStats(stats, *sortBy)
+
+		// selection follows the jail, the first jail if it is gone
+		row := slices.IndexFunc(stats, func(s JailStats) bool { return s.Name == selected })
+		if row < 0 && len(stats) > 0 {
+			row, selected = 0, stats[0].Name
+		}
+
+		var buf bytes.Buffer
+		fmt.Fprintf(&buf, "jmgr top - %s, %d running, every %s, sort %s\n", time.Now().Format("15:04:05"), len(stats), *interval, *sortBy)
+		fmt.Fprintln(&buf, "keys: j/k or arrows select, s stop, r restart, c/m/n/p sort cpu/mem/name/procs, q quit")
+		fmt.Fprintln(&buf)
+		f := "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t\n"
+		w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, f, "JID", "Name", "CPU%", "Memory", "Swap", "Procs", "Net in/s", "Net out/s")
+		for _, st := range stats {
+			netIn, netOut := "-", "-"
+			jail := jails[slices.IndexFunc(jails, func(j Jail) bool { return j.Name == st.Name })]
+			if in, out, ok := jailNetBytes(jail); ok {
+				if prev, ok := samples[jail.Name]; ok {
+					secs := time.Since(prev.at).Seconds()
+					netIn, netOut = fmtBytes(int64(float64(in-prev.in)/secs)), fmtBytes(int64(float64(out-prev.out)/secs))
+				}
+				samples[jail.Name] = netSample{in, out, time.Now()}
+			}
+			swap := "-"
+			if st.Swap >= 0 {
+				swap = fmtBytes(st.Swap)
+			}
+			fmt.Fprintf(w, f, strconv.Itoa(st.Jid), st.Name, strconv.FormatFloat(st.Cpu, 'f', 1, 64), fmtBytes(st.Memory),
+				swap, strconv.FormatInt(st.Procs, 10), netIn, netOut)
+		}
+		w.Flush()
+
+		// selected row in reverse video
+		lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		if row >= 0 {
+			lines[4+row] = "\033[7m" + lines[4+row] + "\033[0m"
+		}
+		fmt.Print("\033[H\033[2J" + strings.Join(lines, "\r\n") + "\r\n\r\n" + status)
+
+		select {
+		case <-time.After(*interval):
+			continue
+		case k, ok := <-keys:
+			if !ok {
+				return
+			}
+			if len(confirm) > 0 {
+				action := confirm
+				confirm, status = "", ""
+				if k == 'y' && row >= 0 {
+					jail := jails[slices.IndexFunc(jails, func(j Jail) bool { return j.Name == selected })]
+					if system.NotRoot() {
+						status = "Need root to " + action + " jails."
+					} else if err := startstop(action, &jail); err != nil {
+						status = action + " " + jail.Name + ": " + err.Error()
+					} else {
+						status = action + " " + jail.Name + " done."
+					}
+				}
+				continue
+			}
+			status = ""
+			switch k {
+			case 'q', 3: // 3 is ctrl-c in raw mode
+				return
+			case 'j':
+				if row >= 0 && row < len(stats)-1 {
+					selected = stats[row+1].Name
+				}
+			case 'k':
+				if row > 0 {
+					selected = stats[row-1].Name
+				}
+			case 'c', 'm', 'n', 'p':
+				*sortBy = map[byte]string{'c': "cpu", 'm': "mem", 'n': "name", 'p': "procs"}[k]
+			case 's', 'r':
+				if row >= 0 {
+					confirm = map[byte]string{'s': "stop", 'r': "restart"}[k]
+					status = confirm + " " + selected + "? (y/n)"
+				}
+			}
+		}
+	}
+}
+
+// jailNetBytes return the bytes received and sent on the interfaces of a vnet jail, lo excluded. Not ok without vnet,
+// a jail without vnet shares the interfaces of the host
+func jailNetBytes(jail Jail) (int64, int64, bool) {
+
+	b, err := system.Run("/usr/sbin/jls", []string{"-j", jail.Name, "vnet"})
+	if err != nil || strings.TrimSpace(string(b)) != "new" {
+		return 0, 0, false
+	}
+	b, err = system.Run("/usr/sbin/jexec", []string{strconv.Itoa(jail.Jid), "/usr/bin/netstat", "-ibn"})
+	if err != nil {
+		return 0, 0, false
+	}
+	var in, out int64
+	for _, line := range strings.Split(string(b), "\n") {
+		// Name Mtu Network [Address] Ipkts Ierrs Idrop Ibytes Opkts Oerrs Obytes Coll, one <Link#n> row per interface
+		f := strings.Fields(line)
+		if len(f) < 11 || strings.HasPrefix(f[0], "lo") || !strings.HasPrefix(f[2], "<Link") {
+			continue
+		}
+		i, _ := strconv.ParseInt(f[len(f)-5], 10, 64)
+		o, _ := strconv.ParseInt(f[len(f)-2], 10, 64)
+		in, out = in+i, out+o
+	}
+	return in, out, true
+}
+
+// Ps list the processes of a jail, or of all jails
+type Ps struct{}
+
+func (Ps) Name() string     { return "ps" }
+func (Ps) Synopsis() string { return "List the processes of a jail, or find the jail of a process." }
+func (Ps) Usage() string {
+	return `ps [-s] 'jail name'
+ps -all [-s] [-pid 'pid']`
+}
+
+func (Ps) Run(args []string) {
+
+	fset := newFlagSet(args[0])
+	all := fset.Bool("all", false, "The processes of all jails.")
+	pid := fset.Int("pid", 0, "Only the process with the pid, with -all to find the jail of a process.")
+	summary := fset.Bool("s", false, "Summarize by command: processes, CPU and memory.")
+	fset.Parse(args[1:])
+	args = append([]string{args[0]}, fset.Args()...)
+
+	var procs []JailProc
+	var err error
+	cfg := jmgrInit()
+	if *all {
+		if len(args) > 1 {
+			help()
+		}
+		procs, err = lifecycle.Procs(0)
+	} else {
+		if len(args) != 2 {
+			help()
+		}
+		if !cfg.exist(args[1]) {
+			fatal(errNoJail(args[1]))
+		}
+		jail := cfg.jail(args[1])
+		if !jail.Runs() {
+			fatal(jmgrError(exitConflict, "Jail "+jail.Name+" is not running.", "Start it with: jmgr start "+jail.Name))
+		}
+		procs, err = lifecycle.Procs(jail.Jid)
+	}
+	if err != nil {
+		fatal(err)
+	}
+
+	// the jail names of the jids
+	for i := range procs {
+		if j := slices.IndexFunc(cfg.Jails, func(j Jail) bool { return j.Jid == procs[i].Jid }); j >= 0 {
+			procs[i].Jail = cfg.Jails[j].Name
+		}
+	}
+	if *pid > 0 {
+		procs = slices.DeleteFunc(procs, func(p JailProc) bool { return p.Pid != *pid })
+		if len(procs) == 0 {
+			fatal(jmgrError(exitNotFound, "No process "+strconv.Itoa(*pid)+" in a jail.", ""))
+		}
+	}
+	if *summary {
+		procs = summarizeProcs(procs)
+	}
+
+	if jsonOutput {
+		printJSON(procs)
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if *summary {
+		f := "%s\t%s\t%s\t%s\t%s\n"
+		fmt.Fprintf(w, f, "Jail", "Count", "CPU%", "Memory", "Command")
+		for _, p := range procs {
+			fmt.Fprintf(w, f, p.Jail, strconv.Itoa(p.Count), strconv.FormatFloat(p.Cpu, 'f', 1, 64), fmtBytes(p.Rss), p.Command)
+		}
+	} else {
+		f := "%s\t%s\t%s\t%s\t%s\t%s\t%s\n"
+		fmt.Fprintf(w, f, "Jail", "PID", "User", "CPU%", "Memory", "Time", "Command")
+		for _, p := range procs {
+			fmt.Fprintf(w, f, p.Jail, strconv.Itoa(p.Pid), p.User, strconv.FormatFloat(p.Cpu, 'f', 1, 64), fmtBytes(p.Rss), p.Time, p.Command)
+		}
+	}
+	w.Flush()
+}
+
+// summarizeProcs return one row per jail and command name, the largest CPU first
+func summarizeProcs(procs []JailProc) []JailProc {
+
+	sum := []JailProc{}
+	for _, p := range procs {
+		name := strings.TrimSuffix(filepath.Base(strings.Fields(p.Command)[0]), ":") // nginx: worker process
+		i := slices.IndexFunc(sum, func(s JailProc) bool { return s.Jid == p.Jid && s.Command == name })
+		if i < 0 {
+			sum = append(sum, JailProc{Jail: p.Jail, Jid: p.Jid, Command: name})
+			i = len(sum) - 1
+		}
+		sum[i].Count++
+		sum[i].Cpu += p.Cpu
+		sum[i].Rss += p.Rss
+	}
+	slices.SortStableFunc(sum, func(a, b JailProc) int { return cmp.Compare(b.Cpu, a.Cpu) })
+	return sum
+}
+
+// Sockets list the listening and connected sockets of a jail, flag the listening ports another jail or the host also listens on
+type Sockets struct{}
+
+func (Sockets) Name() string { return "sockets" }
+func (Sockets) Synopsis() string {
+	return "List the listening ports and connections of a jail, with the ports that collide with the host or other jails."
+}
+func (Sockets) Usage() string { return "sockets [-l | -c] 'jail name'" }
+
+// JailSocket an IPv4 or IPv6 socket of a jail, from sockstat(1)
+type JailSocket struct {
+	User     string `json:"user"`
+	Command  string `json:"command"`
+	Pid      int    `json:"pid"`
+	Proto    string `json:"proto"` // ex: tcp4, udp46
+	Local    string `json:"local"` // address:port, * for any address
+	Foreign  string `json:"foreign"`
+	State    string `json:"state"`              // listen or connected
+	Conflict string `json:"conflict,omitempty"` // the host and jails also listening on the port, ex: host sshd, jail db postgres
+}
+
+func (Sockets) Run(args []string) {
+
+	fset := newFlagSet(args[0])
+	listen := fset.Bool("l", false, "Only the listening sockets.")
+	connected := fset.Bool("c", false, "Only the connected sockets.")
+	fset.Parse(args[1:])
+	args = append([]string{args[0]}, fset.Args()...)
+
+	if len(args) != 2 || (*listen && *connected) {
+		help()
+	}
+	cfg := jmgrInit()
+	if !cfg.exist(args[1]) {
+		fatal(errNoJail(args[1]))
+	}
+	jail := cfg.jail(args[1])
+	if !jail.Runs() {
+		fatal(jmgrError(exitConflict, "Jail "+jail.Name+" is not running.", "Start it with: jmgr start "+jail.Name))
+	}
+
+	sockets := []JailSocket{}
+	for _, state := range []string{"listen", "connected"} {
+		if (state == "listen" && *connected) || (state == "connected" && *listen) {
+			continue
+		}
+		found, err := sockstat(jail.Jid, state)
+		if err != nil {
+			fatal(err)
+		}
+		sockets = append(sockets, found...)
+	}
+	if err := cfg.portConflicts(jail, sockets); err != nil {
+		fatal(err)
+	}
+
+	if jsonOutput {
+		printJSON(sockets)
+		return
+	}
+	f := "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n"
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, f, "Proto", "Local", "Foreign", "State", "Command", "PID", "User", "Conflict")
+	for _, s := range sockets {
+		fmt.Fprintf(w, f, s.Proto, s.Local, s.Foreign, s.State, s.Command, strconv.Itoa(s.Pid), s.User, s.Conflict)
+	}
+	w.Flush()
+}
+
+// sockstat return the listening or connected IPv4 and IPv6 sockets of the jail with jid, or of the host and the
+// jails without vnet if jid is 0
+func sockstat(jid int, state string) ([]JailSocket, error) {
+
+	args := []string{"-46q", "-l"}
+	if state == "connected" {
+		args[1] = "-c"
+	}
+	if jid > 0 {
+		args = append(args, "-j", strconv.Itoa(jid))
+	}
+	b, err := system.Run("/usr/bin/sockstat", args)
+	if err != nil {
+		return nil, err
+	}
+
+	sockets := []JailSocket{}
+	for _, line := range strings.Split(string(b), "\n") {
+		// USER COMMAND PID FD PROTO LOCAL FOREIGN
+		f := strings.Fields(line)
+		if len(f) < 7 {
+			continue
+		}
+		s := JailSocket{User: f[0], Command: f[1], Proto: f[4], Local: f[5], Foreign: f[6], State: state}
+		s.Pid, _ = strconv.Atoi(f[2])
+		sockets = append(sockets, s)
+	}
+	return sockets, nil
+}
+
+// portConflicts set the Conflict of the listening sockets of the jail: the host and the other jails that listen on the same
+// protocol and port with an overlapping address. A vnet jail has its own network stack, its ports do not collide
+func (cfg *Jmgr) portConflicts(jail Jail, sockets []JailSocket) error {
+
+	if b, err := system.Run("/usr/sbin/jls", []string{"-j", jail.Name, "vnet"}); err == nil && strings.TrimSpace(string(b)) == "new" {
+		return nil
+	}
+	all, err := sockstat(0, "listen")
+	if err != nil {
+		return err
+	}
+	procs, err := lifecycle.Procs(0)
+	if err != nil {
+		return err
+	}
+	jids := make(map[int]int) // jid by pid
+	for _, p := range procs {
+		jids[p.Pid] = p.Jid
+	}
+
+	for i, s := range sockets {
+		if s.State != "listen" {
+			continue
+		}
+		var conflicts []string
+		for _, other := range all {
+			if jids[other.Pid] == jail.Jid || !socketsOverlap(s, other) {
+				continue
+			}
+			by := "host " + other.Command
+			if jid := jids[other.Pid]; jid > 0 {
+				by = "jail " + strconv.Itoa(jid) + " " + other.Command
+				if j := slices.IndexFunc(cfg.Jails, func(j Jail) bool { return j.Jid == jid }); j >= 0 {
+					by = "jail " + cfg.Jails[j].Name + " " + other.Command
+				}
+			}
+			if !slices.Contains(conflicts, by) {
+				conflicts = append(conflicts, by)
+			}
+		}
+		sockets[i].Conflict = strings.Join(conflicts, ", ")
+	}
+	return nil
+}
+
+// socketsOverlap return true if the sockets are of the same protocol, tcp or udp, and IP version, with the same port
+// and the same address or one of them on any address, ex: tcp4 *:80 and tcp46 10.0.0.5:80
+func socketsOverlap(a JailSocket, b JailSocket) bool {
+
+	protoA, versionA := strings.TrimRight(a.Proto, "46"), strings.TrimLeft(a.Proto, "tcpud")
+	protoB, versionB := strings.TrimRight(b.Proto, "46"), strings.TrimLeft(b.Proto, "tcpud")
+	if protoA != protoB || !strings.ContainsAny(versionA, versionB) {
+		return false
+	}
+	i, j := strings.LastIndex(a.Local, ":"), strings.LastIndex(b.Local, ":")
+	if i < 0 || j < 0 || a.Local[i:] != b.Local[j:] {
+		return false
+	}
+	return a.Local[:i] == b.Local[:j] || a.Local[:i] == "*" || b.Local[:j] == "*"
+}
+
+// Health define, remove and run the healthchecks of the jails, see JailMeta.Health
+type Health struct{}
+
+func (Health) Name() string { return "health" }
+func (Health) Synopsis() string {
+	return "Check that the service in a jail is alive, with a command, a TCP or an HTTP probe."
+}
+func (Health) Usage() string {
+	return `health 'jail name' ['jail name2' ...] | -all
+health -cmd 'command' | -tcp '[address:]port' | -http 'url|[port]/path' [-interval 'duration'] [-timeout 'duration'] 'jail name'
+health -d 'jail name'`
+}
+
+func (Health) Run(args []string) {
+
+	fset := newFlagSet(args[0])
+	all := fset.Bool("all", false, "Check all jails with a healthcheck.")
+	command := fset.String("cmd", "", "Command run in the jail with jexec, healthy if it exits 0.")
+	tcp := fset.String("tcp", "", "TCP port, or address:port, healthy if it accepts a connection.")
+	httpURL := fset.String("http", "", "URL, or [port]/path on the jail IP, healthy on a 2xx or 3xx reply.")
+	interval := fset.Duration("interval", 0, "Interval of the checks of jmgr daemon, default 30s.")
+	timeout := fset.Duration("timeout", 0, "Timeout of a check, default 5s.")
+	remove := fset.Bool("d", false, "Remove the healthcheck.")
+	fset.Parse(args[1:])
+	args = append([]string{args[0]}, fset.Args()...)
+
+	probes := 0
+	for _, p := range []string{*command, *tcp, *httpURL} {
+		if len(p) > 0 {
+			probes++
+		}
+	}
+	if probes > 1 || (probes > 0 && *remove) || (*interval != 0 || *timeout != 0) && probes == 0 || *interval < 0 || *timeout < 0 {
+		help()
+	}
+
+	// define or remove
+	if probes > 0 || *remove {
+		if len(args) != 2 || *all {
+			help()
+		}
+		cfg, jail, err := verifyArgs(2, 1, true, true, args)
+		if err != nil {
+			fatal(err)
+		}
+		jail.Meta.Health = nil
+		if probes > 0 {
+			jail.Meta.Health = &Healthcheck{Command: *command, TCP: *tcp, HTTP: *httpURL}
+			if *interval > 0 {
+				jail.Meta.Health.Interval = interval.String()
+			}
+			if *timeout > 0 {
+				jail.Meta.Health.Timeout = timeout.String()
+			}
+		}
+		if err := cfg.writeMeta(jail.Name, jail.Meta); err != nil {
+			fatal(err)
+		}
+		if jail.Meta.Health == nil {
+			fmt.Println(jail.Name + ": no healthcheck")
+		} else {
+			fmt.Println(jail.Name + ": " + jail.Meta.Health.String())
+		}
+		printJSON(map[string]any{"jail": jail.Name, "health": jail.Meta.Health})
+		return
+	}
+
+	if (*all && len(args) > 1) || (!*all && len(args) < 2) {
+		help()
+	}
+	cfg := jmgrInit()
+	var jails []Jail
+	for _, name := range args[1:] {
+		if !cfg.exist(name) {
+			fatal(errNoJail(name))
+		}
+		jail := cfg.jail(name)
+		if jail.Meta.Health == nil {
+			fatal(jmgrError(exitNotFound, "Jail "+name+" has no healthcheck.", "Define one with: jmgr health -tcp 'port' "+name))
+		}
+		jails = append(jails, jail)
+	}
+	if *all {
+		jails = slices.DeleteFunc(slices.Clone(cfg.Jails), func(j Jail) bool { return j.Meta.Health == nil })
+	}
+
+	// in parallel, a check can take its timeout
+	results := make([]HealthStatus, len(jails))
+	var wg sync.WaitGroup
+	for i := range jails {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = jailHealth(jails[i])
+		}(i)
+	}
+	wg.Wait()
+
+	var unhealthy []string
+	for i := range jails {
+		jails[i].Health = &results[i]
+		if results[i].Status == "unhealthy" {
+			unhealthy = append(unhealthy, jails[i].Name)
+		}
+	}
+	if jsonOutput {
+		type jailHealth struct {
+			Jail   string       `json:"jail"`
+			Check  string       `json:"check"`
+			Health HealthStatus `json:"health"`
+		}
+		out := []jailHealth{}
+		for _, jail := range jails {
+			out = append(out, jailHealth{jail.Name, jail.Meta.Health.String(), *jail.Health})
+		}
+		printJSON(out)
+	} else {
+		f := "%s\t%s\t%s\t%s\n"
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, f, "Jail", "Health", "Check", "Detail")
+		for _, jail := range jails {
+			fmt.Fprintf(w, f, jail.Name, jail.Health.Status, jail.Meta.Health.String(), jail.Health.Detail)
+		}
+		w.Flush()
+	}
+	if len(unhealthy) > 0 {
+		fatal(jmgrError(exitError, "Unhealthy: "+strings.Join(unhealthy, ", "), ""))
+	}
+}
+
+// jailHealth run the healthcheck of the jail, stopped if the jail is not running
+func jailHealth(jail Jail) HealthStatus {
+
+	h := jail.Meta.Health
+	st := HealthStatus{Status: "healthy", Time: time.Now().Format(time.RFC3339)}
+	if !jail.Runs() {
+		st.Status = "stopped"
+		return st
+	}
+	timeout := 5 * time.Second
+	if d, err := time.ParseDuration(h.Timeout); err == nil && d > 0 {
+		timeout = d
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var err error
+	switch {
+	case len(h.TCP) > 0:
+		addr := h.TCP
+		if !strings.Contains(addr, ":") {
+			addr = net.JoinHostPort(jailAddress(jail), addr)
+		}
+		var conn net.Conn
+		if conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", addr); err == nil {
+			conn.Close()
+		}
+	case len(h.HTTP) > 0:
+		url := h.HTTP
+		if !strings.Contains(url, "://") {
+			port, path, _ := strings.Cut(url, "/")
+			host := jailAddress(jail)
+			if len(port) > 0 {
+				host = net.JoinHostPort(host, port)
+			} else if strings.Contains(host, ":") {
+				host = "[" + host + "]"
+			}
+			url = "http://" + host + "/" + path
+		}
+		var req *http.Request
+		if req, err = http.NewRequestWithContext(ctx, http.MethodGet, url, nil); err == nil {
+			req.Header.Set("User-Agent", "jmgr/"+version)
+			var resp *http.Response
+			if resp, err = http.DefaultClient.Do(req); err == nil {
+				resp.Body.Close()
+				if resp.StatusCode >= 400 {
+					err = errors.New(url + " replied " + resp.Status)
+				}
+			}
+		}
+	default:
+		var out []byte
+		out, err = exec.CommandContext(ctx, system.Tool("/usr/sbin/jexec"), strconv.Itoa(jail.Jid), "/bin/sh", "-c", h.Command).CombinedOutput()
+		if lines := strings.Split(strings.TrimSpace(string(out)), "\n"); err != nil && len(lines[0]) > 0 {
+			err = fmt.Errorf("%w: %s", err, lines[len(lines)-1])
+		}
+	}
+	if ctx.Err() != nil {
+		err = errors.New("timeout after " + timeout.String())
+	}
+	if err != nil {
+		st.Status, st.Detail = "unhealthy", err.Error()
+	}
+	return st
+}
+
+// jailAddress return the address the healthcheck probes: the first IPv4 of the jail, else its first IPv6, else localhost
+func jailAddress(jail Jail) string {
+
+	for _, addrs := range [][]string{jail.Ipv4_addrs, {jail.Ipv4}, jail.Ipv6_addrs} {
+		for _, addr := range addrs {
+			addr, _, _ = strings.Cut(addr, "/")
+			if ip := net.ParseIP(strings.TrimSpace(addr)); ip != nil {
+				return ip.String()
+			}
+		}
+	}
+	return "127.0.0.1"
+}
+
+// Nice set the niceness of the processes of a jail, kept in JailMeta.Nice and applied at every start
+type Nice struct{}
+
+func (Nice) Name() string     { return "nice" }
+func (Nice) Synopsis() string { return "Set, remove or show the scheduling niceness of a jail." }
+func (Nice) Usage() string    { return "nice [-d] 'jail name' ['niceness -20..20']" }
+
+func (Nice) Run(args []string) {
+
+	fset := newFlagSet(args[0])
+	remove := fset.Bool("d", false, "Remove the niceness, the jail processes run at niceness 0.")
+	fset.Parse(args[1:])
+	args = append([]string{args[0]}, fset.Args()...)
+
+	set := *remove || len(args) > 2
+	cfg, jail, err := verifyArgs(2, 1, set, true, args)
+	if err != nil {
+		fatal(err)
+	}
+
+	if set {
+		nice := 0
+		if !*remove {
+			nice, err = strconv.Atoi(args[2])
+			if err != nil || nice < -20 || nice > 20 || len(args) > 3 {
+				fatal(jmgrError(exitUsage, "Not a niceness: "+strings.Join(args[2:], " "), "Use -20 (highest priority) to 20 (lowest), ex: jmgr nice "+jail.Name+" 10"))
+			}
+		}
+		if jail.Runs() {
+			if err := lifecycle.ApplyNice(jail, nice); err != nil {
+				fatal(err)
+			}
+		}
+		jail.Meta.Nice = nice
+		if err := cfg.writeMeta(jail.Name, jail.Meta); err != nil {
+			fatal(err)
+		}
+	}
+	fmt.Println(jail.Name+":", jail.Meta.Nice)
+	printJSON(map[string]any{"jail": jail.Name, "nice": jail.Meta.Nice})
+}
+
+// Lifecycle set the restart policy, start delay and stop timeout of a jail, kept in the metadata over the jail settings
+type Lifecycle struct{}
+
+func (Lifecycle) Name() string { return "lifecycle" }
+func (Lifecycle) Synopsis() string {
+	return "Set, remove or show the restart policy, start delay and stop timeout of a jail."
+}
+func (Lifecycle) Usage() string {
+	return `lifecycle [-restart no|on-failure|always] [-start-delay 'duration'] [-stop-timeout 'duration'] 'jail name'
+lifecycle -d 'jail name'`
+}
+
+func (Lifecycle) Run(args []string) {
+
+	fset := newFlagSet(args[0])
+	restart := fset.String("restart", "", "Restart policy of jmgr daemon: no, on-failure or always.")
+	delay := fset.String("start-delay", "", "Wait after the jail starts before the next jail starts, ex: 10s.")
+	timeout := fset.String("stop-timeout", "", "Kill the processes left when stop takes longer, ex: 30s.")
+	remove := fset.Bool("d", false, "Remove the settings, the jail settings in jmgr.conf apply.")
+	fset.Parse(args[1:])
+	args = append([]string{args[0]}, fset.Args()...)
+
+	set := len(*restart) > 0 || len(*delay) > 0 || len(*timeout) > 0
+	if len(args) != 2 || (set && *remove) {
+		help()
+	}
+	cfg, jail, err := verifyArgs(2, 1, set || *remove, true, args)
+	if err != nil {
+		fatal(err)
+	}
+
+	if len(*restart) > 0 && !slices.Contains(restartPolicies, *restart) {
+		fatal(jmgrError(exitUsage, "Unknown restart policy: "+*restart, "Use one of: "+strings.Join(restartPolicies, ", ")))
+	}
+	for _, value := range []string{*delay, *timeout} {
+		if d, err := time.ParseDuration(value); len(value) > 0 && (err != nil || d < 0) {
+			fatal(jmgrError(exitUsage, "Not a duration: "+value, "Use ex: 10s or 2m, 0 to disable"))
+		}
+	}
+
+	if set || *remove {
+		if *remove {
+			jail.Meta.Restart, jail.Meta.StartDelay, jail.Meta.StopTimeout = "", "", ""
+		}
+		if len(*restart) > 0 {
+			jail.Meta.Restart = *restart
+		}
+		if len(*delay) > 0 {
+			jail.Meta.StartDelay = *delay
+		}
+		if len(*timeout) > 0 {
+			jail.Meta.StopTimeout = *timeout
+		}
+		if err := cfg.writeMeta(jail.Name, jail.Meta); err != nil {
+			fatal(err)
+		}
+	}
+	policy := jail.RestartPolicy()
+	if len(policy) == 0 {
+		policy = "no"
+	}
+	fmt.Println(jail.Name+": restart "+policy+", start delay", jail.StartDelay(), "stop timeout", jail.StopTimeout())
+	printJSON(map[string]any{"jail": jail.Name, "restart": policy, "startdelay": jail.StartDelay().String(), "stoptimeout": jail.StopTimeout().String()})
+}
+
+// jailStopOptions the stop -timeout and -kill, else the stop timeout of the jail with -kill
+func jailStopOptions(j *Jail) lifecycle.StopOptions {
+
+	if stopOpts.Timeout == 0 && j.StopTimeout() > 0 {
+		return lifecycle.StopOptions{Timeout: j.StopTimeout(), Kill: true, Out: os.Stdout}
+	}
+	return stopOpts
+}
+
+// inWindow report if t is in a maintenance window, 'daily|<days> HH:MM-HH:MM', days ex: Sat,Sun. A window may pass midnight.
+func inWindow(window string, t time.Time) (bool, error) {
+
+	bad := fmt.Errorf("not a maintenance window: %s, use ex: 'daily 01:00-05:00' or 'Sat,Sun 22:00-02:00'", window)
+
+	days, hours, ok := strings.Cut(window, " ")
+	if !ok {
+		return false, bad
+	}
+	from, to, ok := strings.Cut(hours, "-")
+	if !ok {
+		return false, bad
+	}
+	start, err := time.Parse("15:04", from)
+	if err != nil {
+		return false, bad
+	}
+	end, err := time.Parse("15:04", to)
+	if err != nil {
+		return false, bad
+	}
+
+	var weekdays []string
+	if days != "daily" {
+		for _, d := range strings.Split(days, ",") {
+			if !slices.Contains([]string{"Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"}, d) {
+				return false, bad
+			}
+			weekdays = append(weekdays, d)
+		}
+	}
+
+	// minutes since midnight, a window passing midnight belongs to the day it starts
+	now := t.Hour()*60 + t.Minute()
+	s, e := start.Hour()*60+start.Minute(), end.Hour()*60+end.Minute()
+	day := t
+	var in bool
+	switch {
+	case s <= e:
+		in = now >= s && now < e
+	case now >= s:
+		in = true
+	case now < e:
+		in = true
+		day = t.AddDate(0, 0, -1)
+	}
+	if in && len(weekdays) > 0 {
+		in = slices.Contains(weekdays, day.Format("Mon"))
+	}
+	return in, nil
+}
+
+// heldBack return why update -all skips the jail, empty if it is updated
+func heldBack(jail *Jail) string {
+
+	if jail.Meta.Hold {
+		return "on hold"
+	}
+	if len(jail.Meta.Window) > 0 {
+		if in, err := inWindow(jail.Meta.Window, time.Now()); err != nil || !in {
+			return "outside window " + jail.Meta.Window
+		}
+	}
+	return ""
+}
+
+// ProviderMap dumps the contents of the provider map SubC
+type ProviderMap struct{}
+
+func (ProviderMap) Name() string     { return "subc" }
+func (ProviderMap) Synopsis() string { return "List the subcommands and their providers." }
+func (ProviderMap) Usage() string    { return "subc" }
+
+func (ProviderMap) Run(args []string) {
+
+	newFlagSet(args[0]).Parse(args[1:])
+
+	var f string = "%s\t%s\t%s\n"
+	var keys []string
+
+	for k := range SubC {
+		keys = append(keys, k)
+	}
+
+	slices.SortFunc(keys, func(a, b string) int {
+		return cmp.Compare(strings.ToLower(a), strings.ToLower(b))
+	})
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, f, "Subcommand", "Method", "Synopsis")
+	for _, k := range keys {
+		fmt.Fprintf(w, f, k, reflect.TypeOf(SubC[k]).String(), SubC[k].Synopsis())
+	}
+	for _, k := range plugins() {
+		fmt.Fprintf(w, f, k, "plugin", pluginPath(k))
+	}
+	w.Flush()
+}
+
+// pluginName, the subcommands that may be a plugin
+var pluginName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// pluginPath returns the path of the executable jmgr-'name' in PATH, empty if there is none
+func pluginPath(name string) string {
+
+	if !pluginName.MatchString(name) || SubC[name] != nil {
+		return ""
+	}
+	path, err := exec.LookPath("jmgr-" + name)
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+// plugins returns the sorted names of the jmgr-'name' executables in PATH, builtin subcommands excluded
+func plugins() []string {
+
+	var names []string
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		files, _ := filepath.Glob(filepath.Join(dir, "jmgr-*"))
+		for _, file := range files {
+			name := strings.TrimPrefix(filepath.Base(file), "jmgr-")
+			if len(pluginPath(name)) > 0 && !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// runPlugin executes the plugin with the arguments, the JSON inventory on stdin and the config location in
+// env JMGR_CONFIG, and exits with the exit code of the plugin
+func runPlugin(cfg *Jmgr, path string, args []string) {
+
+	inventory, err := json.Marshal(cfg)
+	if err != nil {
+		fatal(fmt.Errorf("runPlugin() %w", err))
+	}
+
+	cmd := exec.Command(path, args...)
+	cmd.Stdin = bytes.NewReader(inventory)
+	cmd.Stdout = os.Stdout
+	if jsonOutput {
+		cmd.Stdout = jsonStdout
+	}
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), "JMGR_CONFIG="+cfg.JmgrConfig)
+	if len(profile) > 0 {
+		cmd.Env = append(cmd.Env, "JMGR_PROFILE="+profile)
+	}
+	if assumeYes {
+		cmd.Env = append(cmd.Env, "JMGR_ASSUME_YES=1")
+	}
+	if jsonOutput {
+		cmd.Env = append(cmd.Env, "JMGR_JSON=1")
+	}
+
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		exit(exitErr.ExitCode())
+	} else if err != nil {
+		fatal(fmt.Errorf("runPlugin() %w", err))
+	}
+	exit(0)
+}
+
+//
+// helper methods for struct Jmgr
+//
+
+// Jmgr struct method to find and return a Jail struct from the array(slices) of jails
+func (cfg *Jmgr) jail(jailname string) Jail {
+
+	for _, jail := range cfg.Jails {
+		if jail.Name == jailname {
+			return jail
+		}
+	}
+	return Jail{}
+}
+
+// Jmgr struct method to return all jails with the tag, child jails excluded
+func (cfg *Jmgr) group(tag string) []Jail {
+
+	var jails []Jail
+
+	for _, jail := range cfg.Jails {
+		if len(jail.Parent) == 0 && slices.Contains(jail.Meta.Tags, tag) {
+			jails = append(jails, jail)
+		}
+	}
+	return jails
+}
+
+// groupSnapshot snapshot all jails in the group at the same time, with the same snapshot name 'label' (default: <group>-<time>)
+// and return the created snapshots.
+func (cfg *Jmgr) groupSnapshot(group string, label string, quiesce bool) ([]string, error) {
+
+	jails := cfg.group(group)
+	if len(jails) == 0 {
+		return nil, fmt.Errorf("no jails tagged @%s", group)
+	}
+
+	if len(label) == 0 {
+		label = group + "-" + time.Now().Format("2006-01-02T15:04:05")
+	}
+
+	// zfs snapshots are taken atomically within a pool, one command per pool
+	pools := make(map[string][]string)
+	var poolNames []string
+	for _, jail := range jails {
+		if len(jail.Dataset) == 0 {
+			return nil, fmt.Errorf("jail %s in @%s does not support zfs snapshot", jail.Name, group)
+		}
+		pool := strings.SplitN(jail.Dataset, "/", 2)[0]
+		if _, ok := pools[pool]; !ok {
+			poolNames = append(poolNames, pool)
+		}
+		pools[pool] = append(pools[pool], jail.Dataset+"@"+label)
+	}
+
+	// the jails whose PreSnapshot succeeded, thawed with PostSnapshot whatever happens next
+	var quiesced []Jail
+	var snapErr error
+	if quiesce {
+		for _, jail := range jails {
+			err := runHook("PreSnapshot", cfg.PreSnapshot, []string{jail.Name, jail.Path, jail.Dataset + "@" + label})
+			if err != nil {
+				snapErr = err
+				break
+			}
+			quiesced = append(quiesced, jail)
+		}
+	}
+
+	for _, pool := range poolNames {
+		if snapErr != nil {
+			break
+		}
+		_, err := system.Run("/sbin/zfs", append([]string{"snapshot"}, pools[pool]...))
+		if err != nil {
+			snapErr = fmt.Errorf("groupSnapshot() failed: %w", err)
+		}
+	}
+
+	// always thaw what was quiesced
+	for _, jail := range quiesced {
+		err := runHook("PostSnapshot", cfg.PostSnapshot, []string{jail.Name, jail.Path, jail.Dataset + "@" + label})
+		if err != nil && snapErr == nil {
+			snapErr = err
+		}
+	}
+
+	if snapErr != nil {
+		return nil, snapErr
+	}
+
+	var snaps []string
+	for _, pool := range poolNames {
+		snaps = append(snaps, pools[pool]...)
+	}
+	return snaps, nil
+}
+
+// groupRollback stop the group in reverse dependency order, rollback all jails to snapshot 'label' and start the jails that were running
+func (cfg *Jmgr) groupRollback(group string, label string, force bool, recursive bool) error {
+
+	jails := cfg.group(group)
+	if len(jails) == 0 {
+		return fmt.Errorf("no jails tagged @%s", group)
+	}
+
+	order, err := cfg.startOrder(jails)
+	if err != nil {
+		return err
+	}
+
+	// every member must have the snapshot
+	for _, jail := range order {
+		if jail.Meta.Protected {
+			return errProtected(&jail, "rollback")
+		}
+		if len(jail.Dataset) == 0 {
+			return fmt.Errorf("jail %s in @%s does not support zfs snapshot", jail.Name, group)
+		}
+		if !slices.Contains(jail.Snapshots, jail.Dataset+"@"+label) {
+			return fmt.Errorf("jail %s has no snapshot %s", jail.Name, jail.Dataset+"@"+label)
+		}
+		latest, err := zfs.LatestSnapshot(jail.Dataset)
+		if err == nil && latest != jail.Dataset+"@"+label && !recursive {
+			return fmt.Errorf("snapshot %s is not the latest snapshot for %s, use -r to destroy later snapshots", jail.Dataset+"@"+label, jail.Name)
+		}
+	}
+
+	if !force {
+		for _, jail := range order {
+			fmt.Println("Jail:", jail.Name, "snapshot:", jail.Dataset+"@"+label)
+		}
+		askExitOnNo("Stop and rollback these jails (yes/No)? ")
+	}
+
+	// stop in reverse dependency order
+	running := make(map[string]bool)
+	for i := len(order) - 1; i >= 0; i-- {
+		if order[i].Runs() {
+			running[order[i].Name] = true
+			err := startstop("stop", &order[i])
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, jail := range order {
+		rargs := []string{"rollback", jail.Dataset + "@" + label}
+		if recursive {
+			rargs = []string{"rollback", "-r", jail.Dataset + "@" + label}
+		}
+		_, err := system.Run("/sbin/zfs", rargs)
+		if err != nil {
+			return err
+		}
+		fmt.Println("Jail", jail.Name, "rolled back to", jail.Dataset+"@"+label)
+	}
+
+	// start in dependency order
+	for _, jail := range order {
+		if running[jail.Name] {
+			jail.Jid = 0
+			err := startstop("start", &jail)
+			if err != nil {
+				return err
+			}
+			fmt.Println("Jail", jail.Name, "started.")
+		}
+	}
+	return nil
+}
+
+// startOrder sort jails so that a jail comes after the jails it depends on, dependencies outside 'jails' are ignored
+func (cfg *Jmgr) startOrder(jails []Jail) ([]Jail, error) {
+
+	var order []Jail
+	state := make(map[string]int) // 0 unvisited, 1 visiting, 2 done
+
+	byName := make(map[string]Jail)
+	for _, jail := range jails {
+		byName[jail.Name] = jail
+	}
+
+	var visit func(jail Jail) error
+	visit = func(jail Jail) error {
+		switch state[jail.Name] {
+		case 1:
+			return fmt.Errorf("dependency loop at jail %s", jail.Name)
+		case 2:
+			return nil
+		}
+		state[jail.Name] = 1
+		for _, dep := range jail.Meta.Depends {
+			if d, ok := byName[dep]; ok {
+				if err := visit(d); err != nil {
+					return err
+				}
+			}
+		}
+		state[jail.Name] = 2
+		order = append(order, jail)
+		return nil
+	}
+
+	for _, jail := range jails {
+		if err := visit(jail); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// Jmgr struct method to check if the jail name already exist in the jails struct
+func (cfg *Jmgr) exist(name string) bool {
+
+	if index := slices.IndexFunc(cfg.Jails, func(j Jail) bool { return j.Name == name }); index >= 0 {
+		return true
+	}
+	return false
+}
+
+// Jmgr struct method to get index of a existing jail.
+func (cfg *Jmgr) jIndex(name string) int {
+
+	if index := slices.IndexFunc(cfg.Jails, func(j Jail) bool { return j.Name == name }); index >= 0 {
+		return index
+	}
+	return -42
+}
+
+// createJailConfig Create new /etc/jail.conf.d/<jail.conf> file from template
+func (cfg *Jmgr) createJailConfig(newJail NewJail) error {
+
+	NewConfStr, err := cfg.renderJailConfig(newJail)
+	if err != nil {
+		return err
+	}
+
+	if err = os.WriteFile(newJail.ConfigPath, []byte(NewConfStr), 0666); err != nil {
+		return fmt.Errorf("write to %s, %s", newJail.ConfigPath, err.Error())
+	}
+
+	// record the template in the jail metadata
+	meta, err := cfg.readMeta(newJail.Name)
+	if err != nil {
+		return err
+	}
+	meta.Template = newJail.Template
+	meta.PkgCache = newJail.PkgCache
+	meta.PkgRepos = newJail.PkgRepos
+	meta.Nice = newJail.Nice
+
+	return cfg.writeMeta(newJail.Name, meta)
+}
+
+// renderJailConfig return the jail configuration for a new jail from template
+func (cfg *Jmgr) renderJailConfig(newJail NewJail) (string, error) {
+
+	if len(newJail.Path) == 0 {
+		newJail.Path = cfg.JailsHome + "/" + newJail.Name
+	}
+	newJail.IPconf = templates.IPConf(newJail.IP, newJail.Iface, newJail.InheritIP)
+
+	templateFile, err := cfg.templateFile(newJail.Template)
+	if err != nil {
+		return "", err
+	}
+	return templates.Render(templateFile, templates.Jail{
+		Name:        newJail.Name,
+		Hostname:    newJail.Hostname,
+		Path:        newJail.Path,
+		IPConf:      newJail.IPconf,
+		PkgCache:    cfg.pkgCacheMount(newJail.Path, newJail.PkgCache),
+		Nice:        newJail.Nice,
+		StopTimeout: newJail.StopTimeout,
+	})
+}
+
+// first line of the pkg repository files written by jmgr
+const pkgRepoMark = "# Created by jmgr(8)"
+
+// writePkgRepos write the named pkg repositories to the jail /usr/local/etc/pkg/repos, repository files written before by jmgr and not named are removed
+func (cfg *Jmgr) writePkgRepos(path string, names []string) error {
+
+	dir := path + "/usr/local/etc/pkg/repos"
+	if files, err := filepath.Glob(dir + "/*.conf"); err == nil {
+		for _, file := range files {
+			b, err := os.ReadFile(file)
+			if err == nil && strings.HasPrefix(string(b), pkgRepoMark) && !slices.Contains(names, strings.TrimSuffix(filepath.Base(file), ".conf")) {
+				os.Remove(file)
+			}
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("writePkgRepos() %w", err)
+	}
+	for _, name := range names {
+		repo, ok := cfg.PkgRepos[name]
+		if !ok {
+			return fmt.Errorf("no pkg repository %s in PkgRepos", name)
+		}
+		var conf strings.Builder
+		fmt.Fprintf(&conf, "%s\n%s: {\n", pkgRepoMark, name)
+		if len(repo.URL) > 0 {
+			fmt.Fprintf(&conf, "  url: \"%s\",\n", repo.URL)
+		}
+		if len(repo.MirrorType) > 0 {
+			fmt.Fprintf(&conf, "  mirror_type: \"%s\",\n", repo.MirrorType)
+		}
+		if len(repo.SignatureType) > 0 {
+			fmt.Fprintf(&conf, "  signature_type: \"%s\",\n", repo.SignatureType)
+		}
+		if len(repo.Fingerprints) > 0 {
+			fmt.Fprintf(&conf, "  fingerprints: \"%s\",\n", repo.Fingerprints)
+		}
+		if len(repo.PubKey) > 0 {
+			fmt.Fprintf(&conf, "  pubkey: \"%s\",\n", repo.PubKey)
+		}
+		if repo.Priority != 0 {
+			fmt.Fprintf(&conf, "  priority: %d,\n", repo.Priority)
+		}
+		if repo.Disabled {
+			conf.WriteString("  enabled: no\n}\n")
+		} else {
+			conf.WriteString("  enabled: yes\n}\n")
+		}
+		if err := os.WriteFile(dir+"/"+name+".conf", []byte(conf.String()), 0644); err != nil {
+			return fmt.Errorf("writePkgRepos() %w", err)
+		}
+	}
+	return nil
+}
+
+// pkgCacheMount return the jail.conf mount line for the shared pkg cache, empty if not used
+func (cfg *Jmgr) pkgCacheMount(path string, use bool) string {
+
+	if !use {
+		return ""
+	}
+	return templates.PkgCacheMount(cfg.PkgCacheDir, path)
+}
+
+// templateFile return the jail.conf template file for a named template, empty name is the default JailConfTemplate
+func (cfg *Jmgr) templateFile(name string) (string, error) {
+	return templates.File(cfg.JailTemplateDir, cfg.JailConfTemplate, name)
+}
+
+// readMeta return the jmgr metadata for a jail, a jail without metadata file returns empty metadata
+func (cfg *Jmgr) readMeta(name string) (JailMeta, error) {
+
+	var meta JailMeta
+
+	b, err := os.ReadFile(filepath.Join(cfg.JailMetaDir, name+".yml"))
+	if os.IsNotExist(err) {
+		cfg.mergeTags(name, &meta)
+		return meta, nil
+	}
+	if err != nil {
+		return meta, fmt.Errorf("readMeta() %w", err)
+	}
+
+	err = yaml.Unmarshal(b, &meta)
+	if err != nil {
+		return meta, fmt.Errorf("readMeta() %s: %w", name, err)
+	}
+	cfg.mergeTags(name, &meta)
+	return meta, nil
+}
+
+// mergeTags add the tags from the jail settings in jmgr.conf, see writeMeta
+func (cfg *Jmgr) mergeTags(name string, meta *JailMeta) {
+
+	for _, tag := range cfg.JailSettings[name].Tags {
+		if !slices.Contains(meta.Tags, tag) {
+			meta.Tags = append(meta.Tags, tag)
+		}
+	}
+}
+
+// writeMeta store the jmgr metadata for a jail
+func (cfg *Jmgr) writeMeta(name string, meta JailMeta) error {
+
+	// the tags from the jail settings stay in jmgr.conf
+	if settings := cfg.JailSettings[name].Tags; len(settings) > 0 {
+		meta.Tags = slices.DeleteFunc(slices.Clone(meta.Tags), func(t string) bool { return slices.Contains(settings, t) })
+	}
+
+	b, err := yaml.Marshal(meta)
+	if err != nil {
+		return fmt.Errorf("writeMeta() %w", err)
+	}
+
+	err = os.MkdirAll(cfg.JailMetaDir, 0755)
+	if err != nil {
+		return fmt.Errorf("writeMeta() %w", err)
+	}
+
+	err = os.WriteFile(filepath.Join(cfg.JailMetaDir, name+".yml"), b, 0644)
+	if err != nil {
+		return fmt.Errorf("writeMeta() %w", err)
+	}
+	return nil
+}
+
+// removeMeta remove the jmgr metadata for a jail
+func (cfg *Jmgr) removeMeta(name string) error {
+
+	err := os.Remove(filepath.Join(cfg.JailMetaDir, name+".yml"))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("removeMeta() %w", err)
+	}
+	return nil
+}
+
+// similarKey return the config key closest to key, at most two edits away, or empty
+func similarKey(key string) string {
+
+	// edit distance, Levenshtein
+	distance := func(a, b string) int {
+		prev := make([]int, len(b)+1)
+		for j := range prev {
+			prev[j] = j
+		}
+		for i := 1; i <= len(a); i++ {
+			cur := make([]int, len(b)+1)
+			cur[0] = i
+			for j := 1; j <= len(b); j++ {
+				cost := 1
+				if a[i-1] == b[j-1] {
+					cost = 0
+				}
+				cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			}
+			prev = cur
+		}
+		return prev[len(b)]
+	}
+
+	best, bestDistance := "", 3
+	t := reflect.TypeOf(config.Settings{})
+	for i := 0; i < t.NumField(); i++ {
+		name := t.Field(i).Tag.Get("yaml")
+		if len(name) == 0 {
+			continue
+		}
+		if d := distance(strings.ToLower(key), strings.ToLower(name)); d < bestDistance {
+			best, bestDistance = name, d
+		}
+	}
+	return best
+}
+
+// configCheckFile validate a jmgr config file, return all problems as 'file:line: problem'
+func configCheckFile(file string) ([]string, error) {
+
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("configCheckFile() %w", err)
+	}
+	lines := strings.Split(string(b), "\n")
+	format := config.Format(file)
+	var problems []string
+
+	// line of a top level key, 0 if not found
+	lineOf := func(key string) int {
+		rgx := config.KeyRegexp(format, key)
+		for i, l := range lines {
+			if rgx.MatchString(l) {
+				return i + 1
+			}
+		}
+		return 0
+	}
+	reportIn := func(path string, line int, problem string) {
+		if line > 0 {
+			problems = append(problems, path+":"+strconv.Itoa(line)+": "+problem)
+		} else {
+			problems = append(problems, path+": "+problem)
+		}
+	}
+	report := func(line int, problem string) {
+		reportIn(file, line, problem)
+	}
+
+	// unknown keys and types, all reported with line numbers, return false if path can't be decoded at all
+	decode := func(path string, b []byte, cfg *config.Settings) bool {
+		b, version, changes := config.Migrate(b, config.Format(path))
+		for _, c := range changes {
+			reportIn(path, 0, "deprecated key, "+c+", run: jmgr config migrate")
+		}
+		if version > config.Version {
+			reportIn(path, 0, "config version "+strconv.Itoa(version)+" is newer than this jmgr ("+strconv.Itoa(config.Version)+")")
+		}
+		err := config.Decode(b, config.Format(path), cfg, true)
+		var errs []string
+		if terr, ok := err.(*yaml.TypeError); ok {
+			errs = terr.Errors
+		} else if cerr, ok := err.(config.Errors); ok {
+			errs = cerr
+		} else if err != nil {
+			reportIn(path, 0, err.Error())
+			return false
+		}
+		for _, e := range errs {
+			var line int
+			if _, err := fmt.Sscanf(e, "line %d:", &line); err == nil {
+				_, e, _ = strings.Cut(e, ": ")
+			}
+			e = strings.TrimSpace(strings.Replace(e, "in type config.Settings", "", 1))
+			var field string
+			if _, err := fmt.Sscanf(e, "field %s not found", &field); err == nil {
+				e = "unknown key " + field
+				if key := similarKey(field); len(key) > 0 {
+					e += ", did you mean " + key + "?"
+				}
+			}
+			reportIn(path, line, e)
+		}
+		return true
+	}
+
+	var cfg config.Settings
+	if !decode(file, b, &cfg) {
+		return problems, nil
+	}
+
+	// the values are checked merged with the conf.d files
+	for _, f := range config.ConfDFiles(file) {
+		if fb, err := os.ReadFile(f); err != nil {
+			reportIn(f, 0, err.Error())
+		} else {
+			decode(f, fb, &cfg)
+		}
+	}
+
+	// values
+	t := reflect.TypeOf(cfg)
+	v := reflect.ValueOf(cfg)
+	for i := 0; i < t.NumField(); i++ {
+		key := t.Field(i).Name
+		if len(t.Field(i).Tag.Get("yaml")) == 0 || t.Field(i).Type.Kind() != reflect.String {
+			continue
+		}
+		value := v.Field(i).String()
+		if err := config.Check(key, value); err != nil {
+			report(lineOf(key), err.Error())
+			continue
+		}
+		switch key {
+		case "OsMediaDir", "JailTemplateDir", "PkgCacheDir":
+			if s, err := os.Stat(value); len(value) > 0 && (err != nil || !s.IsDir()) {
+				report(lineOf(key), key+": directory "+value+" does not exist")
+			}
+		case "JailsHome":
+			if s, err := os.Stat(value); len(value) > 0 && len(cfg.ZFSdataSet) == 0 && (err != nil || !s.IsDir()) {
+				report(lineOf(key), key+": directory "+value+" does not exist")
+			}
+		}
+	}
+	if len(cfg.ZFSdataSet) == 0 && len(cfg.JailsHome) == 0 {
+		report(0, "ZFSdataSet or JailsHome must be set")
+	}
+	for _, key := range []string{"OsMediaDir", "OsUrlPrefix", "JailConfTemplate"} {
+		if len(v.FieldByName(key).String()) == 0 {
+			report(0, key+" must be set")
+		}
+	}
+
+	// profiles
+	var profiles []string
+	for name := range cfg.Profiles {
+		profiles = append(profiles, name)
+	}
+	slices.Sort(profiles)
+	for _, name := range profiles {
+		for key, value := range cfg.Profiles[name] {
+			if k, err := config.Key(key); err != nil {
+				report(lineOf("Profiles"), "Profiles "+name+": unknown key "+key)
+			} else if err := config.Check(k, value); err != nil {
+				report(lineOf("Profiles"), "Profiles "+name+": "+err.Error())
+			}
+		}
+	}
+	files, _ := filepath.Glob(filepath.Join(filepath.Dir(file), "profiles.d", "*"))
+	for _, f := range files {
+		pb, err := os.ReadFile(f)
+		if err == nil {
+			err = config.Decode(pb, config.Format(f), &config.Settings{}, true)
+		}
+		if err != nil {
+			problems = append(problems, f+": "+strings.ReplaceAll(err.Error(), "\n", "; "))
+		}
+	}
+
+	// external tools
+	var tools []string
+	for name := range cfg.Tools {
+		tools = append(tools, name)
+	}
+	slices.Sort(tools)
+	for _, name := range tools {
+		path := cfg.Tools[name]
+		if _, ok := system.SystemTools[name]; !ok {
+			report(lineOf("Tools"), "Tools "+name+": not a tool jmgr runs")
+		} else if s, err := os.Stat(path); !filepath.IsAbs(path) || err != nil || s.IsDir() || s.Mode()&0111 == 0 {
+			report(lineOf("Tools"), "Tools "+name+": "+path+" is not an executable file (absolute path)")
+		}
+	}
+
+	// webhooks
+	var hooks []string
+	for name := range cfg.Webhooks {
+		hooks = append(hooks, name)
+	}
+	slices.Sort(hooks)
+	for _, name := range hooks {
+		hook := cfg.Webhooks[name]
+		if u, err := url.Parse(hook.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+			report(lineOf("Webhooks"), "Webhooks "+name+": URL must be an http(s) URL")
+		}
+		for _, e := range hook.Events {
+			if !slices.Contains(events, e) {
+				report(lineOf("Webhooks"), "Webhooks "+name+": unknown event "+e+", one of: "+strings.Join(events, ", "))
+			}
+		}
+	}
+
+	// alerts
+	var alertNames []string
+	for name := range cfg.Alerts {
+		alertNames = append(alertNames, name)
+	}
+	slices.Sort(alertNames)
+	for _, name := range alertNames {
+		a := cfg.Alerts[name]
+		if !slices.Contains(alertChecks, a.Check) {
+			report(lineOf("Alerts"), "Alerts "+name+": unknown Check "+a.Check+", one of: "+strings.Join(alertChecks, ", "))
+		}
+		if a.Above < 0 || a.Above > 100 {
+			report(lineOf("Alerts"), "Alerts "+name+": Above must be a percentage, 1 to 100")
+		}
+		if len(a.Hook) > 0 {
+			if s, err := os.Stat(a.Hook); !filepath.IsAbs(a.Hook) || err != nil || s.IsDir() || s.Mode()&0111 == 0 {
+				report(lineOf("Alerts"), "Alerts "+name+": Hook "+a.Hook+" is not an executable file (absolute path)")
+			}
+		}
+	}
+
+	// per jail settings
+	var jailNames []string
+	for name := range cfg.JailSettings {
+		jailNames = append(jailNames, name)
+	}
+	slices.Sort(jailNames)
+	for _, name := range jailNames {
+		js := cfg.JailSettings[name]
+		line := lineOf("Jails")
+		if err := config.Check("JailIface", js.Iface); err != nil {
+			report(line, "Jails "+name+": "+err.Error())
+		}
+		if len(js.Template) > 0 {
+			dir := cfg.JailTemplateDir
+			if len(dir) == 0 {
+				dir = filepath.Join(filepath.Dir(file), "templates")
+			}
+			if _, err := templates.File(dir, "", js.Template); err != nil {
+				report(line, "Jails "+name+": "+err.Error())
+			}
+		}
+		for _, repo := range js.PkgRepos {
+			if _, ok := cfg.PkgRepos[repo]; !ok {
+				report(line, "Jails "+name+": no pkg repository "+repo+" in PkgRepos")
+			}
+		}
+		if js.Nice < -20 || js.Nice > 20 {
+			report(line, "Jails "+name+": Nice "+strconv.Itoa(js.Nice)+" is not -20..20")
+		}
+		if len(js.Restart) > 0 && !slices.Contains(restartPolicies, js.Restart) {
+			report(line, "Jails "+name+": unknown Restart "+js.Restart+", one of: "+strings.Join(restartPolicies, ", "))
+		}
+		for key, value := range map[string]string{"StartDelay": js.StartDelay, "StopTimeout": js.StopTimeout} {
+			if d, err := time.ParseDuration(value); len(value) > 0 && (err != nil || d < 0) {
+				report(line, "Jails "+name+": "+key+" "+value+" is not a duration, ex: 30s")
+			}
+		}
+		for _, rule := range js.Limits {
+			if err := checkRule(rule); err != nil {
+				report(line, "Jails "+name+": limit "+rule+": "+err.Error())
+			}
+		}
+	}
+
+	// named pkg repositories
+	names := make([]string, 0, len(cfg.PkgRepos))
+	for name := range cfg.PkgRepos {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		repo := cfg.PkgRepos[name]
+		line := lineOf("PkgRepos")
+		if len(repo.URL) == 0 {
+			report(line, "PkgRepos "+name+": URL must be set")
+		}
+		if !slices.Contains([]string{"", "srv", "http", "none"}, repo.MirrorType) {
+			report(line, "PkgRepos "+name+": MirrorType must be srv, http or none")
+		}
+		if !slices.Contains([]string{"", "none", "pubkey", "fingerprints"}, repo.SignatureType) {
+			report(line, "PkgRepos "+name+": SignatureType must be none, pubkey or fingerprints")
+		}
+		if _, err := os.Stat(repo.PubKey); repo.SignatureType == "pubkey" && err != nil {
+			report(line, "PkgRepos "+name+": PubKey "+repo.PubKey+" does not exist")
+		}
+	}
+	return problems, nil
+}
+
+// addJails method goes out and harvest info about existing jails and add these to the Jmgr struct
+func (cfg *Jmgr) addJails() {
+
+	// expressions to capture the jail conf syntax
+	rgx := make(map[string]*regexp.Regexp)
+	rgx["name"] = regexp.MustCompile(`(.*)\s+{`)
+	rgx["Ipv4"] = regexp.MustCompile(`ip4\.addr.=\s*(\d+\.\d+\.\d+\.\d+);`)
+	rgx["Ipv4Inherit"] = regexp.MustCompile(`ip4\s+=\s+(\w+);`)
+	rgx["Path"] = regexp.MustCompile(`path.=\s*"(.*)";`)
+	rgx["Hostname"] = regexp.MustCompile(`hostname\s?=\s?(?P<Hostname>.*);`)
+	rgx["end"] = regexp.MustCompile(`}`)
+
+	b, err := system.Run("/usr/sbin/jls", []string{"-v", "--libxo", "json"})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "addJails() -> jls: "+err.Error())
+	}
+
+	var f Jls
+	if len(b) > 0 {
+		if err := json.Unmarshal(b, &f); err != nil {
+			fmt.Fprintln(os.Stderr, "addJails() -> json: "+err.Error())
+		}
+	}
+
+	// extract the interesting part of the JSON jls struct
+	cfg.Jails = append(cfg.Jails, f.Jls.JailSlices...)
+
+	// Find jails in /etc/jail.conf.d/*.conf
+	files, err := os.ReadDir(cfg.JailsConfD)
+	if err == nil {
+		for _, f := range files {
+			if strings.Contains(f.Name(), ".conf") {
+				cfg.addJailDetailsFromFile(cfg.JailsConfD+"/"+f.Name(), rgx)
+			}
+		}
+	}
+
+	// and the jail.conf
+	cfg.addJailDetailsFromFile("/etc/jail.conf", rgx)
+
+	// get jails that start on boot, sysrc fails when jail_list is not set: none start on boot
+	jailList, _ := system.Run("/usr/sbin/sysrc", []string{"-n", "jail_list"})
+	// Add more details to all jails
+	for i := 0; i < len(cfg.Jails); i++ {
+
+		// add start on boot
+		cfg.Jails[i].OnBoot = inJailList(jailList, cfg.Jails[i].Name)
+
+		// add ZFS dataset
+		if len(cfg.Jails[i].Path) > 0 {
+			p, err := os.Stat(cfg.Jails[i].Path)
+			if err == nil {
+				if p.IsDir() {
+					b, err := system.Run("/sbin/zfs", []string{"list", "-H", cfg.Jails[i].Path})
+					if err == nil {
+						words := strings.Fields(string(b[:]))
+						if len(words) > 0 {
+							regx := regexp.MustCompile(cfg.Jails[i].Name)
+							match := regx.FindStringSubmatch(string(words[0]))
+							if len(match) > 0 {
+								cfg.Jails[i].Dataset = words[0]
+								snaps, err := zfs.Snapshots(cfg.Jails[i].Dataset)
+								if err == nil {
+									cfg.Jails[i].Snapshots = snaps
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+
+		// add jmgr metadata and the settings from jmgr.conf
+		meta, err := cfg.readMeta(cfg.Jails[i].Name)
+		if err == nil {
+			cfg.Jails[i].Meta = meta
+		}
+		cfg.Jails[i].Settings = cfg.JailSettings[cfg.Jails[i].Name]
+
+		// add jail os version
+		v, err := jailVersion(cfg.Jails[i].Path)
+		if err == nil {
+			cfg.Jails[i].OsVersion = v
+		} else if len(cfg.Jails[i].Path) > 0 && system.NotRoot() {
+			cfg.Jails[i].OsVersion = "unknown"
+		}
+
+		// add IPv4 address from jls Ipv4_addrs array if empty or if defined set it to inherit
+		if len(cfg.Jails[i].Ipv4) == 0 && len(cfg.Jails[i].Ipv4_addrs) > 0 {
+			cfg.Jails[i].Ipv4 = cfg.Jails[i].Ipv4_addrs[0]
+
+		} else if len(cfg.Jails[i].Ipv4Inherit) > 0 {
+			cfg.Jails[i].Ipv4 = cfg.Jails[i].Ipv4Inherit
+		}
+
+		// is it a child? family[0] == Parent, family[1] == Child
+		if family := strings.Split(cfg.Jails[i].Name, "."); len(family) > 1 {
+			if cfg.exist(family[0]) {
+
+				cfg.Jails[cfg.jIndex(family[0])].IsParent = true
+
+				// need root to run commands in a jail. Rely on the "." name convention for regular user for now.
+				if system.NotRoot() {
+					cfg.Jails[i].Parent = family[0]
+
+				} else {
+					b, err := system.Run("/usr/sbin/jexec", []string{family[0], "/sbin/sysctl", "-n", "security.jail.children.cur"})
+					if err == nil {
+						if string(b) != "0" {
+							cfg.Jails[i].Parent = family[0]
+						}
+					} else {
+						cfg.Jails[i].Parent = "unknown"
+					}
+				}
+			}
+		}
+	}
+}
+
+// add/update jails from /etc/jail.conf & /etc/jail.conf.d/*.conf
+func (cfg *Jmgr) addJailDetailsFromFile(file string, rgx map[string]*regexp.Regexp) {
+
+	f, err := os.Open(file)
+	if err == nil {
+		defer f.Close()
+
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			match := rgx["name"].FindStringSubmatch(scanner.Text())
+			if len(match) > 0 {
+				var addJail Jail
+				addJail.Name = strings.TrimSpace(match[1])
+				addJail.ConfigPath = file
+
+				for scanner.Scan() {
+					// found end of jail conf, add info to existing jail struct or add a new jail to the struct
+					match := rgx["end"].FindStringSubmatch(scanner.Text())
+					if len(match) > 0 {
+						if cfg.exist(addJail.Name) {
+							for i := 0; i < len(cfg.Jails); i++ {
+								if cfg.Jails[i].Name == addJail.Name {
+									cfg.Jails[i].Hostname = strings.Trim(addJail.Hostname, `"`)
+									cfg.Jails[i].Path = addJail.Path
+									cfg.Jails[i].Ipv4 = addJail.Ipv4
+									cfg.Jails[i].Ipv4Inherit = addJail.Ipv4Inherit
+									cfg.Jails[i].ConfigPath = addJail.ConfigPath
+								}
+							}
+						} else {
+							addJail.Hostname = strings.Trim(addJail.Hostname, `"`)
+							cfg.Jails = append(cfg.Jails, addJail)
+						}
+						break
+					}
+					// loop trough all regex, if match update corresponding struct field
+					for field := range rgx {
+						if field == "name" || field == "end" {
+							continue
+						}
+						match = rgx[field].FindStringSubmatch(scanner.Text())
+						if len(match) > 0 {
+							reflect.ValueOf(&addJail).Elem().FieldByName(field).Set(reflect.ValueOf(strings.TrimSpace(match[1])))
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// newJailCheck check Jail create/clone prereqs (jail_name [IP] [Iface]), hostname defaults to jail_name
+func (cfg *Jmgr) newJailCheck(force *bool, hostname string, args []string) (NewJail, error) {
+
+	if cfg.exist(args[0]) {
+		return NewJail{}, fmt.Errorf("%s alreay exist", args[0])
+	}
+
+	if len(hostname) > 0 && !validHostname(hostname) {
+		return NewJail{}, fmt.Errorf("not a valid hostname: %s", hostname)
+	}
+
+	if cfg.useZFS {
+		// Sanity check: base cfg.ZFSdataSet exist
+		zfsList, err := system.Run("/sbin/zfs", []string{"list", cfg.ZFSdataSet})
+		if err != nil {
+			return NewJail{}, fmt.Errorf(" %s Does not exist. %s", cfg.ZFSdataSet, string(zfsList))
+		}
+
+		// Sanity check: get mount point for base zfs dataset and verify that it matches cfg.JailsHome
+		rgx := regexp.MustCompile(cfg.JailsHome)
+		match := rgx.FindStringSubmatch(string(zfsList))
+		if len(match) == 0 {
+			return NewJail{}, fmt.Errorf("jmgr config 'jail home' does no match where %s is mounted", cfg.ZFSdataSet)
+		}
+	}
+
+	var jail NewJail
+	jail.Name = args[0]
+	jail.Hostname = hostname
+	if len(jail.Hostname) == 0 {
+		jail.Hostname = jail.Name
+	}
+	jail.Iface = cfg.JailIface
+	if iface := cfg.JailSettings[args[0]].Iface; len(iface) > 0 {
+		jail.Iface = iface
+	}
+
+	// resolve jail hostname to IP
+	addrs, err := net.LookupHost(jail.Hostname)
+	if err == nil {
+		jail.IP = addrs[0]
+
+	} else { // IP Address in arg?
+		if len(args) > 1 {
+			_, _, err := net.ParseCIDR(args[1] + "/24")
+			if err != nil {
+				return NewJail{}, fmt.Errorf("not a valid IP address: %s", args[1])
+			}
+			jail.IP = args[1]
+		}
+	}
+
+	// Do we have an IP now? else ask for inherit
+	if len(jail.IP) == 0 {
+		if *force {
+			jail.InheritIP = true
+		} else {
+			jail.InheritIP = askExitOnNo("No IP address found. Use host IP (yes/No)? ")
+		}
+	} else {
+		// ping IP
+		ping := exec.Command(system.Tool("/sbin/ping"), "-c 2", "-t 2", jail.IP)
+		_, err = ping.Output()
+		if err == nil {
+			return NewJail{}, fmt.Errorf("ip address already in use, %s responds to ping, can't continue", jail.IP)
+		}
+
+		// Iface in arg
+		if len(args) > 2 {
+			jail.Iface = args[2]
+		}
+
+		ifcnf := exec.Command(system.Tool("/sbin/ifconfig"), "-l")
+		out, err := ifcnf.Output()
+		if err == nil {
+			// quick and dirty, we may find more than we want.. it's on the TODO list
+			if !bytes.Contains(out, []byte(jail.Iface)) {
+				return NewJail{}, fmt.Errorf("can't find interface: %s on this system", jail.Iface)
+			}
+		} else {
+			return NewJail{}, fmt.Errorf("can't check interface: %s", err.Error())
+		}
+	}
+
+	//Check Config dir
+	d, err := os.Stat(cfg.JailsConfD)
+	if err != nil {
+		return NewJail{}, jmgrError(exitNotFound, "directory "+cfg.JailsConfD+" does not exist.", "Please create "+cfg.JailsConfD+" then try again")
+	}
+	if !d.IsDir() {
+		return NewJail{}, fmt.Errorf("%s is not a directory, can't create new jail", cfg.JailsConfD)
+	}
+
+	// if exist /etc/jail.conf.d/<jail.conf>
+	jail.ConfigPath = cfg.JailsConfD + "/" + jail.Name + ".conf"
+
+	if _, err := os.Stat(jail.ConfigPath); os.IsExist(err) {
+		return NewJail{}, jmgrError(exitConflict, "file: "+jail.ConfigPath+" already exist", "")
+	}
+
+	if cfg.useZFS {
+		// Check jails dataset
+		jail.Dataset = cfg.ZFSdataSet + "/" + jail.Name
+
+		cmd := exec.Command(system.Tool("/sbin/zfs"), "list", jail.Dataset)
+		_, err = cmd.Output()
+		if err == nil {
+			return NewJail{}, jmgrError(exitConflict, "already exist ZFS dataset: "+jail.Dataset, "")
+		}
+	} else {
+		// check if jail Path already exist
+		jail.Path = cfg.JailsHome + "/" + jail.Name
+		_, err := os.Stat(jail.Path)
+		if err == nil {
+			return NewJail{}, jmgrError(exitConflict, jail.Path+" already exist", "")
+		}
+	}
+
+	return jail, nil
+}
+
+// imageStore return the local image store directory
+func (cfg *Jmgr) imageStore() string {
+
+	return cfg.OsMediaDir + "/images"
+}
+
+// newJailDir create the new jail directory, a dataset if the new jail has a dataset in a pool given by 'clone -pool'
+func (cfg *Jmgr) newJailDir(newJail *NewJail) error {
+
+	if len(newJail.Dataset) > 0 && !strings.HasPrefix(newJail.Dataset, cfg.ZFSdataSet+"/") {
+		_, err := system.Run("/sbin/zfs", []string{"create", newJail.Dataset})
+		if err != nil {
+			return fmt.Errorf("newJailDir() %w", err)
+		}
+		newJail.Path, err = zfs.Mountpoint(newJail.Dataset)
+		return err
+	}
+
+	if len(newJail.Path) == 0 {
+		newJail.Path = cfg.JailsHome + "/" + newJail.Name
+	}
+	err := os.MkdirAll(newJail.Path, 0755)
+	if err != nil {
+		return fmt.Errorf("error creating directory %s", err.Error())
+	}
+	return nil
+}
+
+// nextFreeIP return the first address in JailIPPool not used by a jail and not responding to ping
+func (cfg *Jmgr) nextFreeIP() (string, error) {
+
+	first, last, err := config.IPPoolRange(cfg.JailIPPool)
+	if err != nil {
+		return "", err
+	}
+
+	used := make(map[string]bool)
+	for _, jail := range cfg.Jails {
+		used[jail.Ipv4] = true
+		for _, ip := range jail.Ipv4_addrs {
+			used[ip] = true
+		}
+	}
+
+	for a := first; a.IsValid() && !last.Less(a); a = a.Next() {
+		if used[a.String()] {
+			continue
+		}
+		ping := exec.Command(system.Tool("/sbin/ping"), "-c 1", "-t 1", a.String())
+		if _, err := ping.Output(); err == nil {
+			continue
+		}
+		return a.String(), nil
+	}
+	return "", fmt.Errorf("no free address in JailIPPool: %s", cfg.JailIPPool)
+}
+
+//
+// helper methods for struct NewJail
+//
+
+// destination set the new jail dataset to 'pool'/<name> or path to 'dest'/<name>, none given keeps the default from newJailCheck()
+func (j *NewJail) destination(pool string, dest string) error {
+
+	switch {
+
+	case len(pool) > 0 && len(dest) > 0:
+		return errors.New("use either -pool or -dest, not both")
+
+	case len(pool) > 0:
+		if _, err := system.Run("/sbin/zfs", []string{"list", pool}); err != nil {
+			return jmgrError(exitNotFound, "ZFS dataset "+pool+" does not exist", "")
+		}
+		j.Dataset = strings.TrimSuffix(pool, "/") + "/" + j.Name
+		if _, err := system.Run("/sbin/zfs", []string{"list", j.Dataset}); err == nil {
+			return jmgrError(exitConflict, "already exist ZFS dataset: "+j.Dataset, "")
+		}
+		j.Path = ""
+
+	case len(dest) > 0:
+		d, err := os.Stat(dest)
+		if err != nil || !d.IsDir() {
+			return fmt.Errorf("%s is not a directory", dest)
+		}
+		j.Dataset = ""
+		j.Path = filepath.Join(dest, j.Name)
+		if _, err := os.Stat(j.Path); err == nil {
+			return jmgrError(exitConflict, j.Path+" already exist", "")
+		}
+	}
+	return nil
+}
+
+//
+// helper methods for struct Jail
+//
+
+//
+// helper functions
+//
+
+// replicate send the jail dataset (incremental if possible), jail config and metadata to the standby host in jail.Meta.Standby
+func (cfg *Jmgr) replicate(jail *Jail, rcfg Jmgr) error {
+
+	remote := jail.Meta.Standby
+
+	if len(jail.Dataset) == 0 {
+		return fmt.Errorf("replicate() %s is not on ZFS", jail.Name)
+	}
+	if len(rcfg.ZFSdataSet) == 0 || !filepath.IsAbs(rcfg.JailsConfD) || !filepath.IsAbs(rcfg.JailMetaDir) {
+		return fmt.Errorf("replicate() jmgr config on %s is not ok or does not use ZFS", remote)
+	}
+	if jail.ConfigPath == "/etc/jail.conf" {
+		return fmt.Errorf("replicate() %s is configured in /etc/jail.conf, can't replicate", jail.Name)
+	}
+
+	remoteDataset := rcfg.ZFSdataSet + "/" + jail.Name
+	remotePath := rcfg.JailsHome + "/" + jail.Name
+
+	// receive -F overwrites the remote dataset, never an activated replica
+	if err := standbyFenced(jail.Name, remote, rcfg, remoteDataset); err != nil {
+		return err
+	}
+
+	snap, err := zfs.SnapshotPrefix(jail.Dataset, "jmgr-standby-")
+	if err != nil {
+		return err
+	}
+
+	// incremental from the last replicated snapshot if it still exist
+	send := []string{"send", snap}
+	prev := jail.Meta.StandbySnapshot
+	if len(prev) > 0 {
+		if _, err := system.Run("/sbin/zfs", []string{"list", prev}); err == nil {
+			send = []string{"send", "-i", prev, snap}
+		} else {
+			prev = ""
+		}
+	}
+
+	fmt.Println("Replicate " + snap + " to " + remote + ":" + remoteDataset)
+	err = zfsSendSsh(send, remote, []string{"receive", "-u", "-F", remoteDataset})
+	if err != nil {
+		system.Run("/sbin/zfs", []string{"destroy", snap})
+		return err
+	}
+	fmt.Println("/ Replicated " + jail.Name + " to " + remote)
+
+	// jail config, path adjusted to the standby JailsHome
+	conf, err := os.ReadFile(jail.ConfigPath)
+	if err != nil {
+		return fmt.Errorf("replicate() %w", err)
+	}
+	conf = bytes.ReplaceAll(conf, []byte(jail.Path), []byte(remotePath))
+	err = sshWriteFile(remote, rcfg.JailsConfD+"/"+filepath.Base(jail.ConfigPath), conf)
+	if err != nil {
+		return err
+	}
+
+	// old snapshots are not needed for the next incremental send
+	if len(prev) > 0 {
+		system.Run("/sbin/zfs", []string{"destroy", prev})
+		system.Run("/usr/bin/ssh", []string{"-o", "BatchMode=yes", remote, "/sbin/zfs", "destroy", remoteDataset + "@" + strings.SplitN(prev, "@", 2)[1]})
+	}
+
+	jail.Meta.StandbySnapshot = snap
+	jail.Meta.StandbySynced = time.Now().Format(time.RFC3339)
+
+	// metadata, on the standby host the jail is a replica
+	primary, _ := os.Hostname()
+	rmeta := jail.Meta
+	rmeta.Standby = ""
+	rmeta.StandbySnapshot = ""
+	rmeta.StandbyOf = primary
+	b, err := yaml.Marshal(rmeta)
+	if err != nil {
+		return fmt.Errorf("replicate() %w", err)
+	}
+	err = sshWriteFile(remote, rcfg.JailMetaDir+"/"+jail.Name+".yml", b)
+	if err != nil {
+		return err
+	}
+
+	return cfg.writeMeta(jail.Name, jail.Meta)
+}
+
+// readManifest read and check a jail manifest
+func readManifest(file string) (Manifest, error) {
+
+	var m Manifest
+
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return m, fmt.Errorf("readManifest() %w", err)
+	}
+	if err := yaml.UnmarshalStrict(b, &m); err != nil {
+		return m, fmt.Errorf("problem decoding %s: %w", file, err)
+	}
+
+	seen := make(map[string]bool)
+	for _, j := range m.Jails {
+		switch {
+		case len(j.Name) == 0:
+			return m, fmt.Errorf("%s: jail without Name", file)
+		case seen[j.Name]:
+			return m, fmt.Errorf("%s: jail %s defined twice", file, j.Name)
+		case j.State != "" && j.State != "running" && j.State != "stopped":
+			return m, fmt.Errorf("%s: jail %s State must be running or stopped", file, j.Name)
+		case len(j.Release) > 0 && len(j.Image) > 0:
+			return m, fmt.Errorf("%s: jail %s use either Release or Image", file, j.Name)
+		}
+		seen[j.Name] = true
+	}
+	return m, nil
+}
+
+// converge bring the jails on this host to the state in the manifest, jmgr subcommands run as child processes
+func converge(m Manifest, dryRun bool) ApplyResult {
+
+	var result ApplyResult
+	result.Host, _ = os.Hostname()
+
+	run := func(change string, args ...string) bool {
+		result.Changes = append(result.Changes, change)
+		if dryRun {
+			return true
+		}
+		out, err := jmgrCmd(args...)
+		if err != nil {
+			result.Errors = append(result.Errors, change+": "+strings.TrimSpace(out))
+			return false
+		}
+		return true
+	}
+
+	cfg := jmgrInit()
+	for _, spec := range m.Jails {
+
+		// the jail settings in jmgr.conf, create uses the rest
+		settings := cfg.JailSettings[spec.Name]
+		if spec.Tags != nil {
+			for _, t := range settings.Tags {
+				if !slices.Contains(spec.Tags, t) {
+					spec.Tags = append(spec.Tags, t)
+				}
+			}
+		}
+		if spec.Boot == nil {
+			spec.Boot = settings.Boot
+		}
+
+		if !cfg.exist(spec.Name) {
+			cargs := []string{"create", "-f"}
+			if len(spec.Release) > 0 {
+				cargs = append(cargs, "-v", spec.Release)
+			}
+			if len(spec.Image) > 0 {
+				cargs = append(cargs, "-image", spec.Image)
+			}
+			if len(spec.Hostname) > 0 {
+				cargs = append(cargs, "-hostname", spec.Hostname)
+			}
+			if len(spec.Template) > 0 {
+				cargs = append(cargs, "-t", spec.Template)
+			}
+			cargs = append(cargs, spec.Name)
+			if len(spec.IP) > 0 {
+				cargs = append(cargs, spec.IP)
+				if len(spec.Iface) > 0 {
+					cargs = append(cargs, spec.Iface)
+				}
+			}
+			if !run("create "+spec.Name, cargs...) || dryRun {
+				continue
+			}
+			cfg = jmgrInit()
+		}
+		jail := cfg.jail(spec.Name)
+
+		if spec.Tags != nil {
+			var add, remove []string
+			for _, t := range spec.Tags {
+				if !slices.Contains(jail.Meta.Tags, t) {
+					add = append(add, t)
+				}
+			}
+			for _, t := range jail.Meta.Tags {
+				if !slices.Contains(spec.Tags, t) {
+					remove = append(remove, t)
+				}
+			}
+			if len(add) > 0 {
+				run("tag "+spec.Name+" "+strings.Join(add, " "), append([]string{"tag", spec.Name}, add...)...)
+			}
+			if len(remove) > 0 {
+				run("untag "+spec.Name+" "+strings.Join(remove, " "), append([]string{"tag", "-d", spec.Name}, remove...)...)
+			}
+		}
+
+		repos := slices.Clone(spec.PkgRepos)
+		slices.Sort(repos)
+		if spec.PkgRepos != nil && !slices.Equal(repos, jail.Meta.PkgRepos) {
+			if len(jail.Meta.PkgRepos) > 0 {
+				run("remove repos "+spec.Name, append([]string{"repo", "-d", spec.Name}, jail.Meta.PkgRepos...)...)
+			}
+			if len(spec.PkgRepos) > 0 {
+				run("repo "+spec.Name+" "+strings.Join(spec.PkgRepos, " "), append([]string{"repo", spec.Name}, spec.PkgRepos...)...)
+			}
+		}
+
+		if spec.Boot != nil {
+			if *spec.Boot && jail.OnBoot != "Yes" {
+				run("enable "+spec.Name, "enable", spec.Name)
+			} else if !*spec.Boot && jail.OnBoot == "Yes" {
+				run("disable "+spec.Name, "disable", spec.Name)
+			}
+		}
+
+		switch {
+		case spec.State == "running" && !jail.Runs():
+			run("start "+spec.Name, "start", spec.Name)
+		case spec.State == "stopped" && jail.Runs():
+			run("stop "+spec.Name, "stop", spec.Name)
+		}
+	}
+	return result
+}
+
+// remoteApply copy the manifest to a host and run 'jmgr apply' there
+func remoteApply(host string, manifest []byte, args []string) ApplyResult {
+
+	result := ApplyResult{Host: host}
+
+	// a new file only root can read, not a predictable /tmp path
+	b, err := system.Run("/usr/bin/ssh", []string{"-o", "BatchMode=yes", host, "/usr/bin/mktemp", "-t", "jmgr-manifest"})
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		return result
+	}
+	file := strings.TrimSpace(string(b))
+	defer system.Run("/usr/bin/ssh", []string{"-o", "BatchMode=yes", host, "/bin/rm", "-f", file})
+	err = sshWriteFile(host, file, manifest)
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		return result
+	}
+
+	// apply exits 1 on errors, the JSON result is still on stdout
+	cmd := exec.Command(system.Tool("/usr/bin/ssh"), append([]string{"-o", "BatchMode=yes", host}, append(args, "-manifest", file)...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, _ := cmd.Output()
+
+	var r ApplyResult
+	if err := json.Unmarshal(out, &r); err != nil {
+		result.Errors = append(result.Errors, "no result from jmgr apply: "+strings.TrimSpace(stderr.String()))
+		return result
+	}
+	r.Host = host
+	return r
+}
+
+// jmgrCmd run this jmgr binary with args, return the combined output
+func jmgrCmd(args ...string) (string, error) {
+
+	self, err := os.Executable()
+	if err != nil {
+		return "", fmt.Errorf("jmgrCmd() %w", err)
+	}
+	out, err := exec.Command(self, args...).CombinedOutput()
+	return string(out), err
+}
+
+// Return a populated a Jmgr struct
+func jmgrInit() Jmgr {
+
+	// the warm inventory of 'jmgr daemon'
+	if len(daemonSocket) > 0 {
+		return daemonInventory(daemonSocket)
+	}
+
+	cfg := jmgrSettings()
+	system.Tools = cfg.Tools
+	webhooks = cfg.Webhooks
+	syslogFormat = cfg.Syslog
+
+	if len(cfg.ZFSdataSet) > 0 {
+		cfg.useZFS = true
+		cmd := exec.Command(system.Tool("/sbin/zfs"), "list", "-H", cfg.ZFSdataSet)
+		b, err := cmd.Output()
+		if err != nil {
+			cfg.problem("ZFSdataSet", cfg.ZFSdataSet, "Dataset does not exist.")
+		} else {
+			words := strings.Fields(string(b[:]))
+			if len(words) > 4 {
+				cfg.JailsHome = words[4]
+			} else {
+				cfg.problem("JailsHome", cfg.JailsHome, "Can't find Jails Home directory using 'ZFS dataset': "+cfg.ZFSdataSet)
+			}
+		}
+	} else {
+		if _, err := os.Stat(cfg.JailsHome); os.IsNotExist(err) {
+			cfg.problem("JailsHome", cfg.JailsHome, "Directory does not exist.")
+		}
+	}
+
+	cfg.DerivedDefaults()
+
+	// populate struct with existing jails
+	cfg.addJails()
+
+	return cfg
+}
+
+// jmgrSettings return the settings, the defaults, the config file, the profile and then the environment overrides,
+// without the jails
+func jmgrSettings() Jmgr {
+
+	var cfg Jmgr
+	cfg.JmgrConfig = config.File()
+	cfg.Defaults()
+	cfg.jmgrConfigfileReader()
+	if len(profile) > 0 {
+		if err := cfg.ApplyProfile(profile); err != nil {
+			fatal(err)
+		}
+		cfg.Profile = profile
+	}
+	cfg.Env()
+	return cfg
+}
+
+// jmgrConfigfileReader method to read the config file and its conf.d drop-in files, see config.Settings.Read
+func (cfg *Jmgr) jmgrConfigfileReader() {
+
+	warnings, err := cfg.Read()
+	for _, w := range warnings {
+		fmt.Fprintln(os.Stderr, "Warning: "+w)
+	}
+	if err != nil {
+		cfg.problem("JmgrConfig", cfg.JmgrConfig, err.Error())
+	}
+}
+
+// problem record why the config is not ok
+func (cfg *Jmgr) problem(key string, value string, problem string) {
+
+	cfg.Problems = append(cfg.Problems, ConfigProblem{Key: key, Value: value, Problem: problem})
+	cfg.badConfig = true
+}
+
+var profile string // global -profile or env JMGR_PROFILE
+
+// showJail
+func showJail(cfg *Jmgr, args []string) {
+
+	if cfg.exist(args[1]) {
+		var jail = cfg.jail(args[1])
+		var rowsFmt string = "%s\t%s\n"
+
+		usage := jailUsage(jail)
+		if jail.Meta.Health != nil {
+			health := jailHealth(jail)
+			jail.Health = &health
+		}
+		if jsonOutput {
+			printJSON(struct {
+				Jail
+				Usage JailUsage `json:"usage"`
+			}{jail, usage})
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+
+		jidText := strconv.Itoa(jail.Jid)
+		if jail.Jid > 0 {
+			jidText = jidText + " (Running)"
+		} else {
+			jidText = jidText + " (Not running)"
+		}
+
+		fmt.Fprintf(w, rowsFmt, "Jid", jidText)
+		fmt.Fprintf(w, rowsFmt, "Name", jail.Name)
+		fmt.Fprintf(w, rowsFmt, "Hostname", jail.Hostname)
+		
+		if len(jail.Ipv4_addrs) > 0 {
+			for _, ipv4 := range jail.Ipv4_addrs {
+				if len(ipv4) > 0 {
+					fmt.Fprintf(w, rowsFmt, "IPv4", ipv4)
+				}
+			}
+		} else {
+			fmt.Fprintf(w, rowsFmt, "IP Address", jail.Ipv4)
+		}
+
+		if len(jail.Iface) > 0 {
+			fmt.Fprintf(w, rowsFmt, "Interface", jail.Iface)
+		}
+
+		if started, ok := jailStarted(jail); ok {
+			fmt.Fprintf(w, rowsFmt, "Started", started.Format("2006-01-02 15:04:05"))
+			fmt.Fprintf(w, rowsFmt, "Uptime", jailUptime(jail))
+		}
+		if jail.Runs() {
+			if procs, err := lifecycle.Procs(jail.Jid); err == nil {
+				var cpu float64
+				var rss int64
+				for _, p := range procs {
+					cpu, rss = cpu+p.Cpu, rss+p.Rss
+				}
+				fmt.Fprintf(w, rowsFmt, "Processes", fmt.Sprintf("%d%s, CPU %.1f%%, memory %s, see: jmgr ps %s", len(procs),
+					ofLimit(int64(len(procs)), usage.ProcsMax, fmtCount), cpu, fmtBytes(rss), jail.Name))
+			}
+			if usage.Rctl {
+				fmt.Fprintf(w, rowsFmt, "Memory (rctl)", fmtBytes(usage.Memory)+ofLimit(usage.Memory, usage.MemoryMax, fmtBytes))
+				fmt.Fprintf(w, rowsFmt, "CPU% (rctl)", fmtCount(usage.Cpu)+ofLimit(usage.Cpu, usage.CpuMax, fmtCount))
+			}
+		}
+
+		for _, ipv6 := range jail.Ipv6_addrs {
+			if len(ipv6) > 0 {
+				fmt.Fprintf(w, rowsFmt, "IPv6", ipv6)
+			}
+		}
+		if len(jail.Parent) > 0 {
+			fmt.Fprintf(w, rowsFmt, "Parent jail", jail.Parent)
+		}
+		if jail.IsParent {
+			fmt.Fprintf(w, rowsFmt, "Jail Parent", "True")
+		}
+		fmt.Fprintf(w, rowsFmt, "Config", jail.ConfigPath)
+		fmt.Fprintf(w, rowsFmt, "OS Version", jail.OsVersion)
+		if eol, err := cfg.eolTable(false); err == nil {
+			if status, date := eolStatus(jail.OsVersion, eol); len(status) > 0 {
+				fmt.Fprintf(w, rowsFmt, "End of life", date+" ("+status+")")
+			} else if len(date) > 0 {
+				fmt.Fprintf(w, rowsFmt, "End of life", date)
+			}
+		}
+		if len(jail.Meta.Template) > 0 {
+			fmt.Fprintf(w, rowsFmt, "Template", jail.Meta.Template)
+		}
+		if len(jail.Meta.Description) > 0 {
+			fmt.Fprintf(w, rowsFmt, "Description", jail.Meta.Description)
+		}
+		if len(jail.Meta.Tags) > 0 {
+			fmt.Fprintf(w, rowsFmt, "Tags", strings.Join(jail.Meta.Tags, " "))
+		}
+		if len(jail.Meta.Depends) > 0 {
+			fmt.Fprintf(w, rowsFmt, "Depends on", strings.Join(jail.Meta.Depends, " "))
+		}
+		if limits := jail.Limits(); len(limits) > 0 {
+			fmt.Fprintf(w, rowsFmt, "Limits", strings.Join(limits, " "))
+		}
+		if len(jail.Meta.Cpuset) > 0 {
+			fmt.Fprintf(w, rowsFmt, "Cpuset", jail.Meta.Cpuset+" (cpuset id "+strconv.Itoa(jail.Cpusetid)+")")
+		}
+		if jail.Settings.Priority != 0 {
+			fmt.Fprintf(w, rowsFmt, "Priority", strconv.Itoa(jail.Settings.Priority))
+		}
+		if jail.Meta.Nice != 0 {
+			fmt.Fprintf(w, rowsFmt, "Nice", strconv.Itoa(jail.Meta.Nice))
+		}
+		if policy := jail.RestartPolicy(); len(policy) > 0 {
+			fmt.Fprintf(w, rowsFmt, "Restart", policy)
+		}
+		if delay := jail.StartDelay(); delay > 0 {
+			fmt.Fprintf(w, rowsFmt, "Start delay", delay.String())
+		}
+		if timeout := jail.StopTimeout(); timeout > 0 {
+			fmt.Fprintf(w, rowsFmt, "Stop timeout", timeout.String())
+		}
+		if jail.Health != nil {
+			fmt.Fprintf(w, rowsFmt, "Health", strings.TrimSpace(jail.Health.Status+" "+jail.Health.Detail)+" ("+jail.Meta.Health.String()+")")
+		}
+		if len(jail.Settings.Pkgs) > 0 {
+			fmt.Fprintf(w, rowsFmt, "Pkgs (create)", strings.Join(jail.Settings.Pkgs, " "))
+		}
+		if jail.Meta.PkgCache {
+			fmt.Fprintf(w, rowsFmt, "Pkg cache", cfg.PkgCacheDir)
+		}
+		if len(jail.Meta.PkgRepos) > 0 {
+			fmt.Fprintf(w, rowsFmt, "Pkg repos", strings.Join(jail.Meta.PkgRepos, " "))
+		}
+		if len(jail.Meta.Base) > 0 {
+			fmt.Fprintf(w, rowsFmt, "Base", jail.Meta.Base)
+		}
+		if jail.Meta.Hold {
+			fmt.Fprintf(w, rowsFmt, "Hold", "Yes, not updated")
+		}
+		if jail.Meta.Protected {
+			fmt.Fprintf(w, rowsFmt, "Protected", "Yes, not destroyed or rolled back")
+		}
+		if len(jail.Meta.Window) > 0 {
+			fmt.Fprintf(w, rowsFmt, "Maintenance window", jail.Meta.Window)
+		}
+		fmt.Fprintf(w, rowsFmt, "Start on boot", jail.OnBoot)
+		fmt.Fprintf(w, rowsFmt, "Path", jail.Path)
+
+		if len(jail.Dataset) <= 0 {
+			jail.Dataset = "N/A"
+		}
+
+		fmt.Fprintf(w, rowsFmt, "ZFS Dataset", jail.Dataset)
+		if usage.DatasetUsed > 0 {
+			fmt.Fprintf(w, rowsFmt, "ZFS Used", fmtBytes(usage.DatasetUsed)+ofLimit(usage.DatasetUsed, usage.DatasetQuota, fmtBytes))
+		}
+
+		if origin, err := zfs.Origin(jail.Dataset); err == nil && len(origin) > 0 {
+			fmt.Fprintf(w, rowsFmt, "ZFS Origin", origin+" (thin clone)")
+		}
+
+		for _, snap := range jail.Snapshots {
+			if len(snap) > 0 {
+				fmt.Fprintf(w, rowsFmt, "ZFS Snapshot", snap)
+			}
+		}
+
+		w.Flush()
+	}
+}
+
+// Daemon keep the jail inventory warm and serve it, and the daemonCommands, as JSON-RPC on a root only Unix socket
+type Daemon struct{}
+
+func (Daemon) Name() string { return "daemon" }
+func (Daemon) Synopsis() string {
+	return "Serve the jail inventory and start/stop/create/snapshot as JSON-RPC on a Unix socket."
+}
+func (Daemon) Usage() string {
+	return "daemon [-socket 'path'] [-refresh 'seconds'] [-metrics 'address:port'] [-updates 'interval'] [-alerts 'interval'] [-history 'interval'] [-supervise 'interval'] [-grpc 'path']"
+}
+
+func (Daemon) Run(args []string) {
+
+	fset := newFlagSet(args[0])
+	socket := fset.String("socket", defaultSocket, "Unix socket, only root can connect.")
+	refresh := fset.Int("refresh", 60, "Seconds between inventory refreshes, the daemonCommands refresh it when done.")
+	metrics := fset.String("metrics", "", "Serve Prometheus metrics on http://address:port/metrics, ex: 127.0.0.1:9720")
+	updates := fset.Duration("updates", 6*time.Hour, "Interval of the pending updates check for the metrics, 0 disables it.")
+	alerts := fset.Duration("alerts", time.Minute, "Interval of the Alerts check, 0 disables it.")
+	history := fset.Duration("history", time.Minute, "Interval of the usage samples for 'jmgr stats -history', 0 disables them.")
+	supervise := fset.Duration("supervise", 10*time.Second, "Interval of the check of the jails with a Restart policy, 0 disables it.")
+	grpcSocket := fset.String("grpc", "", "Also serve the gRPC API of api/jmgr.proto on this Unix socket, ex: /var/run/jmgr-grpc.sock")
+	fset.Parse(args[1:])
+
+	if fset.NArg() > 0 || *refresh < 1 {
+		fatal(jmgrError(exitUsage, "Syntax: jmgr "+Daemon{}.Usage(), "See: jmgr help"))
+	}
+	if system.NotRoot() {
+		fatal(errNeedRoot)
+	}
+	daemonSocket = "" // harvest the inventory here
+
+	d := newJmgrRPC(*refresh)
+	d.checkAlerts(*alerts)
+	d.checkHealth()
+	d.sampleHistory(*history)
+	d.supervise(*supervise)
+	server := rpc.NewServer()
+	if err := server.RegisterName("Jmgr", d); err != nil {
+		fatal(err)
+	}
+
+	l, err := listenRootOnly(*socket)
+	if err != nil {
+		fatal(err)
+	}
+
+	var gs *grpc.Server
+	if len(*grpcSocket) > 0 {
+		gl, err := listenRootOnly(*grpcSocket)
+		if err != nil {
+			os.Remove(*socket)
+			fatal(err)
+		}
+		gs = grpc.NewServer()
+		jmgrv1.RegisterJmgrServer(gs, grpcJmgr{d: d})
+		go func() {
+			if err := gs.Serve(gl); err != nil {
+				log.Println("jmgr daemon gRPC:", err)
+			}
+		}()
+		fmt.Println("jmgr daemon gRPC listening on " + *grpcSocket)
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sig
+		l.Close()
+		os.Remove(*socket)
+		if gs != nil {
+			gs.Stop()
+			os.Remove(*grpcSocket)
+		}
+		exit(0)
+	}()
+
+	if len(*metrics) > 0 {
+		d.checkUpdates(*updates)
+		go func() {
+			fatal(http.ListenAndServe(*metrics, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/metrics" {
+					http.NotFound(w, r)
+					return
+				}
+				w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+				d.metrics(w)
+			})))
+		}()
+	}
+
+	fmt.Println("jmgr daemon listening on " + *socket)
+	for {
+		conn, err := l.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			select {} // stopped, the signal handler exits
+		}
+		if err != nil {
+			fatal(err)
+		}
+		go server.ServeCodec(jsonrpc.NewServerCodec(conn))
+	}
+}
+
+const defaultSocket = "/var/run/jmgr.sock"
+
+// listenRootOnly listen on the Unix socket path, created 0600 so that no other user can connect, not even before a chmod
+func listenRootOnly(path string) (net.Listener, error) {
+
+	// a socket left by a daemon that did not exit cleanly
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return nil, jmgrError(exitConflict, "A jmgr daemon is already listening on "+path, "Stop it first, ex: service jmgrd stop")
+	}
+	os.Remove(path)
+	mask := syscall.Umask(0077)
+	l, err := net.Listen("unix", path)
+	syscall.Umask(mask)
+	return l, err
+}
+
+// jmgrService a long running jmgr subcommand with an rc.d script, see 'jmgr service'
+type jmgrService struct {
+	Subcommand string // ex: daemon
+	Desc       string
+	Example    string // <name>_args example
+}
+
+// jmgrServices the rc.d services by rc.d script name
+var jmgrServices = map[string]jmgrService{
+	"jmgrd":      {"daemon", "jmgr daemon, JSON-RPC inventory and Prometheus metrics", "-metrics 127.0.0.1:9720"},
+	"jmgr_serve": {"serve", "jmgr serve, REST API", "-cert /usr/local/etc/jmgr/api.crt -key /usr/local/etc/jmgr/api.key"},
+}
+
+// rc.d script of a jmgrService, run and restarted by daemon(8). The options are in <name>_args, rc.subr puts <name>_flags
+// before command_args, that is the daemon(8) options.
+const rcScript = `#!/bin/sh
+#
+# {{.Desc}}, installed by 'jmgr service install {{.Name}}'
+#
+# PROVIDE: {{.Name}}
+# REQUIRE: LOGIN jail zfs
+# KEYWORD: shutdown
+#
+# {{.Name}}_enable (bool):	Set to YES to start {{.Name}} at boot. Default: NO
+# {{.Name}}_args (str):	Options of jmgr {{.Subcommand}}, ex: {{.Example}}
+
+. /etc/rc.subr
+
+name="{{.Name}}"
+rcvar="{{.Name}}_enable"
+
+load_rc_config $name
+
+: ${ {{- .Name}}_enable:="NO"}
+: ${ {{- .Name}}_args:=""}
+
+pidfile="/var/run/${name}.pid"
+procname="/usr/sbin/daemon"
+command="/usr/sbin/daemon"
+command_args="-r -S -T ${name} -P ${pidfile} {{.Jmgr}} {{.Subcommand}} ${ {{- .Name}}_args}"
+
+run_rc_command "$1"
+`
+
+// Service install, remove and list the rc.d scripts of the jmgr services
+type Service struct{}
+
+func (Service) Name() string { return "service" }
+func (Service) Synopsis() string {
+	return "Install or remove the rc.d scripts of jmgr daemon and jmgr serve."
+}
+func (Service) Usage() string {
+	return `service list
+service install [-f] [-enable] [-args 'options'] jmgrd|jmgr_serve
+service remove jmgrd|jmgr_serve`
+}
+
+func (Service) Run(args []string) {
+
+	fset := newFlagSet(args[0])
+	force := fset.Bool("f", false, "Overwrite an existing rc.d script.")
+	enable := fset.Bool("enable", false, "Enable the service in rc.conf and start it.")
+	options := fset.String("args", "", "Options of the jmgr subcommand, set as <name>_args in rc.conf.")
+	fset.Parse(args[1:])
+
+	action := fset.Arg(0)
+	fset.Parse(fset.Args()[min(1, fset.NArg()):])
+
+	if action == "list" {
+		var rows []map[string]string
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", "Service", "Subcommand", "Installed", "Enabled", "Args")
+		names := make([]string, 0, len(jmgrServices))
+		for name := range jmgrServices {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			installed := "no"
+			if _, err := os.Stat("/usr/local/etc/rc.d/" + name); err == nil {
+				installed = "yes"
+			}
+			enabled, _ := system.Run("/usr/sbin/sysrc", []string{"-n", name + "_enable"})
+			svcArgs, _ := system.Run("/usr/sbin/sysrc", []string{"-n", name + "_args"})
+			row := map[string]string{"service": name, "subcommand": jmgrServices[name].Subcommand, "installed": installed,
+				"enabled": strings.TrimSpace(string(enabled)), "args": strings.TrimSpace(string(svcArgs))}
+			rows = append(rows, row)
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", name, row["subcommand"], installed, row["enabled"], row["args"])
+		}
+		w.Flush()
+		printJSON(rows)
+		return
+	}
+
+	name := fset.Arg(0)
+	svc, ok := jmgrServices[name]
+	if fset.NArg() != 1 || !ok || (action != "install" && action != "remove") {
+		fatal(jmgrError(exitUsage, "Syntax: jmgr "+strings.ReplaceAll(Service{}.Usage(), "\n", "\n        jmgr "), "See: jmgr help"))
+	}
+	if system.NotRoot() {
+		fatal(errNeedRoot)
+	}
+	script := "/usr/local/etc/rc.d/" + name
+
+	if action == "remove" {
+		if _, err := os.Stat(script); err != nil {
+			fatal(jmgrError(exitNotFound, "Service "+name+" is not installed.", ""))
+		}
+		system.Run("/usr/sbin/service", []string{name, "onestop"})
+		for _, key := range []string{name + "_enable", name + "_args"} {
+			system.Run("/usr/sbin/sysrc", []string{"-x", key})
+		}
+		if err := os.Remove(script); err != nil {
+			fatal(err)
+		}
+		fmt.Println("Removed " + script)
+		return
+	}
+
+	if _, err := os.Stat(script); err == nil && !*force {
+		fatal(jmgrError(exitConflict, script+" exist.", "Overwrite it with -f"))
+	}
+	self, err := os.Executable()
+	if err != nil {
+		fatal(err)
+	}
+	var b bytes.Buffer
+	err = template.Must(template.New(name).Parse(rcScript)).Execute(&b, map[string]string{
+		"Name": name, "Desc": svc.Desc, "Subcommand": svc.Subcommand, "Example": svc.Example, "Jmgr": self})
+	if err != nil {
+		fatal(err)
+	}
+	if err := os.WriteFile(script, b.Bytes(), 0555); err != nil {
+		fatal(err)
+	}
+	fmt.Println("Installed " + script)
+
+	if len(*options) > 0 {
+		if _, err := system.Run("/usr/sbin/sysrc", []string{name + "_args=" + *options}); err != nil {
+			fatal(err)
+		}
+	}
+	if *enable {
+		if _, err := system.Run("/usr/sbin/sysrc", []string{name + "_enable=YES"}); err != nil {
+			fatal(err)
+		}
+		if _, err := system.Run("/usr/sbin/service", []string{name, "restart"}); err != nil {
+			fatal(err)
+		}
+		fmt.Println("Enabled and started " + name)
+	}
+}
+
+// Serve the REST API over HTTPS, for dashboards and orchestration
+type Serve struct{}
+
+func (Serve) Name() string { return "serve" }
+func (Serve) Synopsis() string {
+	return "Serve a REST API over HTTPS: jails, jail detail, start/stop, snapshot and apply a manifest."
+}
+func (Serve) Usage() string {
+	return "serve [-listen 'address:port'] -cert 'file' -key 'file' [-tokens 'file'] [-refresh 'seconds'] [-updates 'interval'] [-alerts 'interval']"
+}
+
+func (Serve) Run(args []string) {
+
+	fset := newFlagSet(args[0])
+	listen := fset.String("listen", "127.0.0.1:8720", "Address and port to listen on.")
+	cert := fset.String("cert", "", "TLS certificate (PEM).")
+	key := fset.String("key", "", "TLS private key (PEM).")
+	tokens := fset.String("tokens", "", "File with the API tokens, one per line, only readable by root. Default: api.tokens next to the config file")
+	refresh := fset.Int("refresh", 60, "Seconds between inventory refreshes, start/stop/snapshot/apply refresh it when done.")
+	updates := fset.Duration("updates", 6*time.Hour, "Interval of the pending updates check for /metrics, 0 disables it.")
+	alerts := fset.Duration("alerts", time.Minute, "Interval of the Alerts check, 0 disables it.")
+	fset.Parse(args[1:])
+
+	if fset.NArg() > 0 || len(*cert) == 0 || len(*key) == 0 || *refresh < 1 {
+		fatal(jmgrError(exitUsage, "Syntax: jmgr "+Serve{}.Usage(), "See: jmgr help"))
+	}
+	if system.NotRoot() {
+		fatal(errNeedRoot)
+	}
+	if len(*tokens) == 0 {
+		*tokens = filepath.Join(filepath.Dir(config.File()), "api.tokens")
+	}
+	apiTokens, err := readTokens(*tokens)
+	if err != nil {
+		fatal(err)
+	}
+	daemonSocket = "" // harvest the inventory here
+
+	api := &restAPI{d: newJmgrRPC(*refresh), tokens: apiTokens}
+	api.d.checkUpdates(*updates)
+	api.d.checkAlerts(*alerts)
+	api.d.checkHealth()
+	fmt.Println("jmgr serve listening on https://" + *listen + "/v1/")
+	fatal(http.ListenAndServeTLS(*listen, *cert, *key, api))
+}
+
+// readTokens return the API tokens in file, one per line, # comments. The file must not be readable by group or others
+func readTokens(file string) ([]string, error) {
+
+	s, err := os.Stat(file)
+	if err != nil {
+		return nil, fmt.Errorf("readTokens() %w", err)
+	}
+	if s.Mode().Perm()&0077 != 0 {
+		return nil, fmt.Errorf("readTokens() %s is readable by group or others, chmod 600 %s", file, file)
+	}
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("readTokens() %w", err)
+	}
+	var tokens []string
+	for _, line := range strings.Split(string(b), "\n") {
+		if line = strings.TrimSpace(line); len(line) > 0 && !strings.HasPrefix(line, "#") {
+			tokens = append(tokens, line)
+		}
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("readTokens() no tokens in %s", file)
+	}
+	return tokens, nil
+}
+
+// restAPI the HTTP handler of 'jmgr serve', Authorization: Bearer <token>
+//
+//	GET  /v1/jails                      all jails
+//	GET  /v1/jails/<name>               one jail
+//	POST /v1/jails/<name>/start         also stop, restart and snapshot
+//	POST /v1/apply                      converge to the manifest (YAML or JSON) in the body, creates the missing jails
+//	GET  /metrics                       Prometheus metrics
+type restAPI struct {
+	d      *JmgrRPC
+	tokens []string
+}
+
+func (api *restAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	ok := false
+	for _, t := range api.tokens {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(t)) == 1 {
+			ok = true
+		}
+	}
+	if !ok {
+		restReply(w, http.StatusUnauthorized, map[string]string{"error": "unauthorized"})
+		return
+	}
+
+	if r.Method == http.MethodGet && r.URL.Path == "/metrics" {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		api.d.metrics(w)
+		return
+	}
+
+	path := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1"), "/"), "/")
+	switch {
+	case r.Method == http.MethodGet && len(path) == 1 && path[0] == "jails":
+		var jails []Jail
+		api.d.List(struct{}{}, &jails)
+		restReply(w, http.StatusOK, jails)
+
+	case r.Method == http.MethodGet && len(path) == 2 && path[0] == "jails":
+		var jail Jail
+		if err := api.d.Jail(path[1], &jail); err != nil {
+			restReply(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+			return
+		}
+		restReply(w, http.StatusOK, jail)
+
+	case r.Method == http.MethodPost && len(path) == 3 && path[0] == "jails" && slices.Contains([]string{"start", "stop", "restart", "snapshot"}, path[2]):
+		var jail Jail
+		if err := api.d.Jail(path[1], &jail); err != nil {
+			restReply(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+			return
+		}
+		api.run(w, []string{path[2], path[1]})
+
+	case r.Method == http.MethodPost && len(path) == 1 && path[0] == "apply":
+		f, err := os.CreateTemp("", "jmgr-manifest-*.yml")
+		if err != nil {
+			restReply(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		defer os.Remove(f.Name())
+		_, err = io.Copy(f, http.MaxBytesReader(w, r.Body, 1<<20))
+		f.Close()
+		if err != nil {
+			restReply(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		api.run(w, []string{"apply", "-f", "-manifest", f.Name()})
+
+	default:
+		restReply(w, http.StatusNotFound, map[string]string{"error": "no such endpoint: " + r.Method + " " + r.URL.Path})
+	}
+}
+
+// run the subcommand args with global -json, reply its JSON result, or the error with status 500
+func (api *restAPI) run(w http.ResponseWriter, args []string) {
+
+	var reply RunReply
+	if err := api.d.run(RunArgs{Args: args, Json: true}, &reply); err != nil {
+		restReply(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	var result JSONResult
+	if err := json.Unmarshal([]byte(reply.Stdout), &result); err != nil {
+		result.Error = strings.TrimSpace(reply.Stderr)
+	}
+	if reply.Exit != 0 {
+		restReply(w, http.StatusInternalServerError, result)
+		return
+	}
+	restReply(w, http.StatusOK, result)
+}
+
+// restReply write v as the JSON body with status
+func restReply(w http.ResponseWriter, status int, v any) {
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+var daemonSocket string // global -socket or env JMGR_SOCKET, the CLI is a client of 'jmgr daemon'
+
+// daemonCommands the subcommands a client has the daemon run
+var daemonCommands = []string{"start", "stop", "restart", "create", "snapshot"}
+
+// JmgrRPC the JSON-RPC 1.0 methods of 'jmgr daemon', ex: {"method": "Jmgr.Jail", "params": ["web"], "id": 1}
+type JmgrRPC struct {
+	mu      sync.RWMutex
+	cfg     Jmgr                       // the warm inventory
+	updates map[string]UpdateCheck     // pending updates by jail, see checkUpdates
+	history map[string][]HistorySample // usage samples by jail, see sampleHistory
+	health  map[string]HealthStatus    // last healthcheck by jail, see checkHealth
+	running sync.Mutex                 // one subcommand at a time
+}
+
+// RunArgs a subcommand for the daemon to run, ex: Args: ["start", "web"], and the global options of the client
+type RunArgs struct {
+	Args    []string `json:"args"`
+	Json    bool     `json:"json"`
+	Yes     bool     `json:"yes"`
+	Profile string   `json:"profile,omitempty"` // -profile
+	Env     []string `json:"env,omitempty"`     // the JMGR_* environment of the client, ex: JMGR_ZFSDATASET=zroot/jails, instead of the daemon's
+}
+
+// RunReply the output and exit status of a subcommand run by the daemon
+type RunReply struct {
+	Stdout string `json:"stdout"`
+	Stderr string `json:"stderr"`
+	Exit   int    `json:"exit"`
+}
+
+// newJmgrRPC return the daemon methods with the inventory harvested, and again every refresh seconds
+func newJmgrRPC(refresh int) *JmgrRPC {
+
+	d := &JmgrRPC{}
+	d.refresh()
+	go func() {
+		for range time.Tick(time.Duration(refresh) * time.Second) {
+			d.refresh()
+		}
+	}()
+	// the end-of-life table cache of the listings, fetched once it is a week old
+	go func() {
+		for {
+			d.mu.RLock()
+			cfg := d.cfg
+			d.mu.RUnlock()
+			cfg.eolTable(true)
+			time.Sleep(24 * time.Hour)
+		}
+	}()
+	return d
+}
+
+// checkUpdates check the pending updates of all jails now, and again every interval. No-op if interval is 0
+func (d *JmgrRPC) checkUpdates(interval time.Duration) {
+
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		for {
+			d.mu.RLock()
+			cfg := d.cfg
+			d.mu.RUnlock()
+			updates := make(map[string]UpdateCheck)
+			for i := range cfg.Jails {
+				if len(cfg.Jails[i].Parent) == 0 {
+					updates[cfg.Jails[i].Name] = cfg.checkJail(&cfg.Jails[i])
+				}
+			}
+			d.mu.Lock()
+			d.updates = updates
+			d.mu.Unlock()
+			time.Sleep(interval)
+		}
+	}()
+}
+
+// historyKeep how long sampleHistory keeps the samples
+const historyKeep = 7 * 24 * time.Hour
+
+// sampleHistory sample the usage of the running jails every interval, the samples older than historyKeep are dropped.
+// No-op if interval is 0
+func (d *JmgrRPC) sampleHistory(interval time.Duration) {
+
+	if interval <= 0 {
+		return
+	}
+	go func() {
+		for {
+			d.mu.RLock()
+			jails := append([]Jail{}, d.cfg.Jails...)
+			d.mu.RUnlock()
+			now := time.Now()
+			samples := make(map[string]HistorySample)
+			for _, jail := range jails {
+				if !jail.Runs() {
+					continue
+				}
+				st := jailStats(jail)
+				samples[jail.Name] = HistorySample{Time: now.Truncate(time.Second), Cpu: st.Cpu, Memory: st.Memory,
+					Read: st.Read, Write: st.Write, Dataset: zfs.UsedBytes(jail.Dataset)}
+			}
+
+			d.mu.Lock()
+			if d.history == nil {
+				d.history = make(map[string][]HistorySample)
+			}
+			for name, sample := range samples {
+				d.history[name] = append(d.history[name], sample)
+			}
+			for name, h := range d.history {
+				i := 0
+				for i < len(h) && now.Sub(h[i].Time) > historyKeep {
+					i++
+				}
+				if i == len(h) {
+					delete(d.history, name)
+				} else if i > 0 {
+					d.history[name] = append([]HistorySample{}, h[i:]...)
+				}
+			}
+			d.mu.Unlock()
+			time.Sleep(interval)
+		}
+	}()
+}
+
+// checkHealth run the healthcheck of each jail every Interval, the unhealthy event when a jail turns unhealthy and
+// healthy when it recovers
+func (d *JmgrRPC) checkHealth() {
+
+	go func() {
+		last := make(map[string]time.Time) // by jail
+		for range time.Tick(time.Second) {
+			var due []Jail
+			d.mu.RLock()
+			for _, jail := range d.cfg.Jails {
+				if jail.Meta.Health != nil && time.Since(last[jail.Name]) >= jail.Meta.Health.Every() {
+					due = append(due, jail)
+				}
+			}
+			d.mu.RUnlock()
+			if len(due) == 0 {
+				continue
+			}
+
+			results := make([]HealthStatus, len(due))
+			var wg sync.WaitGroup
+			for i := range due {
+				last[due[i].Name] = time.Now()
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					results[i] = jailHealth(due[i])
+				}(i)
+			}
+			wg.Wait()
+
+			d.mu.Lock()
+			if d.health == nil {
+				d.health = make(map[string]HealthStatus)
+			}
+			var changed []int
+			for i, st := range results {
+				name := due[i].Name
+				if prev := d.health[name].Status; prev != st.Status && st.Status != "stopped" &&
+					(st.Status == "unhealthy" || prev == "unhealthy") {
+					changed = append(changed, i)
+				}
+				d.health[name] = st
+				if d.cfg.exist(name) {
+					d.cfg.Jails[d.cfg.jIndex(name)].Health = &results[i]
+				}
+			}
+			d.mu.Unlock()
+			for _, i := range changed {
+				event(results[i].Status, due[i].Name, strings.TrimSpace(due[i].Meta.Health.String()+" "+results[i].Detail))
+			}
+		}
+	}()
+}
+
+// restartPolicies the Restart of the jail settings and metadata, see supervise
+var restartPolicies = []string{"no", "on-failure", "always"}
+
+// supervise restart the jails with a Restart policy that are not running, checked every interval. on-failure restarts a
+// jail started by jmgr and not stopped by jmgr, always also a jail started otherwise, ex: at boot. A restart waits 10s,
+// doubled after each restart up to 5m, and back to 10s once the jail runs for 5m. No-op if interval is 0
+func (d *JmgrRPC) supervise(interval time.Duration) {
+
+	if interval <= 0 {
+		return
+	}
+	type backoff struct {
+		restarts int
+		next     time.Time // of the pending restart
+		started  time.Time // by the last restart
+	}
+	go func() {
+		state := make(map[string]*backoff) // by jail
+		for range time.Tick(interval) {
+			d.mu.RLock()
+			cfg := d.cfg
+			d.mu.RUnlock()
+			for _, jail := range cfg.Jails {
+				policy := jail.RestartPolicy()
+				if (policy != "on-failure" && policy != "always") || len(jail.Parent) > 0 {
+					continue
+				}
+				b := state[jail.Name]
+				if b == nil {
+					b = &backoff{}
+					state[jail.Name] = b
+				}
+				// the inventory may be a refresh old
+				if _, err := system.Run("/usr/sbin/jls", []string{"-j", jail.Name, "jid"}); err == nil {
+					b.next = time.Time{}
+					if b.restarts > 0 && time.Since(b.started) >= 5*time.Minute {
+						b.restarts = 0
+					}
+					continue
+				}
+				meta, err := cfg.readMeta(jail.Name)
+				if err != nil || len(meta.Stopped) > 0 || (policy == "on-failure" && len(meta.Started) == 0) || len(meta.StandbyOf) > 0 {
+					continue
+				}
+
+				if b.next.IsZero() {
+					delay := min(10*time.Second<<b.restarts, 5*time.Minute)
+					b.next = time.Now().Add(delay)
+					fmt.Println("jmgr daemon: " + jail.Name + " is not running, restart in " + delay.String())
+					continue
+				}
+				if time.Now().Before(b.next) {
+					continue
+				}
+				var reply RunReply
+				d.run(RunArgs{Args: []string{"start", jail.Name}, Yes: true}, &reply)
+				b.restarts++
+				b.next, b.started = time.Time{}, time.Now()
+				detail := "policy " + policy + ", restart " + strconv.Itoa(b.restarts)
+				if reply.Exit != 0 {
+					detail += ", failed: " + strings.TrimSpace(reply.Stderr)
+				}
+				fmt.Println("jmgr daemon: " + jail.Name + " " + detail)
+				event("auto-restart", jail.Name, detail)
+			}
+		}
+	}()
+}
+
+// checkAlerts check the Alerts on all jails every interval, on a breach and when it clears send the event and run the Hook.
+// No-op if interval is 0 or there are no Alerts
+func (d *JmgrRPC) checkAlerts(interval time.Duration) {
+
+	d.mu.RLock()
+	alerts := d.cfg.Alerts
+	d.mu.RUnlock()
+	if interval <= 0 || len(alerts) == 0 {
+		return
+	}
+	go func() {
+		breached := make(map[string]bool) // by alert/jail
+		for {
+			time.Sleep(interval)
+			d.mu.RLock()
+			cfg := d.cfg
+			d.mu.RUnlock()
+			for _, jail := range cfg.Jails {
+				var usage *JailUsage
+				for name, alert := range cfg.Alerts {
+					if len(alert.Jails) > 0 && !slices.Contains(alert.Jails, jail.Name) {
+						continue
+					}
+					if usage == nil && alert.Check != "stopped" {
+						u := jailUsage(jail)
+						usage = &u
+					}
+					breach, detail := alertBreach(alert, jail, usage)
+					key := name + "/" + jail.Name
+					if breach == breached[key] {
+						continue
+					}
+					breached[key] = breach
+					e := "alert"
+					if !breach {
+						e = "alert-cleared"
+					}
+					event(e, jail.Name, name+": "+detail)
+					if len(alert.Hook) > 0 {
+						go runAlertHook(name, alert.Hook, e, jail.Name, detail)
+					}
+				}
+			}
+		}
+	}()
+}
+
+// alertBreach return true if the alert is breached on the jail, and the value checked, ex: memory 93% of 2g.
+// usage is nil for the stopped check
+func alertBreach(alert Alert, jail Jail, usage *JailUsage) (bool, string) {
+
+	if alert.Check == "stopped" {
+		// started by jmgr and not stopped by jmgr
+		if !jail.Runs() && len(jail.Meta.Started) > 0 {
+			return true, "stopped, started by jmgr " + jail.Meta.Started
+		}
+		return false, "running"
+	}
+
+	above := int64(alert.Above)
+	if above == 0 {
+		above = 90
+	}
+	var used, limit int64
+	format := fmtCount
+	switch alert.Check {
+	case "memory":
+		used, limit, format = usage.Memory, usage.MemoryMax, fmtBytes
+	case "cpu":
+		used, limit = usage.Cpu, usage.CpuMax
+	case "procs":
+		used, limit = usage.Procs, usage.ProcsMax
+	case "dataset":
+		used, limit, format = usage.DatasetUsed, usage.DatasetQuota, fmtBytes
+	}
+	if limit <= 0 || (alert.Check != "dataset" && !usage.Rctl) {
+		return false, alert.Check + " no limit"
+	}
+	pct := used * 100 / limit
+	return pct > above, fmt.Sprintf("%s %d%% of %s, above %d%%", alert.Check, pct, format(limit), above)
+}
+
+// runAlertHook run the Hook of the alert name for the event on jail, a Hook that fails is a warning
+func runAlertHook(name string, hook string, e string, jail string, detail string) {
+
+	cmd := exec.Command(hook)
+	cmd.Env = append(os.Environ(), "JMGR_ALERT="+name, "JMGR_EVENT="+e, "JMGR_JAIL="+jail, "JMGR_DETAIL="+detail)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		fmt.Fprintln(os.Stderr, "Warning: alert "+name+" hook "+hook+": "+err.Error()+" "+strings.TrimSpace(string(out)))
+	}
+}
+
+// metrics write the Prometheus text format metrics of the inventory, the rctl usage and the dataset used bytes are read now
+func (d *JmgrRPC) metrics(w io.Writer) {
+
+	d.mu.RLock()
+	jails := append([]Jail{}, d.cfg.Jails...)
+	updates := d.updates
+	d.mu.RUnlock()
+
+	type metric struct {
+		name, kind, help string
+		value            func(jail Jail, usage map[string]int64) (float64, bool)
+	}
+	metrics := []metric{
+		{"jmgr_jail_up", "gauge", "1 if the jail is running.", func(jail Jail, _ map[string]int64) (float64, bool) {
+			if jail.Runs() {
+				return 1, true
+			}
+			return 0, true
+		}},
+		{"jmgr_jail_jid", "gauge", "Jail id, 0 if not running.", func(jail Jail, _ map[string]int64) (float64, bool) {
+			return float64(jail.Jid), true
+		}},
+		{"jmgr_jail_memory_bytes", "gauge", "Resident memory of the jail, rctl memoryuse.", func(_ Jail, usage map[string]int64) (float64, bool) {
+			n, ok := usage["memoryuse"]
+			return float64(n), ok
+		}},
+		{"jmgr_jail_cpu_seconds_total", "counter", "CPU time used by the jail, rctl cputime.", func(_ Jail, usage map[string]int64) (float64, bool) {
+			n, ok := usage["cputime"]
+			return float64(n), ok
+		}},
+		{"jmgr_jail_cpu_percent", "gauge", "CPU usage of the jail, rctl pcpu.", func(_ Jail, usage map[string]int64) (float64, bool) {
+			n, ok := usage["pcpu"]
+			return float64(n), ok
+		}},
+		{"jmgr_jail_dataset_used_bytes", "gauge", "Bytes used by the jail dataset and its snapshots.", func(jail Jail, _ map[string]int64) (float64, bool) {
+			return float64(zfs.UsedBytes(jail.Dataset)), len(jail.Dataset) > 0
+		}},
+		{"jmgr_jail_snapshots", "gauge", "Number of snapshots of the jail dataset.", func(jail Jail, _ map[string]int64) (float64, bool) {
+			return float64(len(jail.Snapshots)), len(jail.Dataset) > 0
+		}},
+		{"jmgr_jail_pending_pkg_upgrades", "gauge", "Number of packages to upgrade.", func(jail Jail, _ map[string]int64) (float64, bool) {
+			u, ok := updates[jail.Name]
+			return float64(u.Pkgs), ok && len(u.Error) == 0
+		}},
+		{"jmgr_jail_pending_base_patch", "gauge", "1 if freebsd-update has a base patch for the jail.", func(jail Jail, _ map[string]int64) (float64, bool) {
+			u, ok := updates[jail.Name]
+			if len(u.Patch) > 0 && u.Patch != "pkgbase" {
+				return 1, ok
+			}
+			return 0, ok && len(u.Error) == 0 && u.Patch != "pkgbase"
+		}},
+	}
+
+	usage := make([]map[string]int64, len(jails))
+	for i, jail := range jails {
+		if jail.Runs() {
+			usage[i] = rctlUsage(jail.Name)
+		}
+	}
+	fmt.Fprintf(w, "# HELP jmgr_jails Number of jails.\n# TYPE jmgr_jails gauge\njmgr_jails %d\n", len(jails))
+	for _, m := range metrics {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind)
+		for i, jail := range jails {
+			if v, ok := m.value(jail, usage[i]); ok {
+				fmt.Fprintf(w, "%s{jail=%s} %g\n", m.name, strconv.Quote(jail.Name), v)
+			}
+		}
+	}
+}
+
+// refresh harvest the inventory again
+func (d *JmgrRPC) refresh() {
+
+	cfg := jmgrInit()
+	d.mu.Lock()
+	for i := range cfg.Jails {
+		if st, ok := d.health[cfg.Jails[i].Name]; ok && cfg.Jails[i].Meta.Health != nil {
+			cfg.Jails[i].Health = &st
+		}
+	}
+	d.cfg = cfg
+	d.mu.Unlock()
+}
+
+// Config the config and all jails, as 'jmgr config -json'
+func (d *JmgrRPC) Config(_ struct{}, reply *Jmgr) error {
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	*reply = d.cfg
+	return nil
+}
+
+// List all jails
+func (d *JmgrRPC) List(_ struct{}, reply *[]Jail) error {
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	*reply = append([]Jail{}, d.cfg.Jails...)
+	return nil
+}
+
+// Jail one jail by name
+func (d *JmgrRPC) Jail(name string, reply *Jail) error {
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if !d.cfg.exist(name) {
+		return fmt.Errorf("Jail %s does not exist.", name)
+	}
+	*reply = d.cfg.Jails[d.cfg.jIndex(name)]
+	return nil
+}
+
+// Refresh harvest the inventory now, ex: after jails were changed without the daemon
+func (d *JmgrRPC) Refresh(_ struct{}, reply *bool) error {
+
+	d.refresh()
+	*reply = true
+	return nil
+}
+
+// Updates the pending updates by jail of the last check, see checkUpdates. Empty if not checked, ex: without -metrics
+func (d *JmgrRPC) Updates(_ struct{}, reply *map[string]UpdateCheck) error {
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	*reply = d.updates
+	return nil
+}
+
+// HistoryArgs the jails, all if none, and how far back, see History
+type HistoryArgs struct {
+	Jails []string      `json:"jails"`
+	Since time.Duration `json:"since"` // nanoseconds, ex: 86400000000000 for 24h
+}
+
+// History the usage samples of the jails in the last args.Since, by jail, ex: Jmgr.History [{"jails": ["web"], "since": 3600000000000}]
+func (d *JmgrRPC) History(args HistoryArgs, reply *map[string][]HistorySample) error {
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	from := time.Now().Add(-args.Since)
+	history := make(map[string][]HistorySample)
+	for name, h := range d.history {
+		if len(args.Jails) > 0 && !slices.Contains(args.Jails, name) {
+			continue
+		}
+		i, _ := slices.BinarySearchFunc(h, from, func(s HistorySample, t time.Time) int { return s.Time.Compare(t) })
+		if i < len(h) {
+			history[name] = append([]HistorySample{}, h[i:]...)
+		}
+	}
+	*reply = history
+	return nil
+}
+
+// Start, Stop, Create and Snapshot run the subcommand with args, ex: Jmgr.Snapshot ["web", "before-upgrade"]
+func (d *JmgrRPC) Start(args []string, reply *RunReply) error {
+	return d.Run(RunArgs{Args: append([]string{"start"}, args...)}, reply)
+}
+
+func (d *JmgrRPC) Stop(args []string, reply *RunReply) error {
+	return d.Run(RunArgs{Args: append([]string{"stop"}, args...)}, reply)
+}
+
+func (d *JmgrRPC) Create(args []string, reply *RunReply) error {
+	return d.Run(RunArgs{Args: append([]string{"create"}, args...)}, reply)
+}
+
+func (d *JmgrRPC) Snapshot(args []string, reply *RunReply) error {
+	return d.Run(RunArgs{Args: append([]string{"snapshot"}, args...)}, reply)
+}
+
+// Run one of the daemonCommands, as jmgr without a terminal: questions fail unless Yes. Then refresh the inventory
+func (d *JmgrRPC) Run(args RunArgs, reply *RunReply) error {
+
+	if len(args.Args) == 0 || !slices.Contains(daemonCommands, args.Args[0]) {
+		return fmt.Errorf("Run() not one of: %s", strings.Join(daemonCommands, ", "))
+	}
+	return d.run(args, reply)
+}
+
+// run the subcommand args.Args as jmgr without a terminal and refresh the inventory
+func (d *JmgrRPC) run(args RunArgs, reply *RunReply) error {
+
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("run() %w", err)
+	}
+	var global []string
+	if args.Json {
+		global = append(global, "-json")
+	}
+	if args.Yes {
+		global = append(global, "-y")
+	}
+	if len(args.Profile) > 0 {
+		global = append(global, "-profile", args.Profile)
+	}
+
+	d.running.Lock()
+	defer d.running.Unlock()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(self, append(global, args.Args...)...)
+	for _, env := range os.Environ() {
+		if !strings.HasPrefix(env, "JMGR_SOCKET=") && (args.Env == nil || !strings.HasPrefix(env, "JMGR_")) {
+			cmd.Env = append(cmd.Env, env)
+		}
+	}
+	for _, env := range args.Env {
+		if strings.HasPrefix(env, "JMGR_") && !strings.HasPrefix(env, "JMGR_SOCKET=") {
+			cmd.Env = append(cmd.Env, env)
+		}
+	}
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return fmt.Errorf("run() %w", err)
+	}
+	reply.Stdout = stdout.String()
+	reply.Stderr = stderr.String()
+	reply.Exit = cmd.ProcessState.ExitCode()
+	d.refresh()
+	return nil
+}
+
+// grpcJmgr the gRPC service of api/jmgr.proto, the same methods as JmgrRPC
+type grpcJmgr struct {
+	jmgrv1.UnimplementedJmgrServer
+	d *JmgrRPC
+}
+
+// grpcJail the jail as a jmgr.v1.Jail message
+func grpcJail(jail *Jail) *jmgrv1.Jail {
+
+	state := "stopped"
+	if jail.Runs() {
+		state = "running"
+	}
+	return &jmgrv1.Jail{
+		Jid:        int32(jail.Jid),
+		Name:       jail.Name,
+		Hostname:   jail.Hostname,
+		State:      state,
+		Path:       jail.Path,
+		Dataset:    jail.Dataset,
+		ConfigPath: jail.ConfigPath,
+		OsVersion:  jail.OsVersion,
+		OnBoot:     jail.OnBoot,
+		Iface:      jail.Iface,
+		Ipv4:       jail.Ipv4,
+		Parent:     jail.Parent,
+		Ipv4Addrs:  jail.Ipv4_addrs,
+		Ipv6Addrs:  jail.Ipv6_addrs,
+		Snapshots:  jail.Snapshots,
+		Tags:       jail.Meta.Tags,
+	}
+}
+
+func (g grpcJmgr) List(context.Context, *jmgrv1.ListRequest) (*jmgrv1.ListReply, error) {
+
+	var jails []Jail
+	g.d.List(struct{}{}, &jails)
+	reply := &jmgrv1.ListReply{}
+	for i := range jails {
+		reply.Jails = append(reply.Jails, grpcJail(&jails[i]))
+	}
+	return reply, nil
+}
+
+func (g grpcJmgr) GetJail(_ context.Context, req *jmgrv1.JailRequest) (*jmgrv1.Jail, error) {
+
+	var jail Jail
+	if err := g.d.Jail(req.GetName(), &jail); err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	return grpcJail(&jail), nil
+}
+
+func (g grpcJmgr) Refresh(context.Context, *jmgrv1.RefreshRequest) (*jmgrv1.RefreshReply, error) {
+
+	g.d.refresh()
+	return &jmgrv1.RefreshReply{}, nil
+}
+
+// run the subcommand with the request args, a failed subcommand is a reply with its Exit, not an error
+func (g grpcJmgr) run(subcommand string, req *jmgrv1.RunRequest) (*jmgrv1.RunReply, error) {
+
+	var reply RunReply
+	args := RunArgs{Args: append([]string{subcommand}, req.GetArgs()...), Json: req.GetJson(), Yes: req.GetYes()}
+	if err := g.d.Run(args, &reply); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &jmgrv1.RunReply{Stdout: reply.Stdout, Stderr: reply.Stderr, Exit: int32(reply.Exit)}, nil
+}
+
+func (g grpcJmgr) Start(_ context.Context, req *jmgrv1.RunRequest) (*jmgrv1.RunReply, error) {
+	return g.run("start", req)
+}
+
+func (g grpcJmgr) Stop(_ context.Context, req *jmgrv1.RunRequest) (*jmgrv1.RunReply, error) {
+	return g.run("stop", req)
+}
+
+func (g grpcJmgr) Restart(_ context.Context, req *jmgrv1.RunRequest) (*jmgrv1.RunReply, error) {
+	return g.run("restart", req)
+}
+
+func (g grpcJmgr) Create(_ context.Context, req *jmgrv1.RunRequest) (*jmgrv1.RunReply, error) {
+	return g.run("create", req)
+}
+
+func (g grpcJmgr) Snapshot(_ context.Context, req *jmgrv1.RunRequest) (*jmgrv1.RunReply, error) {
+	return g.run("snapshot", req)
+}
+
+// daemonInventory return the warm inventory of the daemon on socket, instead of harvesting it
+func daemonInventory(socket string) Jmgr {
+
+	client, err := jsonrpc.Dial("unix", socket)
+	if err != nil {
+		fatal(fmt.Errorf("jmgr daemon: %w", err))
+	}
+	defer client.Close()
+
+	var cfg Jmgr
+	if err := client.Call("Jmgr.Config", struct{}{}, &cfg); err != nil {
+		fatal(fmt.Errorf("jmgr daemon: %w", err))
+	}
+
+	// not in the JSON
+	cfg.useZFS = len(cfg.ZFSdataSet) > 0
+	cfg.badConfig = len(cfg.Problems) > 0
+	system.Tools = cfg.Tools
+	webhooks = cfg.Webhooks
+	syslogFormat = cfg.Syslog
+	for _, jail := range cfg.Jails {
+		if len(jail.Parent) > 0 && cfg.exist(jail.Parent) {
+			cfg.Jails[cfg.jIndex(jail.Parent)].IsParent = true
+		}
+	}
+	return cfg
+}
+
+// daemonRun have the daemon on socket run the subcommand args, print its output and exit with its status
+func daemonRun(socket string, args []string) {
+
+	client, err := jsonrpc.Dial("unix", socket)
+	if err != nil {
+		fatal(fmt.Errorf("jmgr daemon: %w", err))
+	}
+	defer client.Close()
+
+	// the daemon runs it with the profile and the JMGR_* environment of this jmgr
+	env := []string{}
+	for _, e := range os.Environ() {
+		if strings.HasPrefix(e, "JMGR_") {
+			env = append(env, e)
+		}
+	}
+	var reply RunReply
+	if err := client.Call("Jmgr.Run", RunArgs{Args: args, Json: jsonOutput, Yes: assumeYes, Profile: profile, Env: env}, &reply); err != nil {
+		fatal(fmt.Errorf("jmgr daemon: %w", err))
+	}
+	if jsonOutput {
+		jsonStdout.WriteString(reply.Stdout)
+		jsonPrinted = true
+	} else {
+		os.Stdout.WriteString(reply.Stdout)
+	}
+	os.Stderr.WriteString(reply.Stderr)
+	exit(reply.Exit)
+}
+
+// alertChecks the checks of an Alert
+var alertChecks = []string{"memory", "cpu", "procs", "dataset", "stopped"}
+
+// Event a lifecycle event of a jail
+type Event struct {
+	Event  string `json:"event"`
+	Jail   string `json:"jail"`
+	Host   string `json:"host"`
+	User   string `json:"user"`
+	Time   string `json:"time"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// events the lifecycle events
+var events = []string{"create", "start", "stop", "restart", "destroy", "update-complete", "update-failed",
+	"snapshot", "rollback", "enable", "disable", "alert", "alert-cleared", "unhealthy", "healthy", "auto-restart", "gc"}
+
+var webhooks map[string]Webhook // the Webhooks from jmgr.conf, set by jmgrInit()
+var syslogFormat string         // the Syslog from jmgr.conf, set by jmgrInit()
+
+// event log the lifecycle event name of jail to syslog and send it to the webhooks that want it. A webhook that fails is a warning
+func event(name string, jail string, detail string) {
+
+	host, _ := os.Hostname()
+	e := Event{Event: name, Jail: jail, Host: host, Time: time.Now().Format(time.RFC3339), Detail: detail}
+	if u, err := user.Current(); err == nil {
+		e.User = u.Username
+	}
+	syslogEvent(e)
+	body, _ := json.Marshal(e)
+
+	// sent in the background, a slow or dead webhook does not hold up the jails, see exit
+	for hookName, hook := range webhooks {
+		if len(hook.Events) > 0 && !slices.Contains(hook.Events, name) {
+			continue
+		}
+		webhookSends.Add(1)
+		go func(hookName string, hook Webhook) {
+			defer webhookSends.Done()
+			if err := postWebhook(hook, body); err != nil {
+				fmt.Fprintln(os.Stderr, "Warning: webhook "+hookName+": "+err.Error())
+			}
+		}(hookName, hook)
+	}
+}
+
+var webhookSends sync.WaitGroup // the webhook POSTs in progress, waited for by exit
+
+// webhookDeadline the time a webhook POST has, its retries included, and the time jmgr waits for them at exit
+const webhookDeadline = 10 * time.Second
+
+// exit wait for the webhook POSTs in progress, at most webhookDeadline, and exit with code
+func exit(code int) {
+
+	done := make(chan struct{})
+	go func() {
+		webhookSends.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(webhookDeadline):
+		fmt.Fprintln(os.Stderr, "Warning: webhooks not sent in "+webhookDeadline.String()+", given up")
+	}
+	os.Exit(code)
+}
+
+// syslogEvent log the event to syslog, facility daemon, tag jmgr, as key=value pairs or JSON. The -failed, alert and unhealthy events with priority err
+func syslogEvent(e Event) {
+
+	if syslogFormat == "none" {
+		return
+	}
+	w, err := syslog.New(syslog.LOG_DAEMON|syslog.LOG_NOTICE, "jmgr")
+	if err != nil {
+		return // no syslogd
+	}
+	defer w.Close()
+
+	var msg string
+	if syslogFormat == "json" {
+		b, _ := json.Marshal(e)
+		msg = string(b)
+	} else {
+		var kv []string
+		for _, f := range [][2]string{{"event", e.Event}, {"jail", e.Jail}, {"user", e.User}, {"detail", e.Detail}} {
+			if len(f[1]) == 0 {
+				continue
+			}
+			if strings.ContainsAny(f[1], " \t\"=") {
+				f[1] = strconv.Quote(f[1])
+			}
+			kv = append(kv, f[0]+"="+f[1])
+		}
+		msg = strings.Join(kv, " ")
+	}
+	if strings.HasSuffix(e.Event, "-failed") || e.Event == "alert" || e.Event == "unhealthy" {
+		w.Err(msg)
+	} else {
+		w.Notice(msg)
+	}
+}
+
+// postWebhook POST body to the webhook, tried 3 times on a network error or a 5xx/429 reply, within webhookDeadline
+func postWebhook(hook Webhook, body []byte) error {
+
+	ctx, cancel := context.WithTimeout(context.Background(), webhookDeadline)
+	defer cancel()
+	client := http.Client{Timeout: 5 * time.Second}
+	var err error
+	for try := 0; try < 3; try++ {
+		if try > 0 {
+			select {
+			case <-time.After(time.Duration(try) * 2 * time.Second):
+			case <-ctx.Done():
+				return err
+			}
+		}
+		req, rerr := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+		if rerr != nil {
+			return fmt.Errorf("postWebhook() %w", rerr)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "jmgr/"+version)
+		if len(hook.Secret) > 0 {
+			mac := hmac.New(sha256.New, []byte(hook.Secret))
+			mac.Write(body)
+			req.Header.Set("X-Jmgr-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+		}
+		resp, derr := client.Do(req)
+		if derr != nil {
+			err = fmt.Errorf("postWebhook() %w", derr)
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 300 {
+			return nil
+		}
+		err = fmt.Errorf("postWebhook() %s replied %s", hook.URL, resp.Status)
+		if resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+			return err
+		}
+	}
+	return err
+}
+
+// return the hosts FreeBSD version
+func hostVersion() (string, error) {
+
+	rgx := regexp.MustCompile(`(.*RELEASE)`)
+	b, err := system.Run("/bin/freebsd-version", []string{})
+	if err != nil {
+		return "", fmt.Errorf("hostVersion() failed with: %w", err)
+	}
+	match := rgx.FindStringSubmatch(string(b[:]))
+
+	return match[1], nil
+}
+
+// return the given jail FreeBSD version
+func jailVersion(jailPath string) (string, error) {
+
+	_, err := os.Stat(jailPath)
+	if err != nil {
+		return "", fmt.Errorf("jailVersion, Path: %s error %w", jailPath, err)
+	}
+
+	b, err := system.Run("/usr/bin/env", []string{"ROOT=" + jailPath, jailPath + "/bin/freebsd-version"})
+	if err != nil {
+		return "", fmt.Errorf("jailVersion failed: %w", err)
+	}
+
+	return string(bytes.TrimRight(b, "\n")), nil
+}
+
+// Starts, stops or restart a given jail.
+func startstop(action string, jail *Jail) error {
+
+	if len(jail.Parent) > 0 {
+		return fmt.Errorf("it's a child. Should be managed from %s", jail.Parent)
+	}
+
+	if action != "stop" && len(jail.Meta.StandbyOf) > 0 {
+		return fmt.Errorf("%s is a standby replica of %s, use 'jmgr standby activate %s'", jail.Name, jail.Meta.StandbyOf, jail.Name)
+	}
+
+	var err error
+	switch action {
+	case "start":
+		if jail.Runs() {
+			return nil
+		}
+		err = lifecycle.Start(jail)
+	case "stop":
+		if !jail.Runs() {
+			return nil
+		}
+		err = lifecycle.Stop(jail, jailStopOptions(jail))
+	case "restart":
+		err = lifecycle.Restart(jail, jailStopOptions(jail))
+	default:
+		return errors.New("startstop() does not understand what to do")
+	}
+
+	var timeout *lifecycle.TimeoutError
+	if errors.As(err, &timeout) {
+		return jmgrError(exitTimeout, timeout.Error(), "Kill the processes left with: jmgr stop -timeout "+timeout.Timeout.String()+" -kill "+jail.Name)
+	}
+	if err != nil {
+		return err
+	}
+	recordStart(jail)
+	event(action, jail.Name, "")
+	return nil
+
+}
+
+// isPattern a jail name argument that selects jails, a glob, ex: 'web*', or '@tag'. Not a snapshot name, ex: web@2024
+func isPattern(arg string) bool {
+	return strings.HasPrefix(arg, "@") || (strings.ContainsAny(arg, "*?[") && !strings.Contains(arg, "@"))
+}
+
+// selectJails expand the globs and '@tag' of args to the jail names, and add the jails with tag, children are skipped.
+// Other args are kept as is, for the caller to check. expanded is true when a glob or tag selected the jails
+func (cfg *Jmgr) selectJails(args []string, tag string) (names []string, expanded bool, err error) {
+
+	if len(tag) > 0 {
+		args = append(args, "@"+strings.TrimPrefix(tag, "@"))
+	}
+	for _, arg := range args {
+		if !isPattern(arg) {
+			if !slices.Contains(names, arg) {
+				names = append(names, arg)
+			}
+			continue
+		}
+		if _, err := filepath.Match(arg, ""); err != nil {
+			return nil, false, jmgrError(exitUsage, "Bad pattern "+arg+": "+err.Error(), "See: jmgr help")
+		}
+		expanded = true
+		var found bool
+		for _, jail := range cfg.Jails {
+			var match bool
+			if name, ok := strings.CutPrefix(arg, "@"); ok {
+				match = slices.Contains(jail.Meta.Tags, name)
+			} else {
+				match, _ = filepath.Match(arg, jail.Name)
+			}
+			if !match || len(jail.Parent) > 0 {
+				continue
+			}
+			found = true
+			if !slices.Contains(names, jail.Name) {
+				names = append(names, jail.Name)
+			}
+		}
+		if !found {
+			return nil, expanded, jmgrError(exitNotFound, "No jail matches "+arg+".", "See the jails with: jmgr jails")
+		}
+	}
+	return names, expanded, nil
+}
+
+// stoppedJails filter the jails selected to the stopped jails, or all the jails with all. Children, protected jails,
+// standby replicas, jails in /etc/jail.conf and the jails that run are skipped
+func (cfg *Jmgr) stoppedJails(names []string, all bool) []string {
+
+	if all {
+		for _, jail := range cfg.Jails {
+			names = append(names, jail.Name)
+		}
+	}
+	var stopped []string
+	for _, name := range names {
+		if !cfg.exist(name) {
+			fatal(errNoJail(name))
+		}
+		switch jail := cfg.jail(name); {
+		case len(jail.Parent) > 0:
+		case jail.Runs():
+			fmt.Println(jail.Name + " runs, skipped.")
+		case jail.Meta.Protected:
+			fmt.Println(jail.Name + " is protected, skipped.")
+		case len(jail.Meta.StandbyOf) > 0:
+			fmt.Println(jail.Name + " is a standby replica of " + jail.Meta.StandbyOf + ", skipped.")
+		case jail.ConfigPath == "/etc/jail.conf":
+			fmt.Println(jail.Name + " is configured in /etc/jail.conf, skipped.")
+		default:
+			stopped = append(stopped, jail.Name)
+		}
+	}
+	return stopped
+}
+
+// confirmSelection list the jails a glob or tag selected and ask to continue, exit on no
+func confirmSelection(action string, names []string) {
+	askExitOnNo(strings.ToUpper(action[:1]) + action[1:] + ": " + strings.Join(names, ", ") + " (yes/No)? ")
+}
+
+// verifyArgs verify requirements before continue. dies if missing requirements. Returns: false with nil pointers or true with struct pointers.
+func verifyArgs(minargs int, namePos int, needRoot bool, exist bool, args []string) (*Jmgr, *Jail, error) {
+
+	if len(args) < minargs || args[namePos] == "help" || args[namePos] == "-h" {
+		help()
+	}
+
+	if needRoot && system.NotRoot() {
+		return nil, nil, errNeedRoot
+	}
+
+	var cfg Jmgr = jmgrInit()
+	if exist && !cfg.exist(args[namePos]) {
+		return nil, nil, errNoJail(args[namePos])
+	}
+
+	var jail Jail = cfg.jail(args[namePos])
+
+	return &cfg, &jail, nil
+}
+
+// JailSnapshot a snapshot selected by selectSnapshots
+type JailSnapshot struct {
+	Name    string // dataset@snapshot
+	Created time.Time
+}
+
+// selectSnapshots expand the snapshot args, 'jail@glob' or 'dataset@glob', to the snapshots that match, only those older than
+// age if age > 0. With age a jail name selects all its snapshots. The other args are returned as is.
+// A glob that matches no snapshot is not found, none older than age is not an error. The jmgr-standby-* snapshots are skipped unless the glob
+// starts with jmgr-standby-, a protected jail is refused
+func (cfg *Jmgr) selectSnapshots(args []string, age time.Duration) (rest []string, snaps []JailSnapshot, err error) {
+
+	for _, arg := range args {
+		name, pattern, isSnap := strings.Cut(arg, "@")
+		switch {
+		case !isSnap && age > 0 && cfg.exist(arg):
+			pattern = "*"
+		case isSnap && (age > 0 || strings.ContainsAny(pattern, "*?[")):
+		default:
+			rest = append(rest, arg)
+			continue
+		}
+		dataset := name
+		if cfg.exist(name) {
+			jail := cfg.jail(name)
+			if len(jail.Dataset) == 0 {
+				return nil, nil, jmgrError(exitConflict, "Jail "+name+" does not support zfs snapshot.", "")
+			}
+			if jail.Meta.Protected {
+				return nil, nil, errProtected(&jail, "destroy the snapshots of")
+			}
+			dataset = jail.Dataset
+		}
+		b, err := system.Run("/sbin/zfs", []string{"list", "-Hp", "-t", "snapshot", "-o", "name,creation", "-s", "creation", "-d", "1", dataset})
+		if err != nil {
+			return nil, nil, jmgrError(exitNotFound, "Can't find snapshots of "+name+".", "See the snapshots with: jmgr 'jail name'")
+		}
+		matched, older := 0, 0
+		for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+			f := strings.Fields(line)
+			if len(f) != 2 {
+				continue
+			}
+			_, snap, _ := strings.Cut(f[0], "@")
+			if ok, _ := filepath.Match(pattern, snap); !ok {
+				continue
+			}
+			// the base of the incremental standby replication, only when named, ex: web@jmgr-standby-*
+			if strings.HasPrefix(snap, "jmgr-standby-") && !strings.HasPrefix(pattern, "jmgr-standby-") {
+				continue
+			}
+			matched++
+			secs, _ := strconv.ParseInt(f[1], 10, 64)
+			created := time.Unix(secs, 0)
+			if age > 0 && time.Since(created) < age {
+				continue
+			}
+			older++
+			snaps = append(snaps, JailSnapshot{Name: f[0], Created: created})
+		}
+		if matched == 0 {
+			return nil, nil, jmgrError(exitNotFound, "No snapshot matches "+arg+".", "See the snapshots with: jmgr "+name)
+		}
+		if older == 0 {
+			fmt.Println("No snapshot of " + arg + " is older than " + fmtAge(age) + ".")
+		}
+	}
+	return rest, snaps, nil
+}
+
+// destroySnapshots list the snapshots with their age and the clones that block the destroy, confirm and destroy them
+func destroySnapshots(snaps []JailSnapshot, force bool, dryRun bool) {
+
+	if dryRun {
+		fmt.Println("Dry run, destroy " + strconv.Itoa(len(snaps)) + " zfs.Snapshot(s):")
+	}
+	for _, snap := range snaps {
+		fmt.Println("Snapshot:", snap.Name+", "+fmtAge(time.Since(snap.Created))+" old")
+		for _, clone := range zfs.Clones(snap.Name) {
+			fmt.Println("Clone:", clone+", blocks the destroy, promote it first, see: jmgr promote")
+		}
+	}
+	if dryRun {
+		return
+	}
+	if !force {
+		askExitOnNo("Destroy these " + strconv.Itoa(len(snaps)) + " zfs.Snapshot(s) (yes/No)? ")
+	}
+	for _, snap := range snaps {
+		if _, err := system.Run("/sbin/zfs", []string{"destroy", snap.Name}); err != nil {
+			fatal(err)
+		}
+		fmt.Println("Snapshot:", snap.Name, "Destroyed.")
+	}
+}
+
+// parseAge parse an age, a time.ParseDuration or days and weeks, ex: 7d, 2w
+func parseAge(s string) (time.Duration, error) {
+
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			days, err := strconv.ParseFloat(n, 64)
+			if err != nil || days < 0 {
+				return 0, fmt.Errorf("parseAge() bad age %s, ex: 12h, 7d or 2w", s)
+			}
+			return time.Duration(days * float64(unit)), nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("parseAge() bad age %s, ex: 12h, 7d or 2w", s)
+	}
+	return d, nil
+}
+
+// fmtAge format an age in days, hours or minutes, ex: 3d, 5h
+func fmtAge(d time.Duration) string {
+
+	switch {
+	case d >= 24*time.Hour:
+		return strconv.Itoa(int(d.Hours()/24)) + "d"
+	case d >= time.Hour:
+		return strconv.Itoa(int(d.Hours())) + "h"
+	}
+	return strconv.Itoa(int(d.Minutes())) + "m"
+}
+
+// validTag check that a tag is usable as a group name and in a snapshot name
+func validTag(tag string) bool {
+
+	return regexp.MustCompile(`^[a-zA-Z0-9_-]+$`).MatchString(tag)
+}
+
+// validHostname check that name is a RFC 1123 host name
+func validHostname(name string) bool {
+
+	rgx := regexp.MustCompile(`^([a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)(\.[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$`)
+	return len(name) <= 253 && rgx.MatchString(name)
+}
+
+// inJailList( addJails() helper, just return info if 'Name' exist in sysrc 'jail_list'
+func inJailList(jailList []byte, Name string) string {
+
+	rgx := regexp.MustCompile(`\b(` + Name + `)\b`)
+	if len(rgx.FindStringSubmatch(string(jailList))) > 1 {
+		return "Yes"
+	} else {
+		return "No"
+	}
+}
+
+var assumeYes bool // global -y or env JMGR_ASSUME_YES, answer yes on all questions
+
+// ask user, exit if not yes
+func askExitOnNo(question string) bool {
+
+	if askYes(question) {
+		return true
+	}
+	exit(0)
+	return false // make compiler happy
+}
+
+// ask user return true if yes. Yes with global -y, fail if there is no terminal to ask
+func askYes(question string) bool {
+
+	fmt.Print(question)
+	if assumeYes {
+		fmt.Println("yes (assumed)")
+		return true
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		fmt.Println()
+		fatal(jmgrError(exitUsage, "No terminal to answer the question, stdin is not a tty.", "Use: jmgr -y or env JMGR_ASSUME_YES=1"))
+	}
+	var answer string
+	fmt.Scanln(&answer)
+	if strings.ToUpper(answer) == "YES" || strings.ToUpper(answer) == "Y" {
+		return true
+	}
+	return false
+}
+
+// ask user for a value, return value if the answer is empty or with global -y
+func askValue(question string, value string) string {
+
+	fmt.Print(question + " [" + value + "]: ")
+	if assumeYes {
+		fmt.Println(value)
+		return value
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		fmt.Println()
+		fatal(jmgrError(exitUsage, "No terminal to answer the question, stdin is not a tty.", "Use: jmgr -y or env JMGR_ASSUME_YES=1"))
+	}
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	if answer = strings.TrimSpace(answer); len(answer) > 0 {
+		return answer
+	}
+	return value
+}
+
+// destroyPlan the resources destroy removes from the jail, with -r its snapshots, and what it affects and keeps, one line each
+func (cfg *Jmgr) destroyPlan(jail *Jail, recursive bool, kept string) []string {
+
+	var plan []string
+	add := func(format string, a ...any) { plan = append(plan, fmt.Sprintf(format, a...)) }
+
+	if jail.Meta.Protected {
+		add("Protected: %s, destroy refuses it, see: jmgr unprotect", jail.Name)
+	}
+	if jail.Runs() {
+		add("Jail: %s, running (jid %d), stopped first", jail.Name, jail.Jid)
+	} else {
+		add("Jail: %s, stopped", jail.Name)
+	}
+	for _, child := range jailChildren(jail) {
+		if child.Jid > 0 {
+			add("Child: %s, running (jid %d), stopped first, destroyed with %s, needs -with-children", child.Name, child.Jid, jail.Name)
+		} else {
+			add("Child: %s, in %s, destroyed with %s, needs -with-children", child.Name, child.Config, jail.Name)
+		}
+	}
+	add("Config: %s, removed", jail.ConfigPath)
+	add("Metadata: %s, removed", filepath.Join(cfg.JailMetaDir, jail.Name+".yml"))
+	if jail.OnBoot == "Yes" {
+		add("jail_list: %s, removed from rc.conf", jail.Name)
+	}
+
+	switch {
+	case len(kept) > 0 && len(jail.Dataset) > 0:
+		add("Dataset: %s (%s), kept as %s with its snapshots and child datasets", jail.Dataset, fmtBytes(zfs.UsedBytes(jail.Dataset)), kept)
+	case len(kept) > 0:
+		add("Filesystem: %s, kept as %s", jail.Path, kept)
+	case len(jail.Dataset) == 0:
+		add("Filesystem: %s, removed", jail.Path)
+	default:
+		add("Dataset: %s (%s), destroyed", jail.Dataset, fmtBytes(zfs.UsedBytes(jail.Dataset)))
+		if origin, err := zfs.Origin(jail.Dataset); err == nil && len(origin) > 0 {
+			add("Origin: %s, kept, %s is a thin clone of it", origin, jail.Name)
+		}
+		b, _ := system.Run("/sbin/zfs", []string{"list", "-H", "-r", "-o", "name", "-t", "filesystem,volume,snapshot", jail.Dataset})
+		for _, name := range strings.Fields(string(b)) {
+			switch {
+			case name == jail.Dataset:
+			case recursive && strings.Contains(name, "@"):
+				add("Snapshot: %s, destroyed", name)
+			case recursive:
+				add("Dataset: %s, destroyed", name)
+			case strings.Contains(name, "@"):
+				add("Snapshot: %s, blocks the destroy, destroy it or use -r", name)
+			default:
+				add("Dataset: %s, blocks the destroy, destroy it or use -r", name)
+			}
+		}
+		for _, clone := range zfs.Clones(jail.Dataset) {
+			add("Clone: %s, blocks the destroy, promote it first, see: jmgr promote", clone)
+		}
+	}
+	if len(kept) > 0 {
+		add("Retired: %s, the config and metadata, re-adopt with: jmgr adopt %s", filepath.Join(cfg.retiredDir(), jail.Name+".yml"), jail.Name)
+		if b, _ := system.Run("/sbin/pfctl", []string{"-a", "jmgr/" + jail.Name, "-sr"}); len(bytes.TrimSpace(b)) > 0 {
+			add("pf anchor: jmgr/%s, flushed", jail.Name)
+		}
+	}
+
+	var dependents []string
+	for _, other := range cfg.Jails {
+		if slices.Contains(other.Meta.Depends, jail.Name) {
+			dependents = append(dependents, other.Name)
+		}
+	}
+	if len(dependents) > 0 {
+		add("Depends on it: %s, kept, their dependency on %s is left", strings.Join(dependents, ", "), jail.Name)
+	}
+	if len(jail.Meta.Standby) > 0 {
+		add("Standby: the replica on %s, kept", jail.Meta.Standby)
+	}
+	for _, rule := range pfRules(append(append([]string{jail.Ipv4}, jail.Ipv4_addrs...), jail.Ipv6_addrs...)) {
+		add("pf rule: %s, kept, it has the IP of the jail", rule)
+	}
+	return plan
+}
+
+// RetiredJail a jail removed with 'destroy -keep-data', its data kept for 'jmgr adopt'
+type RetiredJail struct {
+	Name    string   `yaml:"Name" json:"name"`
+	Config  string   `yaml:"Config" json:"config"`   // jail config file
+	Data    string   `yaml:"Data" json:"data"`       // dataset or path of the jail
+	Kept    string   `yaml:"Kept" json:"kept"`       // dataset or path the data is kept in, Data renamed with the suffix
+	Retired string   `yaml:"Retired" json:"retired"` // time (RFC3339)
+	Meta    JailMeta `yaml:"Meta" json:"meta"`
+}
+
+// retiredDir the directory of the retired jails, <name>.yml and <name>.conf, in JailMetaDir
+func (cfg *Jmgr) retiredDir() string {
+	return filepath.Join(cfg.JailMetaDir, "retired")
+}
+
+// retiredJails return the retired jails, by name
+func (cfg *Jmgr) retiredJails() []RetiredJail {
+
+	var retired []RetiredJail
+	files, _ := filepath.Glob(filepath.Join(cfg.retiredDir(), "*.yml"))
+	for _, file := range files {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		var r RetiredJail
+		if err := yaml.Unmarshal(b, &r); err == nil {
+			retired = append(retired, r)
+		}
+	}
+	return retired
+}
+
+// canRetire check that the jail is not retired already and nothing is in the way of the kept data
+func (cfg *Jmgr) canRetire(jail *Jail, kept string) error {
+
+	if _, err := os.Stat(filepath.Join(cfg.retiredDir(), jail.Name+".yml")); err == nil {
+		return jmgrError(exitConflict, "A retired jail "+jail.Name+" is kept already, Can't continue.", "Adopt it first: jmgr adopt "+jail.Name)
+	}
+	if len(jail.Dataset) > 0 && kept != jail.Dataset {
+		if _, err := system.Run("/sbin/zfs", []string{"list", "-H", kept}); err == nil {
+			return jmgrError(exitConflict, "Dataset "+kept+" exists, Can't continue.", "Use another -suffix")
+		}
+	} else if len(jail.Dataset) == 0 && kept != jail.Path {
+		if _, err := os.Stat(kept); err == nil {
+			return jmgrError(exitConflict, kept+" exists, Can't continue.", "Use another -suffix")
+		}
+	}
+	return nil
+}
+
+// retireJail keep the config and metadata of the stopped jail in retiredDir and rename its dataset or filesystem to kept
+func (cfg *Jmgr) retireJail(jail *Jail, kept string) error {
+
+	conf, err := os.ReadFile(jail.ConfigPath)
+	if err != nil {
+		return fmt.Errorf("retireJail() %w", err)
+	}
+	r := RetiredJail{Name: jail.Name, Config: jail.ConfigPath, Data: jail.Path, Kept: kept, Retired: time.Now().Format(time.RFC3339), Meta: jail.Meta}
+	if len(jail.Dataset) > 0 {
+		r.Data = jail.Dataset
+	}
+	b, err := yaml.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("retireJail() %w", err)
+	}
+	if err := os.MkdirAll(cfg.retiredDir(), 0755); err != nil {
+		return fmt.Errorf("retireJail() %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(cfg.retiredDir(), jail.Name+".conf"), conf, 0644); err != nil {
+		return fmt.Errorf("retireJail() %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(cfg.retiredDir(), jail.Name+".yml"), b, 0644); err != nil {
+		return fmt.Errorf("retireJail() %w", err)
+	}
+	return moveData(r.Data, kept)
+}
+
+// moveData rename a dataset, or a directory, nothing if it does not change
+func moveData(from string, to string) error {
+
+	if from == to {
+		return nil
+	}
+	if strings.HasPrefix(from, "/") {
+		if err := os.Rename(from, to); err != nil {
+			return fmt.Errorf("moveData() %w", err)
+		}
+		return nil
+	}
+	if _, err := system.Run("/sbin/zfs", []string{"rename", from, to}); err != nil {
+		return fmt.Errorf("moveData() %w", err)
+	}
+	return nil
+}
+
+// JailChild a child jail of a jail, see jailChildren
+type JailChild struct {
+	Name   string // as the host names it, ex: web.app
+	Jid    int    // 0 if not running
+	Config string // jail.conf in the parent jail it is configured in, empty if only running
+}
+
+// jailChildren return the child jails of jail, the running descendants by their parent jid, deepest first, then the jails
+// configured in the jail.conf and jail.conf.d of the jail that do not run. Not by the parent.child name
+func jailChildren(jail *Jail) []JailChild {
+
+	var children []JailChild
+	if jail.Runs() {
+		// jid parent name
+		b, _ := system.Run("/usr/sbin/jls", []string{"jid", "parent", "name"})
+		parent := make(map[int]int)
+		var running []JailChild
+		for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+			f := strings.Fields(line)
+			if len(f) != 3 {
+				continue
+			}
+			jid, _ := strconv.Atoi(f[0])
+			parent[jid], _ = strconv.Atoi(f[1])
+			running = append(running, JailChild{Name: f[2], Jid: jid})
+		}
+		depth := func(jid int) int {
+			for n := 0; jid > 0 && n < 64; n++ {
+				if jid = parent[jid]; jid == jail.Jid {
+					return n + 1
+				}
+			}
+			return 0 // not a descendant
+		}
+		for _, child := range running {
+			if depth(child.Jid) > 0 {
+				children = append(children, child)
+			}
+		}
+		slices.SortStableFunc(children, func(a, b JailChild) int { return depth(b.Jid) - depth(a.Jid) })
+	}
+
+	confs, _ := filepath.Glob(filepath.Join(jail.Path, "etc/jail.conf.d/*.conf"))
+	rgx := regexp.MustCompile(`(?m)^\s*([A-Za-z0-9_-]+)\s*\{`)
+	for _, conf := range append([]string{filepath.Join(jail.Path, "etc/jail.conf")}, confs...) {
+		b, err := os.ReadFile(conf)
+		if err != nil {
+			continue
+		}
+		for _, m := range rgx.FindAllStringSubmatch(string(b), -1) {
+			name := jail.Name + "." + m[1]
+			if !slices.ContainsFunc(children, func(c JailChild) bool { return c.Name == name }) {
+				children = append(children, JailChild{Name: name, Config: strings.TrimPrefix(conf, jail.Path)})
+			}
+		}
+	}
+	return children
+}
+
+// stopChildren stop the running child jails in order, each with the jail.conf of its parent jail
+func stopChildren(children []JailChild) error {
+
+	for _, child := range children {
+		if child.Jid == 0 {
+			continue
+		}
+		i := strings.LastIndex(child.Name, ".")
+		if i < 0 {
+			continue
+		}
+		fmt.Println("Stop child jail " + child.Name + ".")
+		if _, err := system.Run("/usr/sbin/jexec", []string{child.Name[:i], "/usr/sbin/jail", "-r", child.Name[i+1:]}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// archiveJail copy the stopped jail to a new directory in dir, 'jail name'-'time': the dataset with its snapshots and
+// children as 'jail name'.zfs (zfs send -R, restore with zfs receive) or the filesystem as 'jail name'.tar, the config
+// file and the metadata. Return the directory. The archive snapshot is destroyed after the send unless keep, the data
+// is kept, a failed archive is removed with its snapshot
+func (cfg *Jmgr) archiveJail(jail *Jail, dir string, keep bool) (archived string, err error) {
+
+	path := filepath.Join(dir, jail.Name+"-"+time.Now().Format("20060102T150405"))
+	if err := os.Mkdir(path, 0700); err != nil {
+		return "", fmt.Errorf("archiveJail() %w", err)
+	}
+	defer func() {
+		if err != nil {
+			os.RemoveAll(path)
+		}
+	}()
+
+	var cmd *exec.Cmd
+	var file string
+	var total int64
+	if len(jail.Dataset) > 0 {
+		// of the dataset and its children
+		snap := jail.Dataset + "@jmgr-archive-" + time.Now().Format("2006-01-02T15:04:05")
+		if _, err := system.Run("/sbin/zfs", []string{"snapshot", "-r", snap}); err != nil {
+			return "", err
+		}
+		defer func() {
+			if err != nil || !keep {
+				system.Run("/sbin/zfs", []string{"destroy", "-r", snap})
+			}
+		}()
+		cmd = exec.Command(system.Tool("/sbin/zfs"), "send", "-R", snap)
+		file = filepath.Join(path, jail.Name+".zfs")
+		total = zfs.SendSize([]string{"send", "-R", snap})
+	} else {
+		cmd = exec.Command(system.Tool("/usr/bin/tar"), "-cf", "-", "-C", jail.Path, ".")
+		file = filepath.Join(path, jail.Name+".tar")
+	}
+
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return "", fmt.Errorf("archiveJail() %w", err)
+	}
+	defer f.Close()
+	p := newProgress("Archive "+jail.Name+" to "+file, total)
+	var stderr bytes.Buffer
+	cmd.Stdout = io.MultiWriter(f, p)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", &JmgrError{Code: exitCommand, Message: fmt.Sprintf("%s %s failed with:%s", cmd.Path, cmd.Args[1:], stderr.String()), err: err}
+	}
+	if err := f.Sync(); err != nil {
+		return "", fmt.Errorf("archiveJail() %w", err)
+	}
+	p.Done()
+
+	for _, src := range []string{jail.ConfigPath, filepath.Join(cfg.JailMetaDir, jail.Name+".yml")} {
+		b, err := os.ReadFile(src)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("archiveJail() %w", err)
+		}
+		if err := os.WriteFile(filepath.Join(path, filepath.Base(src)), b, 0600); err != nil {
+			return "", fmt.Errorf("archiveJail() %w", err)
+		}
+	}
+	return path, nil
+}
+
+// pfRules return the loaded pf filter and nat rules with one of the IP addresses, none if pf is not running
+func pfRules(ips []string) []string {
+
+	// the whole address, 10.0.0.1 is not in 10.0.0.15
+	var rgx []*regexp.Regexp
+	for _, ip := range ips {
+		if len(ip) > 0 && ip != "-" {
+			rgx = append(rgx, regexp.MustCompile(`(^|[^0-9a-fA-F.:])`+regexp.QuoteMeta(ip)+`($|[^0-9a-fA-F.:])`))
+		}
+	}
+	var rules []string
+	for _, show := range []string{"-sn", "-sr"} {
+		b, err := system.Run("/sbin/pfctl", []string{show})
+		if err != nil || len(rgx) == 0 {
+			return nil
+		}
+		for _, line := range strings.Split(string(b), "\n") {
+			if slices.ContainsFunc(rgx, func(r *regexp.Regexp) bool { return r.MatchString(line) }) {
+				rules = append(rules, strings.TrimSpace(line))
+			}
+		}
+	}
+	return rules
+}
+
+// print out all jails
+func reportJails(opts ListOptions, cfg *Jmgr) {
+
+	// flag end-of-life releases, no flags if the EOL table is not cached
+	eol, _ := cfg.eolTable(false)
+
+	if err := opts.check(); err != nil {
+		fatal(err)
+	}
+
+	jails := []Jail{}
+	for _, jail := range cfg.Jails {
+		status, _ := eolStatus(jail.OsVersion, eol)
+		if !opts.match(jail) || (opts.EolOnly && len(status) == 0) {
+			continue
+		}
+		jails = append(jails, jail)
+	}
+
+	switch opts.Sort {
+	case "name":
+		slices.SortStableFunc(jails, func(a, b Jail) int { return cmp.Compare(a.Name, b.Name) })
+	case "jid":
+		slices.SortStableFunc(jails, func(a, b Jail) int { return cmp.Compare(a.Jid, b.Jid) })
+	case "used":
+		used := make(map[string]int64)
+		for _, jail := range jails {
+			used[jail.Name] = zfs.UsedBytes(jail.Dataset)
+		}
+		slices.SortStableFunc(jails, func(a, b Jail) int { return cmp.Compare(used[b.Name], used[a.Name]) })
+	}
+
+	if jsonOutput {
+		printJSON(jails)
+		return
+	}
+
+	if opts.Quiet {
+		for _, jail := range jails {
+			fmt.Println(jail.Name)
+		}
+		return
+	}
+
+	if len(opts.Format) > 0 {
+		formatJails(opts.Format, jails)
+		return
+	}
+
+	columns := jailColumns[:8]
+	if len(opts.Columns) > 0 {
+		var err error
+		columns, err = selectColumns(opts.Columns)
+		if err != nil {
+			fatal(err)
+		}
+	}
+
+	switch opts.Output {
+	case "":
+	case "csv", "yaml":
+		exportJails(opts.Output, columns, jails)
+		return
+	default:
+		fatal(jmgrError(exitUsage, "Unknown output format "+opts.Output+".", "Use csv or yaml."))
+	}
+
+	var buf bytes.Buffer
+	defer func() { page(buf.Bytes(), opts.NoPager) }()
+
+	if len(opts.Columns) > 0 {
+		w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+		var row []string
+		for _, c := range columns {
+			row = append(row, c.Label)
+		}
+		fmt.Fprintln(w, " "+strings.Join(row, "\t"))
+		for _, jail := range jails {
+			if status, _ := eolStatus(jail.OsVersion, eol); len(status) > 0 {
+				jail.OsVersion += " (" + status + ")"
+			}
+			if jail.Meta.Hold {
+				jail.Name += " (hold)"
+			}
+			if jail.Meta.Protected {
+				jail.Name += " (protected)"
+			}
+			row = row[:0]
+			for _, c := range columns {
+				row = append(row, c.Value(jail))
+			}
+			fmt.Fprintln(w, opts.marker(jail)+strings.Join(row, "\t"))
+		}
+		w.Flush()
+		return
+	}
+
+	var labelFmt string = " %s\t%s\t%s\t%s\t%s"
+	var rowsFmt string = "%s%d\t%s\t%s\t%s\t%s"
+	var narrow int = 80
+
+	// the narrow listing drops Config, only on a terminal (not piped) or with -width
+	width := narrow + 1
+	fd := int(os.Stdout.Fd())
+	switch {
+	case opts.Wide:
+	case opts.Width > 0:
+		width = opts.Width
+	case term.IsTerminal(fd):
+		if w, _, err := term.GetSize(fd); err == nil {
+			width = w
+		}
+	}
+
+	w := tabwriter.NewWriter(&buf, 0, 0, 2, ' ', 0)
+
+	switch {
+
+	case width > narrow:
+		labelFmt += "\t%s\t%s\t%s\n"
+		rowsFmt += "\t%s\t%s\t%s\n"
+		fmt.Fprintf(w, labelFmt, "Jid", "Name", "IP Address", "Path", "Config", "OS Version", "Boot", "Uptime")
+
+	default:
+		labelFmt += "\n"
+		rowsFmt += "\n"
+		fmt.Fprintf(w, labelFmt, "Jid", "Name", "IP Address", "Path", "OS Version", "Boot")
+	}
+
+	// iterate Jails
+	for _, jail := range jails {
+		if status, _ := eolStatus(jail.OsVersion, eol); len(status) > 0 {
+			jail.OsVersion += " (" + status + ")"
+		}
+		if jail.Meta.Hold {
+			jail.Name += " (hold)"
+		}
+		if jail.Meta.Protected {
+			jail.Name += " (protected)"
+		}
+		switch {
+		case width > narrow:
+			fmt.Fprintf(w, rowsFmt, opts.marker(jail), jail.Jid, jail.Name, jail.Ipv4, jail.Path, jail.ConfigPath, jail.OsVersion, jail.OnBoot, jailUptime(jail))
+		default:
+			fmt.Fprintf(w, rowsFmt, opts.marker(jail), jail.Jid, jail.Name, jail.Ipv4, jail.Path, jail.OsVersion, jail.OnBoot)
+		}
+	}
+	w.Flush()
+}
+
+// eolTable return FreeBSD release (ex: 14.2 or 14) -> end-of-life date from EolUrl, cached in OsMediaDir for a week.
+// Only with fetch a missing or week old cache is fetched again, by update check, status and the daemon, the listings
+// use the cache as is. A stale cache is used if EolUrl can't be fetched.
+func (cfg *Jmgr) eolTable(fetch bool) (map[string]time.Time, error) {
+
+	cache := filepath.Join(cfg.OsMediaDir, "freebsd-eol.json")
+	b, err := os.ReadFile(cache)
+	if fi, serr := os.Stat(cache); fetch && (err != nil || serr != nil || time.Since(fi.ModTime()) > 7*24*time.Hour) {
+		client := http.Client{Timeout: 5 * time.Second}
+		if resp, gerr := client.Get(cfg.EolUrl); gerr == nil {
+			body, rerr := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if rerr == nil && resp.StatusCode == http.StatusOK {
+				b, err = body, nil
+				os.WriteFile(cache, b, 0644) // best effort, not root
+			}
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("eolTable() no EOL table from %s", cfg.EolUrl)
+	}
+
+	var cycles []struct {
+		Cycle string `json:"cycle"`
+		Eol   any    `json:"eol"` // date or false
+	}
+	if err := json.Unmarshal(b, &cycles); err != nil {
+		return nil, fmt.Errorf("eolTable() %w", err)
+	}
+
+	table := make(map[string]time.Time)
+	for _, c := range cycles {
+		if date, ok := c.Eol.(string); ok {
+			if t, err := time.Parse("2006-01-02", date); err == nil {
+				table[c.Cycle] = t
+			}
+		}
+	}
+	return table, nil
+}
+
+// eolStatus return "EOL" or "EOL soon" (within 90 days) and the end-of-life date for a FreeBSD version, ex: 13.4-RELEASE-p2.
+// The release is looked up before the major version (stable branch).
+func eolStatus(osVersion string, table map[string]time.Time) (string, string) {
+
+	release, _, _ := strings.Cut(osVersion, "-")
+	major, _, _ := strings.Cut(release, ".")
+
+	t, ok := table[release]
+	if !ok {
+		if t, ok = table[major]; !ok {
+			return "", ""
+		}
+	}
+
+	date := t.Format("2006-01-02")
+	switch {
+	case time.Now().After(t):
+		return "EOL", date
+	case time.Until(t) < 90*24*time.Hour:
+		return "EOL soon", date
+	}
+	return "", date
+}
+
+// updateAll patch or upgrade pkgs on all jails, a snapshot per ZFS jail, 'parallel' jails at a time
+func updateAll(what string, tag string, glob string, force bool, parallel int, verify bool) {
+
+	if system.NotRoot() {
+		fatal(errNeedRoot)
+	}
+
+	var cfg Jmgr = jmgrInit()
+	var jails []Jail
+	var held []UpdateResult
+	for _, jail := range cfg.Jails {
+		if len(jail.Parent) > 0 || len(jail.Meta.StandbyOf) > 0 || (len(tag) > 0 && !slices.Contains(jail.Meta.Tags, tag)) {
+			continue
+		}
+		if ok, _ := filepath.Match(glob, jail.Name); len(glob) > 0 && !ok {
+			continue
+		}
+		if why := heldBack(&jail); len(why) > 0 {
+			held = append(held, UpdateResult{Name: jail.Name, Status: "skipped", Error: why})
+			continue
+		}
+		jails = append(jails, jail)
+	}
+	if len(jails) == 0 {
+		fatal(jmgrError(exitNotFound, "No jails to update.", "See the jails with: jmgr jails"))
+	}
+
+	if !force {
+		var names []string
+		for _, jail := range jails {
+			names = append(names, jail.Name)
+		}
+		askExitOnNo("Update " + what + " on: " + strings.Join(names, ", ") + " (yes/No)? ")
+	}
+
+	results := make([]UpdateResult, len(jails))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	s := spinner.StartNew("Update " + what + " on " + strconv.Itoa(len(jails)) + " jails")
+	for i := range jails {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i] = cfg.updateJail(what, &jails[i], verify)
+		}(i)
+	}
+	wg.Wait()
+	s.Stop()
+	fmt.Println("/ Completed.")
+	for _, r := range results {
+		if r.Status == "failed" || r.Status == "verify failed" {
+			event("update-failed", r.Name, what+": "+r.Error)
+		} else {
+			event("update-complete", r.Name, what)
+		}
+	}
+	results = append(results, held...)
+
+	var failed int
+	var rowsFmt string = "%s\t%s\t%s\t%s\n"
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, rowsFmt, "Name", "Status", "Snapshot", "Error")
+	for _, r := range results {
+		if r.Status == "failed" || r.Status == "verify failed" {
+			failed++
+		}
+		fmt.Fprintf(w, rowsFmt, r.Name, r.Status, r.Snapshot, r.Error)
+	}
+	w.Flush()
+
+	if failed > 0 {
+		jsonResult = results
+		fatal(jmgrError(exitCommand, strconv.Itoa(failed)+" of "+strconv.Itoa(len(results))+" jails failed.", ""))
+	}
+	printJSON(results)
+}
+
+// updateRelAll upgrade all jails to a release, default the host release, 'parallel' jails at a time.
+// The result per jail is kept in a state file, a new run continues with the jails not upgraded.
+func updateRelAll(release string, tag string, glob string, force bool, parallel int, verify bool) {
+
+	if system.NotRoot() {
+		fatal(errNeedRoot)
+	}
+
+	var err error
+	if len(release) == 0 {
+		release, err = hostVersion()
+		if err != nil {
+			fatal(err)
+		}
+	}
+
+	var cfg Jmgr = jmgrInit()
+	stateFile := filepath.Join(cfg.UpdateCacheDir, "rel-"+release+".yml")
+	state := RelState{Release: release, Jails: make(map[string]UpdateResult)}
+	if b, err := os.ReadFile(stateFile); err == nil {
+		if err := yaml.Unmarshal(b, &state); err != nil {
+			fatal(jmgrError(exitError, "Problem decoding "+stateFile+": "+err.Error(), "Remove "+stateFile+" to start over."))
+		}
+		fmt.Println("Continue upgrade to " + release + " from " + stateFile)
+	}
+
+	var jails []Jail
+	var names []string
+	for _, jail := range cfg.Jails {
+		globbed, _ := filepath.Match(glob, jail.Name)
+		switch {
+		case len(jail.Parent) > 0 || len(jail.Meta.StandbyOf) > 0:
+			continue
+		case len(tag) > 0 && !slices.Contains(jail.Meta.Tags, tag):
+			continue
+		case len(glob) > 0 && !globbed:
+			continue
+		case strings.HasPrefix(jail.OsVersion, release):
+			if _, ok := state.Jails[jail.Name]; !ok {
+				state.Jails[jail.Name] = UpdateResult{Name: jail.Name, Status: "at release"}
+			}
+			continue
+		case cfg.isPkgBase(&jail):
+			state.Jails[jail.Name] = UpdateResult{Name: jail.Name, Status: "skipped", Error: "pkgbase, see update base"}
+			continue
+		case len(heldBack(&jail)) > 0:
+			state.Jails[jail.Name] = UpdateResult{Name: jail.Name, Status: "skipped", Error: heldBack(&jail)}
+			continue
+		}
+		jails = append(jails, jail)
+		names = append(names, jail.Name+" ("+jail.OsVersion+")")
+	}
+
+	if len(jails) > 0 {
+		if !force {
+			askExitOnNo("Upgrade to " + release + ": " + strings.Join(names, ", ") + " (yes/No)? ")
+		}
+
+		if err := os.MkdirAll(cfg.UpdateCacheDir, 0755); err != nil {
+			fatal(err)
+		}
+		var mu sync.Mutex
+		save := func(r UpdateResult) {
+			mu.Lock()
+			defer mu.Unlock()
+			state.Jails[r.Name] = r
+			if b, err := yaml.Marshal(state); err == nil {
+				os.WriteFile(stateFile, b, 0644)
+			}
+		}
+
+		sem := make(chan struct{}, parallel)
+		var wg sync.WaitGroup
+		s := spinner.StartNew("Upgrade " + strconv.Itoa(len(jails)) + " jails to " + release)
+		for i := range jails {
+			wg.Add(1)
+			go func(jail *Jail) {
+				defer wg.Done()
+				sem <- struct{}{}
+				defer func() { <-sem }()
+
+				r := UpdateResult{Name: jail.Name, Status: "upgrading"}
+				if up := jail.Meta.Upgrade; up != nil && up.To == release {
+					r.Snapshot = up.Snapshot
+				} else if len(jail.Dataset) > 0 {
+					snap, err := zfs.Snapshot(jail.Dataset)
+					if err != nil {
+						r.Status, r.Error = "failed", err.Error()
+						save(r)
+						return
+					}
+					r.Snapshot = snap
+				}
+				save(r)
+
+				if err := cfg.upgradeRel(jail, release, r.Snapshot, false); err != nil {
+					r.Status, r.Error = "failed", strings.ReplaceAll(err.Error(), "\n", " ")
+				} else {
+					r.Status = "upgraded"
+					if verify {
+						if problems := verifyUpdate(jail, release, true); len(problems) > 0 {
+							r.Status, r.Error = "verify failed", strings.Join(problems, "; ")
+						}
+					}
+				}
+				save(r)
+			}(&jails[i])
+		}
+		wg.Wait()
+		s.Stop()
+		fmt.Println("/ Completed.")
+	}
+
+	var keys []string
+	for k := range state.Jails {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	var failed int
+	var rowsFmt string = "%s\t%s\t%s\t%s\n"
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, rowsFmt, "Name", "Status", "Snapshot", "Error")
+	for _, k := range keys {
+		r := state.Jails[k]
+		if r.Status == "failed" || r.Status == "upgrading" || r.Status == "verify failed" {
+			failed++
+		}
+		fmt.Fprintf(w, rowsFmt, r.Name, r.Status, r.Snapshot, r.Error)
+	}
+	w.Flush()
+
+	if failed > 0 {
+		jsonResult = state
+		fatal(jmgrError(exitCommand, strconv.Itoa(failed)+" jails not upgraded, state in "+stateFile+".", "Run again to continue."))
+	}
+	os.Remove(stateFile)
+	printJSON(state)
+}
+
+// updateJail non-interactive patch or pkgs update of one jail for updateAll, stopped jails are started for pkgs and stopped again
+func (cfg *Jmgr) updateJail(what string, jail *Jail, verify bool) UpdateResult {
+
+	r := UpdateResult{Name: jail.Name, Status: "updated"}
+	running := jail.Runs()
+	fail := func(err error) UpdateResult {
+		r.Status = "failed"
+		r.Error = strings.ReplaceAll(err.Error(), "\n", " ")
+		return r
+	}
+
+	if len(jail.Dataset) > 0 {
+		snap, err := zfs.Snapshot(jail.Dataset)
+		if err != nil {
+			return fail(err)
+		}
+		r.Snapshot = snap
+	}
+
+	switch what {
+
+	case "patch", "base":
+		if cfg.isPkgBase(jail) {
+			if err := cfg.updatePkgBase(jail); err != nil {
+				return fail(err)
+			}
+			break
+		}
+		workdir, err := cfg.updateWorkdir(jail.OsVersion)
+		if err != nil {
+			return fail(err)
+		}
+		unlock := lockWorkdir(workdir)
+		_, err = system.Run("/usr/bin/env", []string{
+			"UNAME_r=" + jail.OsVersion, "PAGER=/bin/cat",
+			"/usr/sbin/freebsd-update", "-b", jail.Path, "-d", workdir,
+			"--currently-running", jail.OsVersion,
+			"--not-running-from-cron",
+			"fetch", "install"})
+		unlock()
+		if err != nil {
+			return fail(err)
+		}
+
+	case "pkgs":
+		if !jail.Runs() {
+			if err := startstop("start", jail); err != nil {
+				return fail(err)
+			}
+			defer startstop("stop", jail)
+		}
+		if err := cfg.writePkgRepos(jail.Path, jail.Meta.PkgRepos); err != nil {
+			return fail(err)
+		}
+		if !hasPkg(jail) {
+			if err := pkgBootstrap(jail); err != nil {
+				return fail(err)
+			}
+			r.Status = "updated, pkg bootstrapped"
+		}
+		if _, err := system.Run("/usr/sbin/pkg", []string{"-j", jail.Name, "update"}); err != nil {
+			return fail(err)
+		}
+		if _, err := system.Run("/usr/sbin/pkg", []string{"-j", jail.Name, "upgrade", "-y"}); err != nil {
+			return fail(err)
+		}
+	}
+
+	// a jail started for the update is not restarted
+	if verify {
+		release := regexp.MustCompile(`-p[0-9]+$`).ReplaceAllString(jail.OsVersion, "")
+		if problems := verifyUpdate(jail, release, running); len(problems) > 0 {
+			r.Status, r.Error = "verify failed", strings.Join(problems, "; ")
+		}
+	}
+	return r
+}
+
+// updateCheck print pending base patches and package upgrades for the named jails or all jails, nothing is applied
+func updateCheck(names []string, parallel int) {
+
+	if system.NotRoot() {
+		fatal(errNeedRoot)
+	}
+
+	var cfg Jmgr = jmgrInit()
+	cfg.eolTable(true) // the cache of the listings
+	var jails []Jail
+	for _, jail := range cfg.Jails {
+		if len(jail.Parent) > 0 || (len(names) > 0 && !slices.Contains(names, jail.Name)) {
+			continue
+		}
+		jails = append(jails, jail)
+	}
+	for _, name := range names {
+		if !cfg.exist(name) {
+			fatal(errNoJail(name))
+		}
+	}
+
+	results := make([]UpdateCheck, len(jails))
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	s := spinner.StartNew("Check for updates on " + strconv.Itoa(len(jails)) + " jails")
+	for i := range jails {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i] = cfg.checkJail(&jails[i])
+		}(i)
+	}
+	wg.Wait()
+	s.Stop()
+	fmt.Println("/ Completed.")
+
+	var rowsFmt string = "%s\t%s\t%s\t%s\t%s\n"
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, rowsFmt, "Name", "OS Version", "Base Patch", "Pkg Upgrades", "Error")
+	for _, r := range results {
+		patch, pkgs := "-", "-"
+		if len(r.Patch) > 0 {
+			patch = r.Patch
+		}
+		if r.Pkgs > 0 {
+			pkgs = strconv.Itoa(r.Pkgs)
+		}
+		fmt.Fprintf(w, rowsFmt, r.Name, r.OsVersion, patch, pkgs, r.Error)
+	}
+	w.Flush()
+	printJSON(results)
+}
+
+// checkJail pending base patch and number of package upgrades for one jail.
+// freebsd-update fetch only downloads to the host work directory, pkg upgrade -n does not change the jail.
+func (cfg *Jmgr) checkJail(jail *Jail) UpdateCheck {
+
+	r := UpdateCheck{Name: jail.Name, OsVersion: jail.OsVersion}
+	var errs []string
+
+	// the base packages of a pkgbase jail are counted with the other packages
+	if cfg.isPkgBase(jail) {
+		r.Patch = "pkgbase"
+		return cfg.checkPkgs(jail, r, errs)
+	}
+
+	workdir, err := cfg.updateWorkdir(jail.OsVersion)
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	unlock := lockWorkdir(workdir)
+	b, err := exec.Command(system.Tool("/usr/bin/env"), "UNAME_r="+jail.OsVersion, "PAGER=/bin/cat",
+		"/usr/sbin/freebsd-update", "-b", jail.Path, "-d", workdir,
+		"--currently-running", jail.OsVersion,
+		"--not-running-from-cron", "fetch").CombinedOutput()
+	unlock()
+	if err != nil {
+		errs = append(errs, "freebsd-update: "+err.Error())
+	} else if match := regexp.MustCompile(`updating to (\S+):`).FindSubmatch(b); match != nil {
+		r.Patch = string(match[1])
+	}
+
+	return cfg.checkPkgs(jail, r, errs)
+}
+
+// checkPkgs add the number of package upgrades to an update check
+func (cfg *Jmgr) checkPkgs(jail *Jail, r UpdateCheck, errs []string) UpdateCheck {
+
+	// pkg -j needs a running jail, a stopped jail is checked from the host with -c (chroot)
+	pkgArgs := []string{"-c", jail.Path, "upgrade", "-n"}
+	if jail.Runs() {
+		pkgArgs = []string{"-j", jail.Name, "upgrade", "-n"}
+	}
+	b, err := exec.Command(system.Tool("/usr/sbin/pkg"), pkgArgs...).CombinedOutput()
+	// pkg upgrade -n exits 1 when there are packages to upgrade
+	var exitErr *exec.ExitError
+	if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == 1) {
+		errs = append(errs, "pkg: "+err.Error())
+	} else if match := regexp.MustCompile(`Number of packages to be upgraded: (\d+)`).FindSubmatch(b); match != nil {
+		r.Pkgs, _ = strconv.Atoi(string(match[1]))
+	}
+
+	r.Error = strings.Join(errs, "; ")
+	return r
+}
+
+// verifyUpdate check a jail after an update: freebsd-version is the release, pkg check -sa and, if restart, the running jail restarts.
+// Returns the problems found.
+func verifyUpdate(jail *Jail, release string, restart bool) []string {
+
+	var problems []string
+
+	version, err := jailVersion(jail.Path)
+	if err != nil {
+		problems = append(problems, err.Error())
+	} else if !strings.HasPrefix(version, release) {
+		problems = append(problems, "freebsd-version is "+version+", expected "+release)
+	} else {
+		jail.OsVersion = version
+	}
+
+	if hasPkg(jail) {
+		pkgArgs := []string{"-c", jail.Path}
+		if jail.Runs() {
+			pkgArgs = []string{"-j", jail.Name}
+		}
+		if _, err := system.Run("/usr/sbin/pkg", append(pkgArgs, "check", "-s", "-a", "-q")); err != nil {
+			problems = append(problems, "pkg check -sa: "+strings.TrimSpace(err.Error()))
+		}
+	}
+
+	if restart && jail.Runs() {
+		if err := startstop("restart", jail); err != nil {
+			problems = append(problems, "restart: "+err.Error())
+		}
+	}
+	return problems
+}
+
+// reportVerify print the result of verifyUpdate, with the snapshot to roll back to. Returns false if there are problems.
+func reportVerify(jail *Jail, problems []string, snap string) bool {
+
+	if len(problems) == 0 {
+		fmt.Println("Verify " + jail.Name + ": ok")
+		return true
+	}
+	fmt.Println("Verify " + jail.Name + " failed:")
+	for _, p := range problems {
+		fmt.Println("  " + p)
+	}
+	if len(snap) > 0 {
+		fmt.Println("Roll back with: jmgr rollback " + jail.Name + " " + snap)
+	}
+	return false
+}
+
+// hasPkg report if pkg is installed in the jail
+func hasPkg(jail *Jail) bool {
+
+	_, err := os.Stat(jail.Path + "/usr/local/sbin/pkg")
+	return err == nil
+}
+
+// pkgBootstrap install pkg in a running jail without asking, the ABI is derived from the jail release not the host
+func pkgBootstrap(jail *Jail) error {
+
+	b, err := system.Run("/usr/bin/uname", []string{"-p"})
+	if err != nil {
+		return fmt.Errorf("pkgBootstrap() %w", err)
+	}
+	major, _, _ := strings.Cut(jail.OsVersion, ".")
+	abi := "FreeBSD:" + major + ":" + strings.TrimSpace(string(b))
+
+	_, err = system.Run("/usr/sbin/jexec", []string{jail.Name, "/usr/bin/env",
+		"ASSUME_ALWAYS_YES=yes", "IGNORE_OSVERSION=yes", "ABI=" + abi,
+		"/usr/sbin/pkg", "bootstrap"})
+	if err != nil {
+		return fmt.Errorf("pkgBootstrap() %s: %w", abi, err)
+	}
+	return nil
+}
+
+// isPkgBase report if the jail base system is installed with pkg (pkgbase), detected once and recorded in the jail metadata
+func (cfg *Jmgr) isPkgBase(jail *Jail) bool {
+
+	if jail.Meta.Base == "pkgbase" {
+		return true
+	}
+	if !hasPkg(jail) {
+		return false
+	}
+	_, err := system.Run("/usr/sbin/pkg", []string{"-r", jail.Path, "info", "-e", "FreeBSD-runtime"})
+	if err != nil {
+		return false
+	}
+	jail.Meta.Base = "pkgbase"
+	cfg.writeMeta(jail.Name, jail.Meta)
+	return true
+}
+
+// updatePkgBase upgrade the base system packages from PkgBaseRepo, in a stopped jail from the host with -c (chroot)
+func (cfg *Jmgr) updatePkgBase(jail *Jail) error {
+
+	pkgArgs := []string{"-c", jail.Path}
+	if jail.Runs() {
+		pkgArgs = []string{"-j", jail.Name}
+	}
+	pkgArgs = append(pkgArgs, "upgrade", "-y", "-r", cfg.PkgBaseRepo)
+
+	_, err := system.Run("/usr/sbin/pkg", pkgArgs)
+	if err != nil {
+		return fmt.Errorf("updatePkgBase() %w", err)
+	}
+	return nil
+}
+
+// upgrade packages
+func upgradePkg(jail *Jail) error {
+
+	// pkg update
+	cmd := exec.Command(system.Tool("/usr/sbin/pkg"), []string{"-j", jail.Name, "update"}...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Stdin = os.Stdin
+	err := cmd.Run()
+	if err != nil {
+		return fmt.Errorf("upgradePkg(): %w", err)
+	}
+
+	// pkg upgrade
+	cmd = exec.Command(system.Tool("/usr/sbin/pkg"), []string{"-j", jail.Name, "upgrade"}...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Stdin = os.Stdin
+	err = cmd.Run()
+	if err != nil {
+		return fmt.Errorf("upgradePkg(): %w", err)
+	}
+
+	return nil
+}
+
+// freebsd upgrade jail to a new release
+// Not interactive: the freebsd-update questions are answered yes and a merge conflict fails the upgrade.
+// The completed phases are recorded in the jail metadata, an upgrade to the same release in progress is resumed.
+func (cfg *Jmgr) upgradeRel(jail *Jail, Release string, snapshot string, interactive bool) error {
+
+	up := jail.Meta.Upgrade
+	if up == nil || up.To != Release {
+		up = &RelUpgrade{From: jail.OsVersion, To: Release, Snapshot: snapshot}
+	}
+	up.Error = ""
+	jail.Meta.Upgrade = up
+
+	// the upgrade and the installs use the work directory of the current release
+	workdir, err := cfg.updateWorkdir(up.From)
+	if err != nil {
+		return fmt.Errorf("upgradeRel() %w", err)
+	}
+
+	freebsdUpdate := func(args ...string) error {
+		args = append([]string{"-b", jail.Path, "-d", workdir}, args...)
+		if interactive {
+			return system.RunStdin("/usr/sbin/freebsd-update", args)
+		}
+		unlock := lockWorkdir(workdir)
+		defer unlock()
+		cmd := exec.Command(system.Tool("/usr/bin/env"), append([]string{"PAGER=/bin/cat", "/usr/sbin/freebsd-update", "--not-running-from-cron"}, args...)...)
+		cmd.Stdin = strings.NewReader(strings.Repeat("y\n", 100))
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+			return fmt.Errorf("%w: %s", err, lines[len(lines)-1])
+		}
+		return nil
+	}
+
+	phases := []struct {
+		name string
+		run  func() error
+	}{
+		// get new release
+		{"upgrade", func() error {
+			return freebsdUpdate("--currently-running", up.From, "-r", Release, "upgrade")
+		}},
+		// first install
+		{"install", func() error { return freebsdUpdate("install") }},
+		// jail restart
+		{"restart", func() error {
+			if err := startstop("stop", jail); err != nil {
+				return err
+			}
+			time.Sleep(200 * time.Millisecond)
+			return startstop("start", jail)
+		}},
+		// second install
+		{"install2", func() error { return freebsdUpdate("install") }},
+	}
+
+	skip := len(up.Done) > 0
+	for _, phase := range phases {
+		if skip {
+			skip = phase.name != up.Done
+			continue
+		}
+		if err := phase.run(); err != nil {
+			up.Error = phase.name + ": " + err.Error()
+			cfg.writeMeta(jail.Name, jail.Meta)
+			return fmt.Errorf("upgradeRel() %s, resume with 'jmgr update rel -resume %s' or roll back with -abort", up.Error, jail.Name)
+		}
+		up.Done = phase.name
+		if err := cfg.writeMeta(jail.Name, jail.Meta); err != nil {
+			return fmt.Errorf("upgradeRel() %w", err)
+		}
+	}
+
+	jail.Meta.Upgrade = nil
+	return cfg.writeMeta(jail.Name, jail.Meta)
+}
+
+// fetch and print avaliable freebsd releases
+func printRel() error {
+
+	var cfg Jmgr = jmgrInit()
+	hw, err := machine()
+	if err != nil {
+		return fmt.Errorf("printRel() failed: %w", err)
+	}
+
+	fetchURL := cfg.OsUrlPrefix + "/" + hw + "/" + hw + "/"
+	u, err := url.Parse(fetchURL)
+	if err != nil {
+		return fmt.Errorf("printRel() failed: %w", err)
+	}
+
+	c, err := ftp.Dial(u.Hostname()+":21", ftp.DialWithTimeout(5*time.Second))
+	if err != nil {
+		return fmt.Errorf("printRel() failed: %w", err)
+	}
+	defer c.Quit()
+
+	err = c.Login("anonymous", "anonymous")
+	if err != nil {
+		return fmt.Errorf("printRel() failed: %w", err)
+	}
+
+	list, err := c.List(u.EscapedPath())
+	if err != nil {
+		return fmt.Errorf("printRel() failed: %w", err)
+	}
+
+	rgx := regexp.MustCompile(`(.*RELEASE)`)
+	fmt.Println("Available Releases at:", fetchURL)
+	for _, entry := range list {
+		match := rgx.FindStringSubmatch(entry.Name)
+		if len(match) > 1 {
+			fmt.Println(entry.Name)
+		}
+	}
+
+	return nil
+}
+
+// freebsd update to latest patch
+func (cfg *Jmgr) updateOs(jail *Jail) error {
+
+	workdir, err := cfg.updateWorkdir(jail.OsVersion)
+	if err != nil {
+		return fmt.Errorf("updateOs() %w", err)
+	}
+
+	s := spinner.StartNew("Update FreeBSD on jail " + jail.Name)
+
+	_, err = system.Run("/usr/bin/env", []string{
+		"UNAME_r=" + jail.OsVersion,
+		"/usr/sbin/freebsd-update", "-b", jail.Path, "-d", workdir,
+		"--currently-running", jail.OsVersion,
+		"--not-running-from-cron",
+		"fetch", "install"})
+
+	s.Stop()
+	if err != nil {
+		return fmt.Errorf("runCMD() reports: %s", err.Error())
+	}
+
+	return nil
+}
+
+// locks for freebsd-update work directories shared by jails updated in parallel
+var workdirLocks sync.Map
+
+// updateWorkdir return the shared freebsd-update work directory for a release, ex: 14.2-RELEASE-p1 -> UpdateCacheDir/14.2-RELEASE
+func (cfg *Jmgr) updateWorkdir(osVersion string) (string, error) {
+
+	release := regexp.MustCompile(`-p[0-9]+$`).ReplaceAllString(osVersion, "")
+	dir := filepath.Join(cfg.UpdateCacheDir, release)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("updateWorkdir() %w", err)
+	}
+	return dir, nil
+}
+
+// lockWorkdir serialize freebsd-update on a work directory, freebsd-update refuses to run twice on it. Returns unlock.
+func lockWorkdir(dir string) func() {
+
+	m, _ := workdirLocks.LoadOrStore(dir, &sync.Mutex{})
+	m.(*sync.Mutex).Lock()
+	return m.(*sync.Mutex).Unlock
+}
+
+// updateClean remove the freebsd-update work directories of releases no jail runs
+func updateClean(force bool) {
+
+	if system.NotRoot() {
+		fatal(errNeedRoot)
+	}
+
+	var cfg Jmgr = jmgrInit()
+	used := make(map[string]bool)
+	for _, jail := range cfg.Jails {
+		used[regexp.MustCompile(`-p[0-9]+$`).ReplaceAllString(jail.OsVersion, "")] = true
+	}
+
+	dirs, err := os.ReadDir(cfg.UpdateCacheDir)
+	if err != nil {
+		fatal(err)
+	}
+	var remove []string
+	for _, d := range dirs {
+		if d.IsDir() && !used[d.Name()] {
+			remove = append(remove, d.Name())
+		}
+	}
+	if len(remove) == 0 {
+		fmt.Println("Nothing to clean in " + cfg.UpdateCacheDir + ".")
+		return
+	}
+
+	if !force {
+		askExitOnNo("Remove freebsd-update cache for " + strings.Join(remove, ", ") + " (yes/No)? ")
+	}
+	for _, d := range remove {
+		if err := os.RemoveAll(filepath.Join(cfg.UpdateCacheDir, d)); err != nil {
+			fatal(err)
+		}
+		fmt.Println("Removed", filepath.Join(cfg.UpdateCacheDir, d))
+	}
+}
+
+// return hw platform
+func machine() (string, error) {
+
+	b, err := system.Run("/usr/bin/uname", []string{"-m"})
+	if err != nil {
+		return "", fmt.Errorf("machine() %s ", err.Error())
+	}
+	return string(bytes.TrimRight(b, "\n")), nil
+}
+
+// ZFS or FS clone with progress, 'from'/'to' is either ZFS snapshot/dataset or old/new directory all depending on 'useZFS'
+func clone(useZFS bool, from string, to string) error {
+
+	var err error
+	var RecvOut, SendOut io.ReadCloser
+	var Send, Recv *exec.Cmd
+	var total int64
+
+	if useZFS {
+		Send = exec.Command(system.Tool("/sbin/zfs"), "send", from)
+		Recv = exec.Command(system.Tool("/sbin/zfs"), "receive", to)
+		total = zfs.SendSize([]string{"send", from})
+	} else {
+		Send = exec.Command(system.Tool("/bin/sh"), "-c", "cd "+from+";/usr/bin/tar -cf - *")
+		Recv = exec.Command(system.Tool("/usr/bin/tar"), "-x", "-C", to)
+	}
+	p := newProgress("Clone "+from+" to "+to, total)
+
+	SendOut, err = Send.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("clone() Send.StdoutPipe(): %w", err)
+	}
+	Recv.Stdin = io.TeeReader(SendOut, p)
+
+	RecvOut, err = Recv.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("clone() Recv.StdoutPipe(): %w", err)
+	}
+
+	// Start clone
+	err = Recv.Start()
+	if err != nil {
+		return fmt.Errorf("clone() Recv.Start(): %w", err)
+	}
+
+	err = Send.Start()
+	if err != nil {
+		return fmt.Errorf("clone() Send.Start(): %w", err)
+	}
+
+	// Read the output of the 'receiver' command
+	RecvResult, err := io.ReadAll(RecvOut)
+	if err != nil {
+		return fmt.Errorf("clone() io.ReadAll: %w", err)
+	}
+
+	// Wait for transfer to finish, the receiver reads the sender output
+	err = Recv.Wait()
+	if err != nil {
+		Send.Process.Kill()
+		Send.Wait()
+		return fmt.Errorf("clone() Recv.Wait(): %w", err)
+	}
+
+	err = Send.Wait()
+	if err != nil {
+		return fmt.Errorf("clone() Send.Wait(): %w", err)
+	}
+
+	p.Done()
+	fmt.Println("/ Completed.")
+
+	if len(RecvResult) > 0 {
+		fmt.Printf("zfs recv report: %s\n", RecvResult)
+		return fmt.Errorf("clone() RecvResult: %s", string(RecvResult))
+	}
+	return nil
+}
+
+// Progress count the bytes written to it and redraw a progress line on a terminal, bytes of Total, rate and ETA.
+// Total 0 is unknown, only bytes and rate are shown.
+type Progress struct {
+	Title string
+	Total int64
+	n     int64
+	start time.Time
+	drawn time.Time
+	tty   bool
+}
+
+func newProgress(title string, total int64) *Progress {
+	return &Progress{Title: title, Total: total, start: time.Now(), tty: term.IsTerminal(int(os.Stdout.Fd()))}
+}
+
+func (p *Progress) Write(b []byte) (int, error) {
+
+	p.n += int64(len(b))
+	if p.tty && time.Since(p.drawn) > 200*time.Millisecond {
+		p.draw()
+		p.drawn = time.Now()
+	}
+	return len(b), nil
+}
+
+// Done draw the final progress line
+func (p *Progress) Done() {
+
+	if p.tty {
+		p.draw()
+		fmt.Println()
+	}
+}
+
+func (p *Progress) draw() {
+
+	rate := float64(p.n) / max(time.Since(p.start).Seconds(), 0.001)
+	line := p.Title + " " + fmtBytes(p.n)
+	if p.Total > 0 {
+		line += " of " + fmtBytes(p.Total) + " (" + strconv.FormatInt(min(100*p.n/p.Total, 100), 10) + "%)"
+	}
+	line += " " + fmtBytes(int64(rate)) + "/s"
+	if p.Total > p.n && rate > 0 {
+		eta := time.Duration(float64(p.Total-p.n)/rate) * time.Second
+		line += " ETA " + eta.Round(time.Second).String()
+	}
+	fmt.Print("\r\033[K" + line)
+}
+
+// fmtBytes return n as ex: 12.3 MiB
+func fmtBytes(n int64) string {
+
+	const unit = 1024
+	if n < unit {
+		return strconv.FormatInt(n, 10) + " B"
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// download url (http, https or ftp) to file with progress, through file.part so an interrupted download is not used
+func download(src string, file string, title string) error {
+
+	u, err := url.Parse(src)
+	if err != nil {
+		return fmt.Errorf("download() %w", err)
+	}
+
+	var body io.Reader
+	var total int64
+	switch u.Scheme {
+	case "http", "https":
+		resp, err := http.Get(src)
+		if err != nil {
+			return fmt.Errorf("download() %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("download() %s: %s", src, resp.Status)
+		}
+		body, total = resp.Body, resp.ContentLength
+	case "ftp":
+		c, err := ftp.Dial(u.Hostname()+":21", ftp.DialWithTimeout(5*time.Second))
+		if err != nil {
+			return fmt.Errorf("download() %w", err)
+		}
+		defer c.Quit()
+		if err = c.Login("anonymous", "anonymous"); err != nil {
+			return fmt.Errorf("download() %w", err)
+		}
+		total, _ = c.FileSize(u.Path)
+		r, err := c.Retr(u.Path)
+		if err != nil {
+			return fmt.Errorf("download() %w", err)
+		}
+		defer r.Close()
+		body = r
+	default:
+		_, err := system.Run("/usr/bin/fetch", []string{"-q", "-o", file, src})
+		return err
+	}
+
+	f, err := os.Create(file + ".part")
+	if err != nil {
+		return fmt.Errorf("download() %w", err)
+	}
+	p := newProgress(title, total)
+	_, err = io.Copy(io.MultiWriter(f, p), body)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	p.Done()
+	if err != nil {
+		os.Remove(file + ".part")
+		return fmt.Errorf("download() %s: %w", src, err)
+	}
+	return os.Rename(file+".part", file)
+}
+
+// untar extract a (compressed) tar file to dir with progress, of the bytes read from file
+func untar(file string, dir string) error {
+
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("untar() %w", err)
+	}
+	defer f.Close()
+
+	var total int64
+	if fi, err := f.Stat(); err == nil {
+		total = fi.Size()
+	}
+	p := newProgress("Unpack "+file+" to "+dir, total)
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(system.Tool("/usr/bin/tar"), "-xpf", "-", "-C", dir)
+	cmd.Stdin = io.TeeReader(f, p)
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+	p.Done()
+	if err != nil {
+		return fmt.Errorf("untar() tar failed with: %s", stderr.String())
+	}
+	return nil
+}
+
+// remoteConfig return the jmgr config of a remote host, 'jmgr config -json' via ssh
+func remoteConfig(remote string) (Jmgr, error) {
+
+	var rcfg Jmgr
+
+	b, err := system.Run("/usr/bin/ssh", []string{"-o", "BatchMode=yes", remote, "jmgr", "config", "-json"})
+	if err != nil {
+		return rcfg, fmt.Errorf("remoteConfig() %w", err)
+	}
+
+	err = json.Unmarshal(b, &rcfg)
+	if err != nil {
+		return rcfg, fmt.Errorf("remoteConfig() json: %w", err)
+	}
+	return rcfg, nil
+}
+
+// sshTar copy the contents of a remote directory to a local directory, tar over ssh
+func sshTar(remote string, from string, to string) error {
+
+	var stderr bytes.Buffer
+
+	Send := exec.Command(system.Tool("/usr/bin/ssh"), "-o", "BatchMode=yes", remote, "/usr/bin/tar", "-cf", "-", "-C", from, ".")
+	Recv := exec.Command(system.Tool("/usr/bin/tar"), "-xpf", "-", "-C", to)
+	Send.Stderr = &stderr
+	Recv.Stderr = &stderr
+
+	var err error
+	Recv.Stdin, err = Send.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("sshTar() Send.StdoutPipe(): %w", err)
+	}
+
+	err = Recv.Start()
+	if err != nil {
+		return fmt.Errorf("sshTar() Recv.Start(): %w", err)
+	}
+
+	err = Send.Run()
+	if err != nil {
+		return fmt.Errorf("sshTar() %s:%s failed with: %s", remote, from, stderr.String())
+	}
+
+	err = Recv.Wait()
+	if err != nil {
+		return fmt.Errorf("sshTar() tar failed with: %s", stderr.String())
+	}
+	return nil
+}
+
+// zfsSendSsh pipe 'zfs send' to 'zfs receive' on a remote host
+func zfsSendSsh(send []string, remote string, recv []string) error {
+
+	var sendErr, recvErr bytes.Buffer
+
+	Send := exec.Command(system.Tool("/sbin/zfs"), send...)
+	Recv := exec.Command(system.Tool("/usr/bin/ssh"), append([]string{"-o", "BatchMode=yes", remote, "/sbin/zfs"}, recv...)...)
+	Send.Stderr = &sendErr
+	Recv.Stderr = &recvErr
+
+	SendOut, err := Send.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("zfsSendSsh() Send.StdoutPipe(): %w", err)
+	}
+	p := newProgress("Send "+send[len(send)-1]+" to "+remote, zfs.SendSize(send))
+	Recv.Stdin = io.TeeReader(SendOut, p)
+
+	err = Recv.Start()
+	if err != nil {
+		return fmt.Errorf("zfsSendSsh() Recv.Start(): %w", err)
+	}
+
+	err = Send.Start()
+	if err != nil {
+		Recv.Process.Kill()
+		Recv.Wait()
+		return fmt.Errorf("zfsSendSsh() Send.Start(): %w", err)
+	}
+
+	// the receiver reads the sender output, wait for it first
+	recvWait := Recv.Wait()
+	if recvWait != nil {
+		Send.Process.Kill()
+	}
+	err = Send.Wait()
+	p.Done()
+	if err != nil && recvWait == nil {
+		return fmt.Errorf("zfsSendSsh() zfs %s failed with: %s", send, sendErr.String())
+	}
+	if recvWait != nil {
+		return fmt.Errorf("zfsSendSsh() %s zfs %s failed with: %s", remote, recv, recvErr.String())
+	}
+	return nil
+}
+
+// sshWriteFile write data to file on a remote host
+func sshWriteFile(remote string, file string, data []byte) error {
+
+	var stderr bytes.Buffer
+
+	cmd := exec.Command(system.Tool("/usr/bin/ssh"), "-o", "BatchMode=yes", remote,
+		"/bin/mkdir -p '"+filepath.Dir(file)+"' && /bin/cat > '"+file+"'")
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err != nil {
+		return fmt.Errorf("sshWriteFile() %s:%s failed with: %s", remote, file, stderr.String())
+	}
+	return nil
+}
+
+// standbyFenced check that the replica on the standby host is still a replica of this host and does not run,
+// after 'standby activate' it is the primary and a receive would overwrite it. No replica yet is ok
+func standbyFenced(name string, remote string, rcfg Jmgr, remoteDataset string) error {
+
+	ssh := func(args ...string) ([]byte, error) {
+		return system.Run("/usr/bin/ssh", append([]string{"-o", "BatchMode=yes", remote}, args...))
+	}
+	if _, err := ssh("/sbin/zfs", "list", "-H", "-o", "name", remoteDataset); err != nil {
+		return nil
+	}
+	hint := "If " + remote + " is the primary now, stop replicating " + name + " here: jmgr standby remove " + name
+	if _, err := ssh("/usr/sbin/jls", "-j", name, "jid"); err == nil {
+		return jmgrError(exitConflict, "replica of "+name+" runs on "+remote+", not replicated", hint)
+	}
+	b, err := ssh("/bin/cat", rcfg.JailMetaDir+"/"+name+".yml")
+	if err != nil {
+		return jmgrError(exitConflict, "no metadata for the replica of "+name+" on "+remote+", not replicated", hint)
+	}
+	var rmeta JailMeta
+	if err := yaml.Unmarshal(b, &rmeta); err != nil {
+		return fmt.Errorf("standbyFenced() %s: %w", remote, err)
+	}
+	primary, _ := os.Hostname()
+	if rmeta.StandbyOf != primary {
+		return jmgrError(exitConflict, "replica of "+name+" on "+remote+" is not a replica of "+primary+", activated?, not replicated", hint)
+	}
+	return nil
+}
+
+// standbyLag return time since last replication
+func standbyLag(synced string) string {
+
+	t, err := time.Parse(time.RFC3339, synced)
+	if err != nil {
+		return "N/A"
+	}
+	return time.Since(t).Round(time.Second).String()
+}
+
+// syncFile install file 'from' as 'to' and report what was done. Existing files that differ are only replaced if 'force'
+func syncFile(from string, to string, force bool, dryRun bool) (string, error) {
+
+	src, err := os.ReadFile(from)
+	if err != nil {
+		return "", fmt.Errorf("syncFile() %w", err)
+	}
+
+	status := "added"
+	dst, err := os.ReadFile(to)
+	if err == nil {
+		if bytes.Equal(src, dst) {
+			return "unchanged", nil
+		}
+		if !force {
+			return "differs, skipped (use -f)", nil
+		}
+		status = "updated"
+	}
+
+	if dryRun {
+		return "would be " + status, nil
+	}
+
+	info, err := os.Stat(from)
+	if err != nil {
+		return "", fmt.Errorf("syncFile() %w", err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(to), 0755)
+	if err != nil {
+		return "", fmt.Errorf("syncFile() %w", err)
+	}
+
+	err = os.WriteFile(to, src, info.Mode().Perm())
+	if err != nil {
+		return "", fmt.Errorf("syncFile() %w", err)
+	}
+	return status, nil
+}
+
+// remoteClone clone a ZFS jail to a remote jmgr host: zfs send over ssh, jail config and metadata from this host's template
+func remoteClone(host string, force bool, start bool, hostname string, template string, keepId bool, clearLogs bool, args []string) {
+
+	cfg, oldJail, err := verifyArgs(2, 0, true, true, args)
+	if err != nil {
+		fatal(err)
+	}
+	if len(oldJail.Dataset) == 0 {
+		fatal(jmgrError(exitConflict, "Jail "+oldJail.Name+" is not on ZFS, clone -host needs a ZFS dataset.", ""))
+	}
+
+	rcfg, err := remoteConfig(host)
+	if err != nil {
+		fatal(err)
+	}
+	if len(rcfg.ZFSdataSet) == 0 || !filepath.IsAbs(rcfg.JailsConfD) || !filepath.IsAbs(rcfg.JailMetaDir) {
+		fatal(jmgrError(exitConfig, "jmgr config on "+host+" is not ok or does not use ZFS.", "Run 'jmgr config' on "+host+" to see the problems reported."))
+	}
+	if rcfg.exist(args[1]) {
+		fatal(jmgrError(exitConflict, "Jail "+args[1]+" already exist on "+host+".", ""))
+	}
+
+	var newJail NewJail
+	newJail.Name = args[1]
+	newJail.Hostname = hostname
+	if len(newJail.Hostname) == 0 {
+		newJail.Hostname = newJail.Name
+	} else if !validHostname(newJail.Hostname) {
+		fatal(jmgrError(exitUsage, "Not a valid hostname: "+newJail.Hostname, ""))
+	}
+	newJail.Dataset = rcfg.ZFSdataSet + "/" + newJail.Name
+	newJail.ConfigPath = rcfg.JailsConfD + "/" + newJail.Name + ".conf"
+	newJail.Iface = rcfg.JailIface
+	newJail.Template = oldJail.Meta.Template
+	if len(template) > 0 {
+		newJail.Template = template
+	}
+	if _, err := cfg.templateFile(newJail.Template); err != nil {
+		fatal(err)
+	}
+
+	// IP from arg or DNS, else inherit
+	if len(args) > 2 {
+		if _, err := netip.ParseAddr(args[2]); err != nil {
+			fatal(jmgrError(exitUsage, "Not a valid IP address: "+args[2], ""))
+		}
+		newJail.IP = args[2]
+		if len(args) > 3 {
+			newJail.Iface = args[3]
+		}
+	} else if addrs, err := net.LookupHost(newJail.Hostname); err == nil {
+		newJail.IP = addrs[0]
+	} else {
+		newJail.InheritIP = true
+	}
+
+	fmt.Println("Jail Name:", newJail.Name, "on", host)
+	fmt.Println("Jail Hostname:", newJail.Hostname)
+	fmt.Println("Jail Dataset:", newJail.Dataset)
+	if newJail.InheritIP {
+		fmt.Println("Jail IP: Inherit host IP address")
+	} else {
+		fmt.Println("Jail IP:", newJail.IP)
+		fmt.Println("Jail Iface:", newJail.Iface)
+	}
+	if !force {
+		askExitOnNo("Clone this jail from " + oldJail.Name + " to " + host + " (yes/No)? ")
+	}
+
+	hookArgs := []string{oldJail.Name, oldJail.Path, newJail.Name}
+	err = runHook("PreClone", cfg.PreClone, hookArgs)
+	if err != nil {
+		fatal(err)
+	}
+	snap, err := zfs.Snapshot(oldJail.Dataset)
+	if err != nil {
+		fatal(fmt.Errorf("Clone, %w", err))
+	}
+	err = runHook("PostClone", cfg.PostClone, hookArgs)
+	if err != nil {
+		fatal(err)
+	}
+
+	fmt.Println("Clone " + snap + " to " + host + ":" + newJail.Dataset)
+	err = zfsSendSsh([]string{"send", snap}, host, []string{"receive", newJail.Dataset})
+	if err != nil {
+		fatal(err)
+	}
+	fmt.Println("/ Completed.")
+
+	ssh := func(command ...string) ([]byte, error) {
+		return system.Run("/usr/bin/ssh", append([]string{"-o", "BatchMode=yes", host}, command...))
+	}
+
+	// the received snapshot is not needed on the remote host
+	_, err = ssh("/sbin/zfs", "destroy", newJail.Dataset+"@"+strings.SplitN(snap, "@", 2)[1])
+	if err != nil {
+		fatal(err)
+	}
+
+	b, err := ssh("/sbin/zfs", "list", "-H", "-o", "mountpoint", newJail.Dataset)
+	if err != nil {
+		fatal(err)
+	}
+	newJail.Path = strings.TrimSpace(string(b))
+
+	conf, err := cfg.renderJailConfig(newJail)
+	if err != nil {
+		fatal(err)
+	}
+	err = sshWriteFile(host, newJail.ConfigPath, []byte(conf))
+	if err != nil {
+		fatal(err)
+	}
+
+	meta, err := yaml.Marshal(JailMeta{Template: newJail.Template, Tags: oldJail.Meta.Tags})
+	if err != nil {
+		fatal(err)
+	}
+	err = sshWriteFile(host, rcfg.JailMetaDir+"/"+newJail.Name+".yml", meta)
+	if err != nil {
+		fatal(err)
+	}
+
+	// the same steps as a local clone, on the remote host
+	if !keepId || clearLogs {
+		_, err = ssh("/bin/sh", "-c", shellQuote(identityScript(newJail.Path, newJail.Hostname, keepId, clearLogs)))
+		if err != nil {
+			fatal(err)
+		}
+	}
+
+	fmt.Println("Jail", newJail.Name, "created on", host+".")
+
+	if start {
+		_, err = ssh("jmgr", "start", newJail.Name)
+		if err != nil {
+			fatal(err)
+		}
+		fmt.Println("Jail", newJail.Name, "started on", host+".")
+	}
+}
+
+// bulkClone clone 'from jail' to 'count' new jails <name>1..<name>count, IP addresses from the JailIPPool
+func bulkClone(fset *flag.FlagSet, count int, force bool, hostname string, args []string) {
+
+	if len(args) != 2 {
+		help()
+	}
+
+	cfg, oldJail, err := verifyArgs(2, 0, true, true, args)
+	if err != nil {
+		fatal(err)
+	}
+
+	var names []string
+	for i := 1; i <= count; i++ {
+		name := args[1] + strconv.Itoa(i)
+		if cfg.exist(name) {
+			fatal(jmgrError(exitConflict, "Jail "+name+" already exist.", ""))
+		}
+		names = append(names, name)
+	}
+
+	if !force {
+		askExitOnNo("Clone " + oldJail.Name + " to " + strings.Join(names, ", ") + " (yes/No)? ")
+	}
+
+	// pass on all flags except -n, each clone runs without questions
+	var flags []string
+	fset.Visit(func(f *flag.Flag) {
+		if f.Name != "n" && f.Name != "f" && f.Name != "hostname" {
+			flags = append(flags, "-"+f.Name+"="+f.Value.String())
+		}
+	})
+	self, err := os.Executable()
+	if err != nil {
+		fatal(err)
+	}
+
+	// a clone that fails does not stop the others, the failures are reported at the end
+	type cloneResult struct {
+		Name  string `json:"name"`
+		IP    string `json:"ip,omitempty"`
+		Error string `json:"error,omitempty"`
+	}
+	var results []cloneResult
+	failed := 0
+	for i, name := range names {
+		cargs := append([]string{"clone", "-f"}, flags...)
+
+		// sequential hostnames, www.example.org -> www1.example.org
+		if len(hostname) > 0 {
+			host, domain, _ := strings.Cut(hostname, ".")
+			h := host + strconv.Itoa(i+1)
+			if len(domain) > 0 {
+				h += "." + domain
+			}
+			cargs = append(cargs, "-hostname", h)
+		}
+
+		cargs = append(cargs, oldJail.Name, name)
+
+		r := cloneResult{Name: name}
+		if len(cfg.JailIPPool) > 0 {
+			ip, err := cfg.nextFreeIP()
+			if err != nil {
+				r.Error = err.Error()
+				results = append(results, r)
+				failed++
+				continue
+			}
+			r.IP = ip
+			cargs = append(cargs, ip)
+		}
+
+		fmt.Println("---", name, "---")
+		if len(profile) > 0 {
+			cargs = append([]string{"-profile", profile}, cargs...)
+		}
+		cmd := exec.Command(self, cargs...)
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			r.Error = err.Error()
+			failed++
+		}
+		results = append(results, r)
+
+		// refresh, the new jail IP is now in use
+		*cfg = jmgrInit()
+	}
+
+	for _, r := range results {
+		if len(r.Error) > 0 {
+			fmt.Println(r.Name + ": failed, " + r.Error)
+		} else {
+			fmt.Println(r.Name + ": cloned " + r.IP)
+		}
+	}
+	if failed > 0 {
+		jsonResult = results
+		fatal(jmgrError(exitError, strconv.Itoa(failed)+" of "+strconv.Itoa(len(names))+" clones failed.", "See the output of each clone above."))
+	}
+	printJSON(results)
+}
+
+// cloneReport compare the source and the new jail: release, packages, enabled services and size
+func cloneReport(srcPath string, srcDataset string, newPath string, newDataset string) {
+
+	var rowsFmt string = "%s\t%s\t%s\t%s\n"
+
+	type facts struct {
+		release  string
+		pkgs     []string
+		services []string
+		size     string
+	}
+
+	gather := func(path string, dataset string) facts {
+		var f facts
+		f.release, _ = jailVersion(path)
+		if b, err := system.Run("/usr/sbin/pkg", []string{"-r", path, "query", "%n-%v"}); err == nil {
+			f.pkgs = strings.Fields(string(b))
+		}
+		f.services = enabledServices(path)
+		f.size = diskUsage(path, dataset)
+		return f
+	}
+
+	src := gather(srcPath, srcDataset)
+	dst := gather(newPath, newDataset)
+
+	match := func(ok bool) string {
+		if ok {
+			return "yes"
+		}
+		return "NO"
+	}
+
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, rowsFmt, "", "Source", "New", "Match")
+	fmt.Fprintf(w, rowsFmt, "Release", src.release, dst.release, match(src.release == dst.release))
+	fmt.Fprintf(w, rowsFmt, "Packages", strconv.Itoa(len(src.pkgs)), strconv.Itoa(len(dst.pkgs)), match(slices.Equal(src.pkgs, dst.pkgs)))
+	fmt.Fprintf(w, rowsFmt, "Services", strings.Join(src.services, " "), strings.Join(dst.services, " "), match(slices.Equal(src.services, dst.services)))
+	fmt.Fprintf(w, rowsFmt, "Size", src.size, dst.size, "")
+	w.Flush()
+
+	for _, p := range src.pkgs {
+		if !slices.Contains(dst.pkgs, p) {
+			fmt.Println("Package missing in new jail:", p)
+		}
+	}
+}
+
+// enabledServices return the services enabled in a jail rc.conf
+func enabledServices(jailPath string) []string {
+
+	var services []string
+
+	b, err := system.Run("/usr/sbin/sysrc", []string{"-f", jailPath + "/etc/rc.conf", "-a"})
+	if err != nil {
+		return services
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		key, value, ok := strings.Cut(line, ": ")
+		if ok && strings.HasSuffix(key, "_enable") && strings.EqualFold(strings.TrimSpace(value), "YES") {
+			services = append(services, strings.TrimSuffix(key, "_enable"))
+		}
+	}
+	slices.Sort(services)
+	return services
+}
+
+// diskUsage return the used space of a jail, dataset 'referenced' or du(1) for a directory
+func diskUsage(path string, dataset string) string {
+
+	if len(dataset) > 0 {
+		b, err := system.Run("/sbin/zfs", []string{"get", "-H", "-o", "value", "referenced", dataset})
+		if err == nil {
+			return string(bytes.TrimRight(b, "\n"))
+		}
+	}
+	b, err := system.Run("/usr/bin/du", []string{"-sh", path})
+	if err == nil {
+		if words := strings.Fields(string(b)); len(words) > 0 {
+			return words[0]
+		}
+	}
+	return "N/A"
+}
+
+// runHook run a configured hook script, an empty script name is no hook
+func runHook(hook string, script string, args []string) error {
+
+	if len(script) == 0 {
+		return nil
+	}
+
+	fmt.Println("Running " + hook + " script:" + script)
+	p, err := os.Stat(script)
+	if err != nil {
+		return fmt.Errorf("error with %s %s", script, err.Error())
+	}
+
+	pMode := p.Mode()
+	if !pMode.IsRegular() || (pMode.Perm()&0111) == 0 {
+		return fmt.Errorf("%s script: %s is not a file and/or not executable", hook, script)
+	}
+
+	// the script path as configured, not a tool
+	cmd := exec.Command(script, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Stdin = os.Stdin
+	err = cmd.Run()
+	if err != nil {
+		return fmt.Errorf("script %s finished with error: %s", script, err.Error())
+	}
+	fmt.Println(hook + " script completed.")
+	return nil
+}
+
+// rsync copy directory 'from' to 'to' with rsync, only changes are copied on a second run
+func rsync(from string, to string) error {
+
+	s := spinner.StartNew("rsync " + from + " to " + to)
+	_, err := system.Run("/usr/local/bin/rsync", []string{"-aH", "--numeric-ids", "--delete", from + "/", to + "/"})
+	s.Stop()
+	if err != nil {
+		return fmt.Errorf("rsync() %w", err)
+	}
+	fmt.Println("/ Completed.")
+	return nil
+}
+
+// resetIdentity give a cloned jail filesystem its own identity: hostname in rc.conf, SSH host keys, hostid and machine-id
+func resetIdentity(jailPath string, hostname string, keepId bool, clearLogs bool) error {
+
+	fmt.Println("Reset identity in " + jailPath)
+
+	_, err := system.Run("/bin/sh", []string{"-c", identityScript(jailPath, hostname, keepId, clearLogs)})
+	if err != nil {
+		return fmt.Errorf("resetIdentity() %w", err)
+	}
+	return nil
+}
+
+// identityScript the sh(1) script of resetIdentity(), clone -host runs the same script on the remote host
+func identityScript(jailPath string, hostname string, keepId bool, clearLogs bool) string {
+
+	p := shellQuote(jailPath)
+	var steps []string
+
+	if !keepId {
+		steps = append(steps,
+			// rc.conf hostname, only if the source jail has one
+			"if /usr/sbin/sysrc -f "+p+"/etc/rc.conf -n hostname >/dev/null 2>&1; then /usr/sbin/sysrc -f "+p+"/etc/rc.conf hostname="+shellQuote(hostname)+" >/dev/null || exit 1; fi",
+			// SSH host keys, regenerate the keys the source jail had
+			"if ls "+p+"/etc/ssh/ssh_host_*key* >/dev/null 2>&1; then /bin/rm -f "+p+"/etc/ssh/ssh_host_*key* && /usr/bin/ssh-keygen -A -f "+p+" >/dev/null || exit 1; fi",
+			// hostid and machine-id
+			"U=$(/bin/uuidgen) || exit 1",
+			"if [ -f "+p+"/etc/hostid ]; then echo $U > "+p+"/etc/hostid || exit 1; fi",
+			"if [ -f "+p+"/etc/machine-id ]; then echo $U | /usr/bin/tr -d - > "+p+"/etc/machine-id || exit 1; fi",
+		)
+	}
+	if clearLogs {
+		// remove the rotated logs, truncate the others
+		steps = append(steps,
+			"if [ -d "+p+"/var/log ]; then /usr/bin/find -E "+p+"/var/log -type f -regex '.*\\.[0-9]+(\\.(bz2|gz|xz|zst))?$' -delete || exit 1; /usr/bin/find "+p+"/var/log -type f -exec /usr/bin/truncate -s 0 {} + || exit 1; fi",
+		)
+	}
+	return strings.Join(steps, "; ")
+}
+
+// shellQuote quote a string for sh(1)
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+//
+// OCI image and registry helpers
+//
+
+// OCI media types
+const (
+	ociManifest = "application/vnd.oci.image.manifest.v1+json"
+	ociConfig   = "application/vnd.oci.image.config.v1+json"
+	ociLayer    = "application/vnd.oci.image.layer.v1.tar+gzip"
+)
+
+// OCI content descriptor
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// OCI image manifest
+type ociImageManifest struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType"`
+	Config        ociDescriptor     `json:"config"`
+	Layers        []ociDescriptor   `json:"layers"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+// OCI image config, only the parts jmgr use
+type ociImageConfig struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+	Created      string `json:"created"`
+	Config       struct {
+		Labels map[string]string `json:"Labels"`
+	} `json:"config"`
+	RootFS struct {
+		Type    string   `json:"type"`
+		DiffIDs []string `json:"diff_ids"`
+	} `json:"rootfs"`
+}
+
+// a jail image layer, a gzip tar file of a part of the jail filesystem
+type imageLayer struct {
+	Name   string // layer group: base, config or local
+	File   string // gzip tar file
+	Digest string // sha256 of File
+	DiffID string // sha256 of the uncompressed tar
+	Size   int64
+}
+
+// image reference, registry/repository:tag
+type imageRef struct {
+	Scheme     string
+	Registry   string
+	Repository string
+	Tag        string
+	token      string
+}
+
+// parseImageRef parse 'registry.example.com/base-web:14.2', tag defaults to latest
+func parseImageRef(ref string, insecure bool) (*imageRef, error) {
+
+	r := &imageRef{Scheme: "https", Tag: "latest"}
+	if insecure {
+		r.Scheme = "http"
+	}
+
+	registry, repo, ok := strings.Cut(ref, "/")
+	if !ok || !strings.ContainsAny(registry, ".:") || len(repo) == 0 {
+		return nil, fmt.Errorf("not a valid image reference: %s, use registry.example.com/name:tag", ref)
+	}
+	r.Registry = registry
+
+	if i := strings.LastIndex(repo, ":"); i > 0 {
+		r.Tag = repo[i+1:]
+		repo = repo[:i]
+	}
+	r.Repository = repo
+
+	if !regexp.MustCompile(`^[a-z0-9]+([._/-][a-z0-9]+)*$`).MatchString(r.Repository) {
+		return nil, fmt.Errorf("not a valid image repository: %s", r.Repository)
+	}
+	if !regexp.MustCompile(`^[\w][\w.-]{0,127}$`).MatchString(r.Tag) {
+		return nil, fmt.Errorf("not a valid image tag: %s", r.Tag)
+	}
+	return r, nil
+}
+
+// String return the reference as registry/repository:tag
+func (r *imageRef) String() string {
+
+	return r.Registry + "/" + r.Repository + ":" + r.Tag
+}
+
+// url return the registry API URL for path, ex: blobs/uploads/
+func (r *imageRef) url(path string) string {
+
+	return r.Scheme + "://" + r.Registry + "/v2/" + r.Repository + "/" + path
+}
+
+// do send a registry request, authenticate and retry once on a 401 challenge. body may be nil
+func (r *imageRef) do(method string, url string, contentType string, body func() (io.ReadCloser, int64, error)) (*http.Response, error) {
+
+	for attempt := 0; ; attempt++ {
+
+		var reader io.ReadCloser
+		var size int64
+		if body != nil {
+			var err error
+			reader, size, err = body()
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		req, err := http.NewRequest(method, url, reader)
+		if err != nil {
+			return nil, err
+		}
+		if body != nil {
+			req.ContentLength = size
+		}
+		if len(contentType) > 0 {
+			req.Header.Set("Content-Type", contentType)
+		}
+		req.Header.Set("Accept", ociManifest)
+
+		user, pass := os.Getenv("JMGR_REGISTRY_USER"), os.Getenv("JMGR_REGISTRY_PASSWORD")
+		if len(r.token) > 0 {
+			req.Header.Set("Authorization", "Bearer "+r.token)
+		} else if len(user) > 0 {
+			req.SetBasicAuth(user, pass)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode != http.StatusUnauthorized || attempt > 0 {
+			return resp, nil
+		}
+		challenge := resp.Header.Get("Www-Authenticate")
+		resp.Body.Close()
+
+		if !strings.HasPrefix(challenge, "Bearer ") {
+			return nil, fmt.Errorf("registry %s: authentication required, set JMGR_REGISTRY_USER and JMGR_REGISTRY_PASSWORD", r.Registry)
+		}
+		err = r.login(challenge, user, pass)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// login get a bearer token from the token service in a 'Www-Authenticate: Bearer realm=..,service=..,scope=..' challenge
+func (r *imageRef) login(challenge string, user string, pass string) error {
+
+	params := make(map[string]string)
+	for _, m := range regexp.MustCompile(`(\w+)="([^"]*)"`).FindAllStringSubmatch(challenge, -1) {
+		params[m[1]] = m[2]
+	}
+
+	u, err := url.Parse(params["realm"])
+	if err != nil || len(params["realm"]) == 0 {
+		return fmt.Errorf("registry %s: bad auth challenge: %s", r.Registry, challenge)
+	}
+	q := u.Query()
+	if len(params["service"]) > 0 {
+		q.Set("service", params["service"])
+	}
+	q.Set("scope", "repository:"+r.Repository+":pull,push")
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return err
+	}
+	if len(user) > 0 {
+		req.SetBasicAuth(user, pass)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("registry %s: login failed: %s", r.Registry, resp.Status)
+	}
+
+	var t struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&t)
+	if err != nil {
+		return fmt.Errorf("registry %s: login: %w", r.Registry, err)
+	}
+	r.token = t.Token
+	if len(r.token) == 0 {
+		r.token = t.AccessToken
+	}
+	return nil
+}
+
+// pushBlob upload a blob unless the registry already has it
+func (r *imageRef) pushBlob(digest string, size int64, open func() (io.ReadCloser, int64, error)) error {
+
+	resp, err := r.do("HEAD", r.url("blobs/"+digest), "", nil)
+	if err != nil {
+		return fmt.Errorf("pushBlob() %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		fmt.Println("Blob", digest, "exists.")
+		return nil
+	}
+
+	resp, err = r.do("POST", r.url("blobs/uploads/"), "", nil)
+	if err != nil {
+		return fmt.Errorf("pushBlob() %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("pushBlob() start upload: %s", resp.Status)
+	}
+
+	loc, err := resp.Location()
+	if err != nil {
+		return fmt.Errorf("pushBlob() %w", err)
+	}
+	q := loc.Query()
+	q.Set("digest", digest)
+	loc.RawQuery = q.Encode()
+
+	s := spinner.StartNew("Upload " + digest + " " + strconv.FormatInt(size, 10) + " bytes")
+	resp, err = r.do("PUT", loc.String(), "application/octet-stream", open)
+	s.Stop()
+	if err != nil {
+		return fmt.Errorf("pushBlob() %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("pushBlob() upload %s: %s", digest, resp.Status)
+	}
+	fmt.Println("/ Uploaded", digest)
+	return nil
+}
+
+// push upload layers, config and the manifest
+func (r *imageRef) push(layers []imageLayer, config []byte) error {
+
+	manifest := ociImageManifest{
+		SchemaVersion: 2,
+		MediaType:     ociManifest,
+		Annotations:   map[string]string{"org.opencontainers.image.created": time.Now().UTC().Format(time.RFC3339)},
+	}
+
+	for _, l := range layers {
+		file := l.File
+		err := r.pushBlob(l.Digest, l.Size, func() (io.ReadCloser, int64, error) {
+			f, err := os.Open(file)
+			if err != nil {
+				return nil, 0, err
+			}
+			st, err := f.Stat()
+			if err != nil {
+				f.Close()
+				return nil, 0, err
+			}
+			return f, st.Size(), nil
+		})
+		if err != nil {
+			return err
+		}
+		manifest.Layers = append(manifest.Layers, ociDescriptor{MediaType: ociLayer, Digest: l.Digest, Size: l.Size,
+			Annotations: map[string]string{"org.jmgr.layer": l.Name}})
+	}
+
+	configDigest := fmt.Sprintf("sha256:%x", sha256.Sum256(config))
+	err := r.pushBlob(configDigest, int64(len(config)), func() (io.ReadCloser, int64, error) {
+		return io.NopCloser(bytes.NewReader(config)), int64(len(config)), nil
+	})
+	if err != nil {
+		return err
+	}
+	manifest.Config = ociDescriptor{MediaType: ociConfig, Digest: configDigest, Size: int64(len(config))}
+
+	b, err := json.Marshal(manifest)
+	if err != nil {
+		return fmt.Errorf("push() %w", err)
+	}
+	resp, err := r.do("PUT", r.url("manifests/"+r.Tag), ociManifest, func() (io.ReadCloser, int64, error) {
+		return io.NopCloser(bytes.NewReader(b)), int64(len(b)), nil
+	})
+	if err != nil {
+		return fmt.Errorf("push() %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("push() manifest: %s", resp.Status)
+	}
+	return nil
+}
+
+// pull download manifest, config and the layers not already in the local store, layers are verified against their digest
+func (r *imageRef) pull(store string) (ociImageManifest, ociImageConfig, error) {
+
+	var manifest ociImageManifest
+	var config ociImageConfig
+
+	resp, err := r.do("GET", r.url("manifests/"+r.Tag), "", nil)
+	if err != nil {
+		return manifest, config, fmt.Errorf("pull() %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return manifest, config, fmt.Errorf("pull() manifest %s: %s", r.String(), resp.Status)
+	}
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return manifest, config, fmt.Errorf("pull() %w", err)
+	}
+	err = json.Unmarshal(b, &manifest)
+	if err != nil {
+		return manifest, config, fmt.Errorf("pull() manifest: %w", err)
+	}
+	if manifest.MediaType != ociManifest || len(manifest.Layers) == 0 {
+		return manifest, config, fmt.Errorf("pull() %s is not a jmgr image", r.String())
+	}
+
+	var fetched, reused int64
+	for _, d := range append([]ociDescriptor{manifest.Config}, manifest.Layers...) {
+		ok, err := r.fetchBlob(store, d)
+		if err != nil {
+			return manifest, config, err
+		}
+		if ok {
+			fetched += d.Size
+		} else {
+			reused += d.Size
+		}
+	}
+	fmt.Println("Downloaded", fetched, "bytes,", reused, "bytes already in the local image store.")
+
+	b, err = os.ReadFile(blobFile(store, manifest.Config.Digest))
+	if err != nil {
+		return manifest, config, fmt.Errorf("pull() %w", err)
+	}
+	err = json.Unmarshal(b, &config)
+	if err != nil {
+		return manifest, config, fmt.Errorf("pull() config: %w", err)
+	}
+
+	// keep the manifest, the image is available as registry/repository/tag.json
+	b, _ = json.Marshal(manifest)
+	mfile := filepath.Join(store, "manifests", r.Registry, r.Repository, r.Tag+".json")
+	if err := os.MkdirAll(filepath.Dir(mfile), 0755); err != nil {
+		return manifest, config, fmt.Errorf("pull() %w", err)
+	}
+	if err := os.WriteFile(mfile, b, 0644); err != nil {
+		return manifest, config, fmt.Errorf("pull() %w", err)
+	}
+	return manifest, config, nil
+}
+
+// fetchBlob download a blob to the store unless already there, returns true if downloaded
+func (r *imageRef) fetchBlob(store string, d ociDescriptor) (bool, error) {
+
+	if !strings.HasPrefix(d.Digest, "sha256:") {
+		return false, fmt.Errorf("fetchBlob() unsupported digest: %s", d.Digest)
+	}
+
+	// a blob in the store is reused only if its digest matches, a corrupt or truncated one is downloaded again
+	file := blobFile(store, d.Digest)
+	if st, err := os.Stat(file); err == nil && st.Size() == d.Size {
+		digest, err := fileDigest(file)
+		if err == nil && digest == d.Digest {
+			return false, nil
+		}
+		fmt.Println("Blob", d.Digest, "in the local image store is corrupt, download it again.")
+	}
+
+	err := os.MkdirAll(filepath.Dir(file), 0755)
+	if err != nil {
+		return false, fmt.Errorf("fetchBlob() %w", err)
+	}
+
+	resp, err := r.do("GET", r.url("blobs/"+d.Digest), "", nil)
+	if err != nil {
+		return false, fmt.Errorf("fetchBlob() %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("fetchBlob() %s: %s", d.Digest, resp.Status)
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(file), "download-")
+	if err != nil {
+		return false, fmt.Errorf("fetchBlob() %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	s := spinner.StartNew("Download " + d.Digest + " " + strconv.FormatInt(d.Size, 10) + " bytes")
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(f, h), resp.Body)
+	f.Close()
+	s.Stop()
+	if err != nil {
+		return false, fmt.Errorf("fetchBlob() %w", err)
+	}
+	if fmt.Sprintf("sha256:%x", h.Sum(nil)) != d.Digest {
+		return false, fmt.Errorf("fetchBlob() %s: digest mismatch", d.Digest)
+	}
+	fmt.Println("/ Downloaded", d.Digest)
+
+	err = os.Rename(f.Name(), file)
+	if err != nil {
+		return false, fmt.Errorf("fetchBlob() %w", err)
+	}
+	return true, nil
+}
+
+// fileDigest return the sha256 digest of a file, as sha256:<hex>
+func fileDigest(file string) (string, error) {
+
+	f, err := os.Open(file)
+	if err != nil {
+		return "", fmt.Errorf("fileDigest() %w", err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("fileDigest() %w", err)
+	}
+	return fmt.Sprintf("sha256:%x", h.Sum(nil)), nil
+}
+
+// blobFile return the file name of a blob in the local image store
+func blobFile(store string, digest string) string {
+
+	return store + "/blobs/" + strings.Replace(digest, ":", "/", 1)
+}
+
+// layerGroups split the top of a jail filesystem in layer groups that change at different pace: base system, config and packages
+func layerGroups(root string) (map[string][]string, error) {
+
+	groups := map[string][]string{"base": {}, "config": {}, "local": {}}
+
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, fmt.Errorf("layerGroups() %w", err)
+	}
+	for _, e := range entries {
+		switch e.Name() {
+		case "etc", "root", "var", "home":
+			groups["config"] = append(groups["config"], e.Name())
+		case "usr":
+			usr, err := os.ReadDir(filepath.Join(root, "usr"))
+			if err != nil {
+				return nil, fmt.Errorf("layerGroups() %w", err)
+			}
+			for _, u := range usr {
+				if u.Name() == "local" {
+					groups["local"] = append(groups["local"], "usr/local")
+				} else {
+					groups["base"] = append(groups["base"], "usr/"+u.Name())
+				}
+			}
+		case ".zfs":
+		default:
+			groups["base"] = append(groups["base"], e.Name())
+		}
+	}
+	return groups, nil
+}
+
+// buildLayers create one gzip tar layer per layer group in dir, gzip without timestamps so unchanged layers get the same digest
+func buildLayers(root string, dir string) ([]imageLayer, error) {
+
+	var layers []imageLayer
+
+	groups, err := layerGroups(root)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range []string{"base", "config", "local"} {
+		if len(groups[name]) == 0 {
+			continue
+		}
+		slices.Sort(groups[name])
+
+		f, err := os.CreateTemp(dir, "jmgr-layer-")
+		if err != nil {
+			return layers, fmt.Errorf("buildLayers() %w", err)
+		}
+		layer := imageLayer{Name: name, File: f.Name()}
+		layers = append(layers, layer)
+
+		gzHash := sha256.New()
+		tarHash := sha256.New()
+		gz := gzip.NewWriter(io.MultiWriter(f, gzHash))
+
+		s := spinner.StartNew("Export layer " + name + ": " + strings.Join(groups[name], " "))
+		cmd := exec.Command(system.Tool("/usr/bin/tar"), append([]string{"-cf", "-", "-C", root}, groups[name]...)...)
+		var stderr bytes.Buffer
+		cmd.Stdout = io.MultiWriter(gz, tarHash)
+		cmd.Stderr = &stderr
+		err = cmd.Run()
+		s.Stop()
+		if err != nil {
+			f.Close()
+			return layers, fmt.Errorf("buildLayers() tar failed with: %s", stderr.String())
+		}
+		if err := gz.Close(); err != nil {
+			f.Close()
+			return layers, fmt.Errorf("buildLayers() %w", err)
+		}
+		st, err := f.Stat()
+		f.Close()
+		if err != nil {
+			return layers, fmt.Errorf("buildLayers() %w", err)
+		}
+
+		layer.Digest = fmt.Sprintf("sha256:%x", gzHash.Sum(nil))
+		layer.DiffID = fmt.Sprintf("sha256:%x", tarHash.Sum(nil))
+		layer.Size = st.Size()
+		layers[len(layers)-1] = layer
+		fmt.Println("/ Layer", name, layer.Digest, layer.Size, "bytes")
+	}
+	return layers, nil
+}
+
+// imageConfig create the OCI image config for a jail, jmgr details as labels
+func imageConfig(cfg *Jmgr, jail *Jail, layers []imageLayer) ([]byte, error) {
+
+	var c ociImageConfig
+
+	hw, err := machine()
+	if err != nil {
+		return nil, err
+	}
+	c.Architecture = hw
+	c.OS = "freebsd"
+	c.Created = time.Now().UTC().Format(time.RFC3339)
+	c.RootFS.Type = "layers"
+	for _, l := range layers {
+		c.RootFS.DiffIDs = append(c.RootFS.DiffIDs, l.DiffID)
+	}
+
+	c.Config.Labels = map[string]string{
+		"org.jmgr.name":      jail.Name,
+		"org.jmgr.osversion": jail.OsVersion,
+	}
+	if len(jail.Meta.Template) > 0 {
+		c.Config.Labels["org.jmgr.template"] = jail.Meta.Template
+	}
+	if templateFile, err := cfg.templateFile(jail.Meta.Template); err == nil {
+		if b, err := os.ReadFile(templateFile); err == nil {
+			c.Config.Labels["org.jmgr.jailconf"] = string(b)
+		}
+	}
+
+	b, err := json.Marshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("imageConfig() %w", err)
+	}
+	return b, nil
+}
+
+// Help page
+func help() {
+
+	var string = ` jmgr help
+
+ Syntax: jmgr [-json] [-json-errors] [-y] [-profile 'name'] [-socket 'path'] [ subcommand ] [options] [ arguments.. ] | [ jail name ]
+         jmgr help 'subcommand' | jmgr 'subcommand' -h
+         jmgr completion bash|zsh|fish
+         jmgr gen-man
+  
+ View:
+  config [-json]			
+  config -check
+  config migrate [-n]
+  config diff
+  config get 'key'
+  config set 'key' 'value'
+  jails [-q] [-watch 'seconds'] [-wide | -width 'n'] [-no-pager] [-eol-only] [-format 'Go template'] [-o csv|yaml] [-columns 'jid,name,...'] [filters] [-sort name|jid|used]
+  runs [-q] [-watch 'seconds'] [-wide | -width 'n'] [-no-pager] [-eol-only] [-format 'Go template'] [-o csv|yaml] [-columns 'jid,name,...'] [filters] [-sort name|jid|used]
+   filters: [-state running|stopped] [-boot yes|no] [-release 'FreeBSD Release'] [-name 'glob'] [-tag 'tag']
+  jail [-format 'Go template'] 'jail name'
+  check -jail 'jail name' [-expect running|stopped] [-warn-snapshot-age 'age'] [-max-snapshot-age 'age'] [-warn-pending-vulns 'n'] [-max-pending-vulns 'n']
+  status [-updates] [-snapshot-age 'age']
+  stats [-sort name|cpu|mem|swap|procs|files|read|write] [-tag 'tag'] [-history 'age'] ['jail name' 'jail name2' ... ]
+  top [-interval 'duration'] [-sort name|cpu|mem|swap|procs|files] [-tag 'tag']
+  ps [-s] 'jail name'
+  ps -all [-s] [-pid 'pid']
+  sockets [-l | -c] 'jail name'
+  health 'jail name' ['jail name2' ...] | -all
+  health -cmd 'command' | -tcp '[address:]port' | -http 'url|[port]/path' [-interval 'duration'] [-timeout 'duration'] 'jail name'
+  health -d 'jail name'
+  'jail name'	
+										
+ Setup:
+  init [-f] [-n] [-dataset 'ZFS dataset' [-mountpoint 'directory'] | -home 'directory'] [-iface 'interface name']
+
+ Create/Backup:
+  create [-f] [-v 'FreeBSD Release'] [-hostname 'host name'] [-t 'template'] [-pkgcache] [-repo 'repo,repo2'] 'jail name' [ 'IP address' [ 'interface name' ] ]
+  create -l 
+  create [-f] [-insecure] -image 'registry/name:tag' [create options] 'jail name' [ 'IP address' [ 'interface name' ] ]
+  snapshot 'jail name' ['glob' ... ]
+  snapshot [-q] '@tag' ['label']
+  snapshot [-q] -tag 'tag' ['label']
+
+  promote 'jail name'
+
+ Publish:
+  publish [-f] [-insecure] 'jail name' 'registry/name:tag'
+  pull [-insecure] 'registry/name:tag'
+
+ Clone:
+  clone [-f] [-hostname 'host name'] [-t 'template'] [-keepid] [-clearlogs] [-live] [-thin] [-pool 'ZFS dataset' | -dest 'directory'] 'from jail name' 'new jail name' [ 'new jail IP address' [ 'new jail interface' ] ]
+  clone -n 'count' [clone options] 'from jail name' 'new jail name prefix'
+  clone -host 'user@host' [-f] [-start] [-hostname 'host name'] [-t 'template'] [-keepid] [-clearlogs] 'from jail name' 'new jail name' [ 'IP address' [ 'interface' ] ]
+
+ Jails admin:  			
+  enter 'jail name' [ 'user name' ]
+  exec [-u 'user name'] 'jail name' [--] 'command' [ 'argument' ... ]
+  exec [-f] [-u 'user name'] [-parallel 'n'] -all | -tag 'tag' | 'glob' | '@tag' [--] 'command' [ 'argument' ... ]
+  start [-wait | -wait-tcp '[address:]port' | -wait-cmd 'command'] [-timeout 'duration'] [-all | -tag 'tag'] ['jail name' 'glob' '@tag' ... ] 
+  stop [-timeout 'duration' [-kill]] [-all | -tag 'tag'] ['jail name' 'glob' '@tag' ... ] 
+  restart [-timeout 'duration' [-kill]] [-all | -tag 'tag'] ['jail name' 'glob' '@tag' ... ] 
+  enable [-before 'jail name' | -after 'jail name'] [-parallel yes|no] [-reverse-stop yes|no] 'jail name'
+  enable -order | -parallel yes|no | -reverse-stop yes|no
+  disable 'jail name'
+  tag [-d] 'jail name' ['tag' 'tag2' ... ]
+  depend [-d] 'jail name' ['jail name it depends on' ... ]
+  pkg [-f] 'jail name' install|delete|search|info|query|... [pkg arguments ...]
+  audit [-start] [-json] ['jail name' 'jail name2' ... ]
+  pkgcache [-d] 'jail name'
+  repo [-d] 'jail name' ['repository' 'repository2' ... ]
+  verify [-etc] 'jail name'
+  hold [-d] 'jail name'
+  window [-d] 'jail name' ['daily|Mon,Tue.. HH:MM-HH:MM']
+  protect 'jail name'
+  unprotect 'jail name'
+  describe [-d] 'jail name' ['description']
+  limits 'jail name' [show]
+  limits 'jail name' set 'resource=amount' ['resource:action=amount' ...]
+  limits 'jail name' unset 'resource' ['resource:action' ...]
+  cpuset [-l 'cpu list' | -d] 'jail name'
+  nice [-d] 'jail name' ['niceness -20..20']
+  lifecycle [-restart no|on-failure|always] [-start-delay 'duration'] [-stop-timeout 'duration'] 'jail name'
+  lifecycle -d 'jail name'
+
+ Destroy:	
+  destroy [-f] [-r] [-n] [-archive 'directory'] [-with-children] [-tag 'tag'] ['jail name' 'glob' '@tag' ... ]	
+  destroy -stopped [-f] [-r] [-n] -all | -tag 'tag' | 'jail name' 'glob' '@tag' ...
+  destroy -keep-data [-suffix 'suffix'] [-f] [-n] [-with-children] 'jail name' ...
+  destroy [-f] [-n] [-older-than 'age'] ['snapshot name' 'jail@glob' ... ]	
+  destroy [-f] [-n] -older-than 'age' 'jail name' ...
+  gc [-f] [-n]
+  adopt ['jail name']
+
+ Update os, Upgrade pkgs, Upgrade os release:
+  update [-f] patch 'jail name'
+  update [-f] base 'jail name'
+  update [-f] pkgs 'jail name'
+  update [-f] -all|-tag 'tag' [-parallel 'n'] patch|base|pkgs
+  update [-f] [-parallel 'n'] patch|base|pkgs 'glob'|'@tag'
+  update [-f] -all|-tag 'tag' [-parallel 'n'] [-v 'FreeBSD Release'] rel
+  update [-f] [-parallel 'n'] [-v 'FreeBSD Release'] rel 'glob'|'@tag'
+  update [-parallel 'n'] check ['jail name' 'glob' '@tag' ... ]
+  update [-f] clean
+  update [-v 'FreeBSD Release'] rel 'jail name'
+  update [-f] -resume|-abort rel 'jail name'
+  update -l
+
+ Rollback:
+  rollback 'jail name' 'latest snapshot name'
+  rollback [-f] [-r] '@tag' 'label'
+
+ Declarative:
+  apply [-f] [-n] [-json] -manifest 'jails.yml'
+  fleet apply [-f] [-n] [-parallel 'n'] -hosts 'hosts.yml' -manifest 'jails.yml'
+
+ Standby:
+  sync-definitions [-f] [-n] 'user@host'
+  standby setup [-f] 'user@host' 'jail name' ['jail name2' ... ]
+  standby run [-interval 'duration'] ['jail name' ... ]
+  standby status
+  standby remove 'jail name' ['jail name2' ... ]
+  standby activate [-f] 'jail name'
+
+ Daemon:
+  service list
+  service install [-f] [-enable] [-args 'options'] jmgrd|jmgr_serve
+  service remove jmgrd|jmgr_serve
+  daemon [-socket 'path'] [-refresh 'seconds'] [-metrics 'address:port'] [-updates 'interval'] [-alerts 'interval'] [-history 'interval'] [-supervise 'interval'] [-grpc 'path']
+  serve [-listen 'address:port'] -cert 'file' -key 'file' [-tokens 'file'] [-refresh 'seconds'] [-updates 'interval'] [-alerts 'interval']
+
+ Plugins:
+  'plugin' [ arguments.. ]	runs jmgr-'plugin' from PATH, the JSON inventory on stdin and the config file in env JMGR_CONFIG
+
+Options:
+  -f 		Assume 'yes' on all questions. 
+  -json		Print output in JSON format. Before the subcommand: {"result": ..., "error": "..."} on stdout for any subcommand
+  -json-errors	Before the subcommand: print an error as {"code": ..., "message": "...", "hint": "..."} on stderr, the code is the exit status
+  -profile	Before the subcommand: use the settings of a config profile, same as env JMGR_PROFILE
+  -socket	Before the subcommand: use the inventory of 'jmgr daemon' on this socket, it runs start, stop, restart, create and snapshot. Same as env JMGR_SOCKET
+  -refresh	Seconds between the inventory refreshes of 'jmgr daemon' and 'jmgr serve'
+  -metrics	Serve Prometheus metrics on http://address:port/metrics from 'jmgr daemon'
+  -updates	Interval of the pending updates check for the metrics, ex: 6h, 0 disables it
+  -alerts	Interval of the Alerts check of 'jmgr daemon' and 'jmgr serve', ex: 1m, 0 disables it
+  -history	Interval of the usage samples of 'jmgr daemon', ex: 1m, 0 disables them. Stats min, avg and max over an age, ex: 24h or 7d
+  -supervise	Interval of the 'jmgr daemon' check of the jails with a Restart policy, ex: 10s, 0 disables it
+  -y		Before the subcommand: assume 'yes' on all questions, same as env JMGR_ASSUME_YES=1. Without it questions fail if stdin is not a terminal
+  -r 		Destroy jail[s] including their snapshots, group rollback to a snapshot that is not the latest
+  -all		Start or Stop all jails, update patch or pkgs on all jails, ps of all jails, health of all jails with a healthcheck.
+  -pid		Only the process with the pid, ps -all -pid 'pid' finds the jail of a process
+  -s		Summarize the ps processes by command
+  -l, -c	Only the listening or the connected sockets
+  -l 		Provides a list of avaliable 'FreeBSD Releases'
+  -v		Define desired version of 'FreeBSD Release'
+  -n		Dry run, only report what would be done
+  -interval	Repeat with this interval, ex: 5m, the top refresh interval, default 2s, the healthcheck interval, default 30s
+  -cmd		Healthcheck command run in the jail, healthy if it exits 0
+  -tcp		Healthcheck TCP port on the jail IP, or address:port
+  -http		Healthcheck URL, or [port]/path on the jail IP, healthy on a 2xx or 3xx reply
+  -timeout	Timeout of a healthcheck, default 5s, of stop and restart, default wait, of start -wait, default 1m
+  -wait		Start: wait until the healthcheck of the jail passes, exit status 8 after -timeout
+  -wait-tcp	Start: wait until the TCP port, or address:port, of the jail accepts a connection
+  -wait-cmd	Start: wait until the command run in the jail exits 0
+  -kill		Stop and restart: after -timeout, default 30s, kill the processes left in the jail, jail(8) then finishes the stop
+  -q		Run the PreSnapshot/PostSnapshot scripts around a group snapshot
+  -d		Remove
+  -hostname	Jail hostname, default is the jail name
+  -t		Named jail.conf template in the template directory
+  -keepid	Keep the source jail identity in a cloned jail
+  -clearlogs	Clear the log files in a cloned jail
+  -live		Clone a running non-ZFS jail without stopping it (rsync)
+  -pool		ZFS dataset where a cloned jail dataset is created, ex: ssd/jails
+  -dest		Directory where a cloned jail is created (no ZFS), ex: /ssd/jails
+  -insecure	Use http instead of https to the image registry
+  -thin		Thin clone with 'zfs clone', see promote
+  -n		Bulk clone 'count' jails, IP addresses from JailIPPool
+  -host		Clone to a remote jmgr host, user@host
+  -start	Start the jail on the remote host after clone, start stopped jails for audit
+  -manifest	YAML file with the desired jails
+  -hosts	YAML file with the hosts for fleet apply
+  -parallel	Number of hosts or jails to work on in parallel
+  -pkgcache	Mount the shared pkg cache PkgCacheDir in the jail
+  -eol-only	Only list jails with an end-of-life (EOL) or soon EOL FreeBSD release
+  -format	Print each jail with a Go template, ex: '{{.Name}} {{.Ipv4}}'
+  -o		Output format of jails and runs, csv or yaml
+  -columns	Columns of jails and runs: jid,name,ipv4,path,config,osversion,boot,uptime,hostname,dataset,used,tags,description,health
+  -state	Only list running or stopped jails
+  -boot		Only list jails started (yes) or not started (no) at boot
+  -release	Only list jails with the 'FreeBSD Release'
+  -name		Only list jails with a name matching the glob, ex: 'web*'
+  -tag		Only list jails with the tag, start/stop/restart, snapshot or update the jails with the tag
+  -sort		Sort jails by name, jid or used (largest first), stats by name, cpu, mem, swap, procs, files, read or write (largest first)
+  -q		Only print the jail names of jails and runs, one per line
+  -no-pager	Do not page jails or runs longer than the terminal through $PAGER
+  -wide		Wide listing of jails or runs, with the Config column, whatever the terminal width
+  -width	Terminal width for jails or runs, the Config column is dropped below 81
+  -watch	Redraw jails or runs every n seconds, jails started or stopped since the previous redraw are marked '*'
+  -resume	Resume an interrupted or failed release upgrade
+  -abort	Abort a release upgrade, roll back to the pre-upgrade snapshot
+  -noverify	Do not verify the jail after an update
+  -etc		Include /etc in verify
+  -repo		Comma separated pkg repositories from PkgRepos in jmgr.conf
+  -dataset	ZFS dataset home for jails created by init, ex: zroot/jails
+  -mountpoint	Mountpoint of the ZFS dataset created by init
+  -home		Directory home for jails without ZFS, created by init
+  -iface	Default jail interface set by init
+  -l		CPU list of cpuset, ex: 0-3 or 0,2,4-7
+  -check	Validate the jmgr config file, all problems with line numbers, exit 7 if any
+
+ See jmgr(8) for details.
+
+` // eof string
+
+	fmt.Println(string)
+	if jsonOutput || jsonStderr {
+		fatal(jmgrError(exitUsage, "Unknown subcommand or bad arguments", "See: jmgr help"))
+	}
+	exit(0)
+}
+
+// eof
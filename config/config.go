@@ -0,0 +1,962 @@
+/*-
+ * Copyright (c) 2024 peter@libassi.se
+ *
+ * SPDX-License-Identifier: BSD-2-Clause
+ */
+
+// Package config is the jmgr(8) configuration, jmgr.conf: the settings, the config files in YAML, TOML or JSON with
+// their conf.d drop-in files, the profiles and the JMGR_<KEY> environment overrides. Load reads the settings the way
+// the jmgr command does.
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"jmgr/inventory"
+	"jmgr/system"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// Settings the settings of jmgr.conf, the keys are the YAML names, ex: ZFSdataSet
+type Settings struct {
+	JmgrConfig       string `json:"jmgrconfig"`                               // Name of jmgr config (YAML) file.
+	Version          int    `yaml:"Version" json:"version"`                   // config layout, see Version
+	JailsHome        string `yaml:"JailsHome" json:"jailshome"`               // Directory where new jails are created/cloned
+	OsMediaDir       string `yaml:"OsMediaDir" json:"osmediadir"`             // Directory where the OS bits are stored
+	ZFSdataSet       string `yaml:"ZFSdataSet" json:"zfsdataset"`             // if defined JailsHome is derived from ZFSdataSet
+	JailsConfD       string `json:"jailsconfd"`                               // /etc/jail.conf.d
+	JailConfTemplate string `yaml:"JailConfTemplate" json:"jailconftemplate"` // Default: jail.conf.template
+	JailTemplateDir  string `yaml:"JailTemplateDir" json:"jailtemplatedir"`   // Directory with named jail.conf templates, <name>.template
+	JailMetaDir      string `yaml:"JailMetaDir" json:"jailmetadir"`           // Directory with jmgr jail metadata, <jail name>.yml
+	PostInstall      string `yaml:"PostInstall" json:"postinstall"`           // Script if exist runs after create
+	PreClone         string `yaml:"PreClone" json:"preclone"`                 // Script if exist runs before the source jail is copied, ex: flush a database
+	PostClone        string `yaml:"PostClone" json:"postclone"`               // Script if exist runs after the source jail is copied
+	PreSnapshot      string `yaml:"PreSnapshot" json:"presnapshot"`           // Script if exist runs before a quiesced group snapshot, ex: flush a database
+	PostSnapshot     string `yaml:"PostSnapshot" json:"postsnapshot"`         // Script if exist runs after a quiesced group snapshot
+	OsUrlPrefix      string `yaml:"OsUrlPrefix" json:"osurlprefix"`           // OS download URL prefix
+	EolUrl           string `yaml:"EolUrl" json:"eolurl"`                     // FreeBSD end-of-life table (JSON), cached in OsMediaDir
+	UpdateCacheDir   string `yaml:"UpdateCacheDir" json:"updatecachedir"`     // Shared freebsd-update work directories, one per release
+	PkgBaseRepo      string `yaml:"PkgBaseRepo" json:"pkgbaserepo"`           // pkg repository with the base system for pkgbase jails
+	JailUser         string `yaml:"JailUser" json:"jailuser"`                 // Default user when enter a running jail
+	JailIface        string `yaml:"JailIface" json:"jailiface"`               // Default IPv4 interface
+	JailIPPool       string `yaml:"JailIPPool" json:"jailippool"`             // IPv4 addresses for 'clone -n', CIDR or range, ex: 192.168.1.128/26
+	PkgCacheDir      string `yaml:"PkgCacheDir" json:"pkgcachedir"`           // Shared host pkg cache, nullfs mounted on /var/cache/pkg in jails, see 'jmgr pkgcache'
+	Syslog           string `yaml:"Syslog" json:"syslog"`                     // Format of the events logged to syslog: kv (default), json or none
+
+	// External tool paths by name, ex: zfs: /usr/local/sbin/zfs-wrapper, see system.Tool()
+	Tools map[string]string `yaml:"Tools" json:"tools"`
+
+	// Named pkg repositories for jails, see 'jmgr repo'
+	PkgRepos map[string]PkgRepo `yaml:"PkgRepos" json:"pkgrepos"`
+
+	// Named webhooks for the lifecycle events, see event() of jmgr
+	Webhooks map[string]Webhook `yaml:"Webhooks" json:"webhooks"`
+
+	// Named thresholds checked by 'jmgr daemon' and 'jmgr serve'
+	Alerts map[string]Alert `yaml:"Alerts" json:"alerts"`
+
+	// Per jail settings, see inventory.JailSettings
+	JailSettings map[string]inventory.JailSettings `yaml:"Jails" json:"jailsettings"`
+
+	// Named profiles, settings that override the ones above, selected with 'jmgr -profile <name>', see ApplyProfile
+	Profiles map[string]map[string]string `yaml:"Profiles" json:"profiles"`
+	Profile  string                       `json:"profile"` // active profile
+
+}
+
+// Load return the settings of file, the default config file (see File) if empty: the built-in defaults, the config
+// file and its conf.d drop-in files, the profile if not empty and the environment overrides, ex: JMGR_ZFSDATASET.
+// The warnings are problems that do not stop jmgr, ex: an unknown key
+func Load(file string, profile string) (Settings, []string, error) {
+
+	var cfg Settings
+	cfg.JmgrConfig = file
+	if len(cfg.JmgrConfig) == 0 {
+		cfg.JmgrConfig = File()
+	}
+	cfg.Defaults()
+	warnings, err := cfg.Read()
+	if err != nil {
+		return cfg, warnings, fmt.Errorf("%s: %w", cfg.JmgrConfig, err)
+	}
+	if len(profile) > 0 {
+		if err := cfg.ApplyProfile(profile); err != nil {
+			return cfg, warnings, err
+		}
+		cfg.Profile = profile
+	}
+	cfg.Env()
+	cfg.DerivedDefaults()
+	return cfg, warnings, nil
+}
+
+// Read decode the config file JmgrConfig, then its conf.d drop-in files, into cfg. An error if the config file can't
+// be read or decoded. Unknown keys (typos) and the drop-in files that can't be decoded are warnings
+func (cfg *Settings) Read() (warnings []string, err error) {
+
+	s, err := os.Stat(cfg.JmgrConfig)
+	if err != nil {
+		return nil, errors.New("File does not exist.")
+	}
+	if s.IsDir() {
+		return nil, errors.New("File is a directory.")
+	}
+
+	// read file, YAML, TOML or JSON see Format
+	b, err := os.ReadFile(cfg.JmgrConfig)
+	if err != nil {
+		return nil, errors.New("File gives error: " + err.Error())
+	}
+
+	format := Format(cfg.JmgrConfig)
+	b, version, changes := Migrate(b, format)
+	if len(changes) > 0 || version > Version {
+		warnings = append(warnings, cfg.JmgrConfig+" is config version "+strconv.Itoa(version)+", this jmgr uses version "+strconv.Itoa(Version)+", see: jmgr config migrate")
+	}
+	if err := Decode(b, format, cfg, false); err != nil {
+		return warnings, errors.New("Problem decoding: " + err.Error())
+	}
+
+	// unknown keys (typos) are ignored above, warn about them
+	if err := Decode(b, format, &Settings{}, true); err != nil {
+		warnings = append(warnings, cfg.JmgrConfig+" has problems, see: jmgr config -check")
+	}
+
+	// drop-in files in conf.d, in name order, later files win
+	for _, file := range ConfDFiles(cfg.JmgrConfig) {
+		b, err := os.ReadFile(file)
+		if err == nil {
+			b, _, _ = Migrate(b, Format(file))
+			err = Decode(b, Format(file), cfg, false)
+		}
+		if err != nil {
+			warnings = append(warnings, file+" skipped, see: jmgr config -check")
+			continue
+		}
+		if err := Decode(b, Format(file), &Settings{}, true); err != nil {
+			warnings = append(warnings, file+" has problems, see: jmgr config -check")
+		}
+	}
+	return warnings, nil
+}
+
+// pkg repository written to /usr/local/etc/pkg/repos/<name>.conf in a jail, see pkg.conf(5)
+type PkgRepo struct {
+	URL           string `yaml:"URL" json:"url"`                      // ex: pkg+http://pkg.FreeBSD.org/${ABI}/latest
+	MirrorType    string `yaml:"MirrorType" json:"mirror_type"`       // srv, http or none
+	SignatureType string `yaml:"SignatureType" json:"signature_type"` // none, pubkey or fingerprints
+	Fingerprints  string `yaml:"Fingerprints" json:"fingerprints"`    // directory with fingerprints
+	PubKey        string `yaml:"PubKey" json:"pubkey"`                // public key file
+	Priority      int    `yaml:"Priority" json:"priority"`
+	Disabled      bool   `yaml:"Disabled" json:"disabled"` // disable a repository, ex: FreeBSD
+}
+
+// Webhook a URL that receives the lifecycle events as JSON, with a Secret signed: X-Jmgr-Signature: sha256=<HMAC-SHA256 of the body>
+type Webhook struct {
+	URL    string   `yaml:"URL" json:"url"`
+	Secret string   `yaml:"Secret,omitempty" json:"secret,omitempty"`
+	Events []string `yaml:"Events,omitempty" json:"events,omitempty"` // default all events
+}
+
+// Alert a threshold on a jail, an alert event when it is breached and alert-cleared when it is no more, checked by 'jmgr daemon'
+type Alert struct {
+	Check string   `yaml:"Check" json:"check"`                     // memory, cpu or procs (percent of the rctl limit), dataset (percent of the quota) or stopped
+	Above int      `yaml:"Above,omitempty" json:"above,omitempty"` // percent, default 90
+	Jails []string `yaml:"Jails,omitempty" json:"jails,omitempty"` // default all jails
+	Hook  string   `yaml:"Hook,omitempty" json:"hook,omitempty"`   // script run on the events, env JMGR_ALERT, JMGR_EVENT, JMGR_JAIL and JMGR_DETAIL
+}
+
+// Defaults set the built-in defaults, some are relative to the config file directory
+func (cfg *Settings) Defaults() {
+
+	cfg.JailsConfD = "/etc/jail.conf.d"
+	cfg.JailTemplateDir = filepath.Join(filepath.Dir(cfg.JmgrConfig), "templates")
+	cfg.JailMetaDir = filepath.Join(filepath.Dir(cfg.JmgrConfig), "meta")
+	cfg.EolUrl = "https://endoflife.date/api/freebsd.json"
+}
+
+// DerivedDefaults set the built-in defaults of the settings not configured that depend on other settings
+func (cfg *Settings) DerivedDefaults() {
+
+	if len(cfg.UpdateCacheDir) == 0 {
+		cfg.UpdateCacheDir = filepath.Join(cfg.OsMediaDir, "freebsd-update")
+	}
+	if len(cfg.PkgBaseRepo) == 0 {
+		cfg.PkgBaseRepo = "FreeBSD-base"
+	}
+}
+
+// Diff return the settings of cfg that differ from the built-in defaults, by config key
+func Diff(cfg Settings) map[string]any {
+
+	// UpdateCacheDir defaults to a directory in the configured OsMediaDir
+	def := Settings{JmgrConfig: cfg.JmgrConfig, Version: Version, OsMediaDir: cfg.OsMediaDir}
+	def.Defaults()
+	def.DerivedDefaults()
+	def.OsMediaDir = ""
+	// a config file without Version is version 1
+	if cfg.Version == 0 {
+		def.Version = 0
+	}
+
+	diff := make(map[string]any)
+	t := reflect.TypeOf(cfg)
+	v := reflect.ValueOf(cfg)
+	dv := reflect.ValueOf(def)
+	for i := 0; i < t.NumField(); i++ {
+		key := t.Field(i).Tag.Get("yaml")
+		if len(key) == 0 || reflect.DeepEqual(v.Field(i).Interface(), dv.Field(i).Interface()) {
+			continue
+		}
+		// ex: an empty map is no setting
+		if v.Field(i).Kind() == reflect.Map && v.Field(i).Len() == 0 {
+			continue
+		}
+		diff[key] = v.Field(i).Interface()
+	}
+	if len(cfg.Profile) > 0 {
+		diff["Profile"] = cfg.Profile
+	}
+	return diff
+}
+
+// File return the jmgr config file name, env JMGR_CONFIG overrides the default
+func File() string {
+
+	env, ok := os.LookupEnv("JMGR_CONFIG")
+	if len(env) > 0 && ok {
+		return env
+	}
+	// a regular user can keep a personal config in ~/.config/jmgr ($XDG_CONFIG_HOME/jmgr)
+	if system.NotRoot() {
+		if dir, err := os.UserConfigDir(); err == nil {
+			if file := FindFile(dir + "/jmgr"); len(file) > 0 {
+				return file
+			}
+		}
+	}
+	if file := FindFile("/usr/local/etc/jmgr"); len(file) > 0 {
+		return file
+	}
+	return "/usr/local/etc/jmgr/jmgr.conf"
+}
+
+// FindFile return jmgr.conf (YAML), or if it does not exist jmgr.toml or jmgr.json in dir. Empty if none exist
+func FindFile(dir string) string {
+
+	for _, name := range []string{"jmgr.conf", "jmgr.toml", "jmgr.json"} {
+		if _, err := os.Stat(dir + "/" + name); err == nil {
+			return dir + "/" + name
+		}
+	}
+	return ""
+}
+
+// ConfDFiles return the drop-in config files, *.conf (YAML), *.yml, *.toml and *.json in conf.d next to the config file, in name order
+func ConfDFiles(file string) []string {
+
+	var files []string
+	all, _ := filepath.Glob(filepath.Join(filepath.Dir(file), "conf.d", "*"))
+	for _, f := range all {
+		switch filepath.Ext(f) {
+		case ".conf", ".yml", ".yaml", ".toml", ".json":
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// Key return the jmgr.conf key name for key (any case), only the string settings can be get/set
+func Key(key string) (string, error) {
+
+	var keys []string
+	t := reflect.TypeOf(Settings{})
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if len(f.Tag.Get("yaml")) == 0 || f.Type.Kind() != reflect.String {
+			continue
+		}
+		if strings.EqualFold(f.Name, key) {
+			return f.Name, nil
+		}
+		keys = append(keys, f.Name)
+	}
+	return "", fmt.Errorf("Unknown config key: %s, valid keys: %s", key, strings.Join(keys, ", "))
+}
+
+// Check validate a value for a jmgr.conf key, an empty value removes the key
+func Check(key string, value string) error {
+
+	if len(value) == 0 {
+		return nil
+	}
+	if strings.ContainsAny(value, "\n\r") {
+		return fmt.Errorf("%s: value must be one line", key)
+	}
+
+	switch key {
+	case "ZFSdataSet":
+		if _, err := system.Run("/sbin/zfs", []string{"list", "-H", value}); err != nil {
+			return fmt.Errorf("%s: dataset %s does not exist", key, value)
+		}
+	case "JailsHome", "OsMediaDir", "UpdateCacheDir", "JailTemplateDir", "JailMetaDir", "PkgCacheDir":
+		if !filepath.IsAbs(value) {
+			return fmt.Errorf("%s: %s is not an absolute path", key, value)
+		}
+	case "JailConfTemplate", "PostInstall", "PreClone", "PostClone", "PreSnapshot", "PostSnapshot":
+		if !filepath.IsAbs(value) {
+			return fmt.Errorf("%s: %s is not an absolute path", key, value)
+		}
+		if s, err := os.Stat(value); err != nil || s.IsDir() {
+			return fmt.Errorf("%s: file %s does not exist", key, value)
+		}
+	case "OsUrlPrefix", "EolUrl":
+		u, err := url.Parse(value)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "ftp") || len(u.Host) == 0 {
+			return fmt.Errorf("%s: %s is not a http, https or ftp URL", key, value)
+		}
+	case "JailIface":
+		if _, err := net.InterfaceByName(value); err != nil {
+			return fmt.Errorf("%s: interface %s does not exist", key, value)
+		}
+	case "JailIPPool":
+		if _, _, err := IPPoolRange(value); err != nil {
+			return err
+		}
+	case "Syslog":
+		if !slices.Contains([]string{"kv", "json", "none"}, value) {
+			return fmt.Errorf("%s: must be kv, json or none", key)
+		}
+	default:
+		if strings.ContainsAny(value, " \t") {
+			return fmt.Errorf("%s: value must not contain spaces", key)
+		}
+	}
+	return nil
+}
+
+// Set set key to value in the jmgr config file, keep comments and the other settings.
+// An empty value comments the key out. The previous version is saved as <file>.bak
+func Set(file string, key string, value string) error {
+
+	err := Check(key, value)
+	if err != nil {
+		return err
+	}
+
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("Set() %w", err)
+	}
+	format := Format(file)
+
+	var conf string
+	if format == "json" {
+		conf, err = setJSON(b, key, value)
+		if err != nil {
+			return fmt.Errorf("Set() %s: %w", file, err)
+		}
+	} else {
+		conf = SetLine(b, format, key, value)
+	}
+
+	// the result must still be a valid jmgr config
+	var check Settings
+	if err := Decode([]byte(conf), format, &check, false); err != nil {
+		return fmt.Errorf("Set() %s: %w", file, err)
+	}
+	return writeFile(file, b, conf)
+}
+
+// writeFile replace the config file with conf, the previous version old is saved as <file>.bak
+func writeFile(file string, old []byte, conf string) error {
+
+	s, err := os.Stat(file)
+	if err != nil {
+		return fmt.Errorf("writeFile() %w", err)
+	}
+	if err := os.WriteFile(file+".bak", old, s.Mode().Perm()); err != nil {
+		return fmt.Errorf("writeFile() backup %w", err)
+	}
+	f, err := os.CreateTemp(filepath.Dir(file), ".jmgr.conf-")
+	if err != nil {
+		return fmt.Errorf("writeFile() %w", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(conf); err != nil {
+		f.Close()
+		return fmt.Errorf("writeFile() %w", err)
+	}
+	f.Chmod(s.Mode().Perm())
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("writeFile() %w", err)
+	}
+	return os.Rename(f.Name(), file)
+}
+
+// Version is the current config layout. A config file without Version is version 1
+const Version = 1
+
+// Migrations the deprecated keys of each config version, renamed by the loader and 'jmgr config migrate'.
+// When a key is renamed, add {From: Version, Rename: {"old": "new"}} and increase Version.
+var Migrations = []struct {
+	From   int               // config version with the old keys
+	Rename map[string]string // old key: new key
+}{}
+
+// Migrate rename the deprecated keys of a config file older than Version,
+// return the migrated file, its version and the changes
+func Migrate(b []byte, format string) ([]byte, int, []string) {
+
+	var v Settings
+	Decode(b, format, &v, false)
+	version := max(v.Version, 1)
+
+	var changes []string
+	for _, m := range Migrations {
+		if m.From < version {
+			continue
+		}
+		for old, key := range m.Rename {
+			rgx := KeyRegexp(format, old)
+			lines := strings.Split(string(b), "\n")
+			for i, l := range lines {
+				if rgx.MatchString(l) {
+					if format == "json" {
+						lines[i] = regexp.MustCompile(`(?i)"`+old+`"`).ReplaceAllString(l, `"`+strings.ToLower(key)+`"`)
+					} else {
+						lines[i] = strings.Replace(l, old, key, 1)
+					}
+					changes = append(changes, "line "+strconv.Itoa(i+1)+": "+old+" renamed to "+key)
+				}
+			}
+			b = []byte(strings.Join(lines, "\n"))
+		}
+	}
+	return b, version, changes
+}
+
+// MigrateFile rewrite a config file to the current layout, the deprecated keys renamed and Version set.
+// The previous version is saved as <file>.bak. Return the changes
+func MigrateFile(file string, dryRun bool) ([]string, error) {
+
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("MigrateFile() %w", err)
+	}
+	format := Format(file)
+	migrated, version, changes := Migrate(b, format)
+
+	if version > Version {
+		return nil, fmt.Errorf("%s is config version %d, newer than this jmgr (%d)", file, version, Version)
+	}
+	var v Settings
+	Decode(b, format, &v, false)
+	if v.Version != Version {
+		conf := string(migrated)
+		if format == "json" {
+			var m map[string]json.RawMessage
+			if err := json.Unmarshal(migrated, &m); err != nil {
+				return nil, fmt.Errorf("MigrateFile() %s: %w", file, err)
+			}
+			for k := range m {
+				if strings.EqualFold(k, "version") {
+					delete(m, k)
+				}
+			}
+			m["version"] = json.RawMessage(strconv.Itoa(Version))
+			out, _ := json.MarshalIndent(m, "", "  ")
+			conf = string(out) + "\n"
+		} else {
+			// Version first, a TOML key must be before the tables
+			line := "Version: " + strconv.Itoa(Version)
+			if format == "toml" {
+				line = "Version = " + strconv.Itoa(Version)
+			}
+			var lines []string
+			for _, l := range strings.Split(conf, "\n") {
+				if !KeyRegexp(format, "Version").MatchString(l) {
+					lines = append(lines, l)
+				}
+			}
+			at := 0
+			for at < len(lines) && strings.HasPrefix(lines[at], "#") {
+				at++ // after the header comment
+			}
+			lines = append(lines[:at], append([]string{line}, lines[at:]...)...)
+			conf = strings.Join(lines, "\n")
+		}
+		migrated = []byte(conf)
+		changes = append(changes, "Version set to "+strconv.Itoa(Version))
+	}
+
+	if len(changes) == 0 {
+		return []string{"current, version " + strconv.Itoa(Version)}, nil
+	}
+	var check Settings
+	if err := Decode(migrated, format, &check, false); err != nil {
+		return nil, fmt.Errorf("MigrateFile() %s: %w", file, err)
+	}
+	if dryRun {
+		return changes, nil
+	}
+	return changes, writeFile(file, b, string(migrated))
+}
+
+// KeyRegexp match the line of a setting in a config file of format
+func KeyRegexp(format string, key string) *regexp.Regexp {
+
+	switch format {
+	case "toml":
+		return regexp.MustCompile(`^` + key + `\s*=`)
+	case "json":
+		return regexp.MustCompile(`(?i)^\s*"` + key + `"\s*:`)
+	}
+	return regexp.MustCompile(`^` + key + `\s*:`)
+}
+
+// SetLine set key in a YAML or TOML config, keep comments and the other settings. Replace the setting,
+// or add it after the commented out example, or at the end of the top level settings. An empty value comments it out
+func SetLine(b []byte, format string, key string, value string) string {
+
+	lines := strings.Split(strings.TrimRight(string(b), "\n"), "\n")
+
+	sep := ": "
+	quoted := value
+	if format == "toml" {
+		sep = " = "
+		quoted = strconv.Quote(value)
+	} else if y, err := yaml.Marshal(value); err == nil {
+		quoted = strings.TrimSpace(string(y))
+	}
+	line := "#" + key + strings.TrimRight(sep, " ")
+	if len(value) > 0 {
+		line = key + sep + quoted
+	}
+
+	set := KeyRegexp(format, key)
+	example := regexp.MustCompile(`^#\s*` + strings.TrimPrefix(set.String(), "^"))
+	at := -1
+	for i, l := range lines {
+		if set.MatchString(l) {
+			at = i
+		}
+	}
+	if at >= 0 {
+		lines[at] = line
+	} else if len(value) > 0 {
+		end := len(lines)
+		for i, l := range lines {
+			if example.MatchString(l) && at < 0 {
+				at = i + 1
+			}
+			// TOML tables, the top level settings end at the first table
+			if format == "toml" && strings.HasPrefix(strings.TrimSpace(l), "[") && end == len(lines) {
+				end = i
+			}
+		}
+		for end > 0 && end < len(lines) && len(strings.TrimSpace(lines[end-1])) == 0 {
+			end-- // before the empty lines above the first table
+		}
+		if at < 0 || at > end {
+			at = end
+		}
+		lines = append(lines[:at], append([]string{line}, lines[at:]...)...)
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// setJSON set key in a JSON config, the JSON key is the one from 'jmgr config -json'. An empty value removes it
+func setJSON(b []byte, key string, value string) (string, error) {
+
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(b, &m); err != nil {
+		return "", err
+	}
+	f, _ := reflect.TypeOf(Settings{}).FieldByName(key)
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	for k := range m {
+		if strings.EqualFold(k, name) || strings.EqualFold(k, key) {
+			delete(m, k)
+		}
+	}
+	if len(value) > 0 {
+		m[name], _ = json.Marshal(value)
+	}
+	out, err := json.MarshalIndent(m, "", "  ")
+	return string(out) + "\n", err
+}
+
+// ApplyProfile override settings with profile name, from Profiles in the config file or the file
+// <name>.conf, .yml, .toml or .json in the profiles.d directory next to the config file
+func (cfg *Settings) ApplyProfile(name string) error {
+
+	if settings, ok := cfg.Profiles[name]; ok {
+		v := reflect.ValueOf(cfg).Elem()
+		for key, value := range settings {
+			key, err := Key(key)
+			if err != nil {
+				return fmt.Errorf("Profile %s: %w", name, err)
+			}
+			v.FieldByName(key).SetString(value)
+		}
+		return nil
+	}
+
+	file := cfg.JmgrConfig
+	if len(file) == 0 {
+		file = File()
+	}
+	dir := filepath.Join(filepath.Dir(file), "profiles.d")
+	for _, ext := range []string{".conf", ".yml", ".toml", ".json"} {
+		b, err := os.ReadFile(filepath.Join(dir, name+ext))
+		if err != nil {
+			continue
+		}
+		if err := Decode(b, Format(name+ext), cfg, false); err != nil {
+			return fmt.Errorf("Profile %s: %w", filepath.Join(dir, name+ext), err)
+		}
+		return nil
+	}
+	return fmt.Errorf("Unknown profile: %s, not in Profiles in %s or %s", name, file, dir)
+}
+
+// Env override settings with the environment, JMGR_<KEY IN UPPER CASE>, ex: JMGR_ZFSDATASET.
+// Set but empty clears the setting, ex: JMGR_ZFSDATASET= to use JailsHome without ZFS
+func (cfg *Settings) Env() {
+
+	v := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		key := v.Type().Field(i).Tag.Get("yaml")
+		if len(key) == 0 || v.Field(i).Kind() != reflect.String {
+			continue
+		}
+		if env, ok := os.LookupEnv(EnvName(key)); ok {
+			v.Field(i).SetString(env)
+		}
+	}
+}
+
+// EnvName return the environment variable that overrides key
+func EnvName(key string) string {
+	return "JMGR_" + strings.ToUpper(key)
+}
+
+// Errors all problems found decoding a config file, one 'line n: problem' each
+type Errors []string
+
+func (e Errors) Error() string { return strings.Join(e, "\n") }
+
+// Format return the format of a jmgr config file from its extension: yaml (.conf, .yml), toml or json
+func Format(file string) string {
+
+	switch filepath.Ext(file) {
+	case ".toml":
+		return "toml"
+	case ".json":
+		return "json"
+	}
+	return "yaml"
+}
+
+// Decode decode a jmgr config file in format into cfg, strict reports unknown keys
+func Decode(b []byte, format string, cfg *Settings, strict bool) error {
+
+	switch format {
+	case "toml":
+		return tomlDecode(b, cfg, strict)
+	case "json":
+		return jsonDecode(b, cfg, strict)
+	}
+	if strict {
+		return yaml.UnmarshalStrict(b, cfg)
+	}
+	return yaml.Unmarshal(b, cfg)
+}
+
+// Encode encode the settings of cfg as a toml or json config file
+func Encode(cfg Settings, format string) ([]byte, error) {
+
+	t := reflect.TypeOf(cfg)
+	v := reflect.ValueOf(cfg)
+
+	if format == "json" {
+		m := make(map[string]any)
+		for i := 0; i < t.NumField(); i++ {
+			if len(t.Field(i).Tag.Get("yaml")) > 0 && !v.Field(i).IsZero() {
+				name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+				m[name] = v.Field(i).Interface()
+			}
+		}
+		b, err := json.MarshalIndent(m, "", "  ")
+		return append(b, '\n'), err
+	}
+
+	var b strings.Builder
+	b.WriteString("# jmgr(8) config\n")
+	for i := 0; i < t.NumField(); i++ {
+		if len(t.Field(i).Tag.Get("yaml")) > 0 && t.Field(i).Type.Kind() != reflect.Map && !v.Field(i).IsZero() {
+			b.WriteString(t.Field(i).Tag.Get("yaml") + " = " + tomlValue(v.Field(i)) + "\n")
+		}
+	}
+	names := make([]string, 0, len(cfg.Tools))
+	for name := range cfg.Tools {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	if len(names) > 0 {
+		b.WriteString("\n[Tools]\n")
+	}
+	for _, name := range names {
+		b.WriteString(name + " = " + strconv.Quote(cfg.Tools[name]) + "\n")
+	}
+	names = names[:0]
+	for name := range cfg.PkgRepos {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		b.WriteString("\n[PkgRepos." + strconv.Quote(name) + "]\n")
+		rv := reflect.ValueOf(cfg.PkgRepos[name])
+		for i := 0; i < rv.NumField(); i++ {
+			if !rv.Field(i).IsZero() {
+				b.WriteString(rv.Type().Field(i).Tag.Get("yaml") + " = " + tomlValue(rv.Field(i)) + "\n")
+			}
+		}
+	}
+	names = names[:0]
+	for name := range cfg.Webhooks {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		b.WriteString("\n[Webhooks." + strconv.Quote(name) + "]\n")
+		rv := reflect.ValueOf(cfg.Webhooks[name])
+		for i := 0; i < rv.NumField(); i++ {
+			if !rv.Field(i).IsZero() {
+				b.WriteString(rv.Type().Field(i).Tag.Get("yaml") + " = " + tomlValue(rv.Field(i)) + "\n")
+			}
+		}
+	}
+	names = names[:0]
+	for name := range cfg.Alerts {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		b.WriteString("\n[Alerts." + strconv.Quote(name) + "]\n")
+		rv := reflect.ValueOf(cfg.Alerts[name])
+		for i := 0; i < rv.NumField(); i++ {
+			if !rv.Field(i).IsZero() {
+				b.WriteString(rv.Type().Field(i).Tag.Get("yaml") + " = " + tomlValue(rv.Field(i)) + "\n")
+			}
+		}
+	}
+	names = names[:0]
+	for name := range cfg.JailSettings {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		b.WriteString("\n[Jails." + strconv.Quote(name) + "]\n")
+		rv := reflect.ValueOf(cfg.JailSettings[name])
+		for i := 0; i < rv.NumField(); i++ {
+			if !rv.Field(i).IsZero() {
+				b.WriteString(rv.Type().Field(i).Tag.Get("yaml") + " = " + tomlValue(rv.Field(i)) + "\n")
+			}
+		}
+	}
+	names = names[:0]
+	for name := range cfg.Profiles {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		b.WriteString("\n[Profiles." + strconv.Quote(name) + "]\n")
+		keys := make([]string, 0, len(cfg.Profiles[name]))
+		for key := range cfg.Profiles[name] {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		for _, key := range keys {
+			b.WriteString(key + " = " + strconv.Quote(cfg.Profiles[name][key]) + "\n")
+		}
+	}
+	return []byte(b.String()), nil
+}
+
+// tomlValue return v as a TOML value
+func tomlValue(v reflect.Value) string {
+
+	switch v.Kind() {
+	case reflect.String:
+		return strconv.Quote(v.String())
+	case reflect.Pointer:
+		return tomlValue(v.Elem())
+	case reflect.Slice:
+		var list []string
+		for i := 0; i < v.Len(); i++ {
+			list = append(list, tomlValue(v.Index(i)))
+		}
+		return "[" + strings.Join(list, ", ") + "]"
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// tomlDecode decode a TOML jmgr config, the keys are the YAML keys, ex: ZFSdataSet = "zroot/jails", and the maps are
+// tables, ex: [PkgRepos.FreeBSD]. Parsed by BurntSushi/toml, then each top level key is decoded as YAML into cfg, so
+// that the TOML, YAML and JSON configs have the same keys and checks
+func tomlDecode(b []byte, cfg *Settings, strict bool) error {
+
+	var keys map[string]any
+	if _, err := toml.Decode(string(b), &keys); err != nil {
+		var perr toml.ParseError
+		if errors.As(err, &perr) {
+			return Errors{strings.TrimPrefix(perr.Error(), "toml: ")} // line n (last key ...): ...
+		}
+		return Errors{err.Error()}
+	}
+	names := make([]string, 0, len(keys))
+	for k := range keys {
+		names = append(names, k)
+	}
+	slices.Sort(names)
+
+	// line of the key or its table, ex: [Webhooks.slack]
+	lines := strings.Split(string(b), "\n")
+	lineOf := func(key string) int {
+		for i, l := range lines {
+			l = strings.TrimLeft(strings.TrimSpace(l), `["'`)
+			if strings.HasPrefix(l, key) && len(l) > len(key) && strings.ContainsRune(" =.]\"'\t", rune(l[len(key)])) {
+				return i + 1
+			}
+		}
+		return 0
+	}
+	yamlLine := regexp.MustCompile(`^line [0-9]+: `)
+
+	var errs Errors
+	for _, k := range names {
+		y, err := yaml.Marshal(map[string]any{k: keys[k]})
+		if err == nil {
+			if strict {
+				err = yaml.UnmarshalStrict(y, cfg)
+			} else {
+				err = yaml.Unmarshal(y, cfg)
+			}
+		}
+		var terr *yaml.TypeError
+		if errors.As(err, &terr) {
+			for _, e := range terr.Errors {
+				errs = append(errs, fmt.Sprintf("line %d: %s", lineOf(k), yamlLine.ReplaceAllString(e, "")))
+			}
+		} else if err != nil {
+			errs = append(errs, fmt.Sprintf("line %d: %s", lineOf(k), err.Error()))
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// jsonDecode decode a JSON jmgr config, the keys are those of 'jmgr config -json', ex: "zfsdataset": "zroot/jails"
+func jsonDecode(b []byte, cfg *Settings, strict bool) error {
+
+	// line of offset in b
+	lineAt := func(offset int64) int {
+		return bytes.Count(b[:min(int(offset), len(b))], []byte("\n")) + 1
+	}
+
+	var keys map[string]json.RawMessage
+	if err := json.Unmarshal(b, &keys); err != nil {
+		var serr *json.SyntaxError
+		if errors.As(err, &serr) {
+			return Errors{fmt.Sprintf("line %d: %s", lineAt(serr.Offset), serr.Error())}
+		}
+		return Errors{err.Error()}
+	}
+	names := make([]string, 0, len(keys))
+	for k := range keys {
+		names = append(names, k)
+	}
+	slices.Sort(names)
+
+	var errs Errors
+	v := reflect.ValueOf(cfg).Elem()
+	for _, k := range names {
+		line := lineAt(int64(bytes.Index(b, []byte(`"`+k+`"`))))
+		var field reflect.Value
+		for i := 0; i < v.NumField(); i++ {
+			name, _, _ := strings.Cut(v.Type().Field(i).Tag.Get("json"), ",")
+			if len(v.Type().Field(i).Tag.Get("yaml")) > 0 && strings.EqualFold(name, k) {
+				field = v.Field(i)
+			}
+		}
+		if !field.IsValid() {
+			if strict {
+				errs = append(errs, fmt.Sprintf("line %d: field %s not found", line, k))
+			}
+			continue
+		}
+		if err := json.Unmarshal(keys[k], field.Addr().Interface()); err != nil {
+			errs = append(errs, fmt.Sprintf("line %d: %s: cannot unmarshal %s into %s", line, k, keys[k], field.Kind()))
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// prefixLast return the last address of prefix, the host bits set
+func prefixLast(prefix netip.Prefix) netip.Addr {
+
+	b := prefix.Masked().Addr().AsSlice()
+	for i := prefix.Bits(); i < len(b)*8; i++ {
+		b[i/8] |= 0x80 >> (i % 8)
+	}
+	last, _ := netip.AddrFromSlice(b)
+	return last
+}
+
+// IPPoolRange return the first and last usable address of a JailIPPool, CIDR or range
+func IPPoolRange(pool string) (netip.Addr, netip.Addr, error) {
+
+	var first, last netip.Addr
+
+	if prefix, err := netip.ParsePrefix(pool); err == nil {
+		prefix = prefix.Masked()
+		first, last = prefix.Addr(), prefixLast(prefix)
+		switch prefix.Addr().BitLen() - prefix.Bits() {
+		case 0: // /32 or /128, the one address
+		case 1: // /31 or /127, both addresses, a point-to-point link has no network and broadcast address (RFC 3021)
+		default: // skip the network and the broadcast address
+			first, last = first.Next(), last.Prev()
+		}
+	} else {
+		from, to, ok := strings.Cut(pool, "-")
+		var err1, err2 error
+		first, err1 = netip.ParseAddr(strings.TrimSpace(from))
+		last, err2 = netip.ParseAddr(strings.TrimSpace(to))
+		if !ok || err1 != nil || err2 != nil || last.Less(first) {
+			return first, last, fmt.Errorf("JailIPPool: %s is not a CIDR or address range", pool)
+		}
+	}
+	return first, last, nil
+}
@@ -0,0 +1,18 @@
+/*-
+ * Copyright (c) 2024 peter@libassi.se
+ *
+ * SPDX-License-Identifier: BSD-2-Clause
+ */
+
+// Package jmgr is the root of the jmgr(8) module, the command is in cmd/jmgr. The packages below it are the library:
+// inventory (the jails), config (jmgr.conf), zfs (the datasets and snapshots), templates (the jail.conf of a new jail),
+// lifecycle (start and stop) and system (the external tools jmgr runs). Package client talks to a running 'jmgr daemon'
+// instead. This package has the default configuration.
+package jmgr
+
+import "embed"
+
+// DefaultConfig the default jmgr configuration, templates and scripts, installed by 'jmgr init'
+//
+//go:embed usr/local/etc/jmgr
+var DefaultConfig embed.FS
@@ -0,0 +1,192 @@
+/*-
+ * Copyright (c) 2024 peter@libassi.se
+ *
+ * SPDX-License-Identifier: BSD-2-Clause
+ */
+
+// Package inventory is the jail inventory of jmgr(8): the jails with their metadata and settings, the pending updates,
+// healthchecks and usage samples. The types are shared by the jmgr command, its JSON output ('jmgr -json'),
+// 'jmgr daemon' and the Go client, package jmgr/client.
+package inventory
+
+import (
+	"slices"
+	"time"
+)
+
+// Jail an existing jail, as jls(8) and the jail config report it, with its metadata and settings
+type Jail struct {
+	Jid         int           `json:"jid"`
+	Hostname    string        `json:"hostname"`
+	Name        string        `json:"name"`
+	State       string        `json:"state"`
+	Cpusetid    int           `json:"cpusetid"`
+	Path        string        `json:"path"`
+	Dataset     string        `json:"dataset"`
+	ConfigPath  string        `json:"configpath"`
+	OsVersion   string        `json:"osversion"`
+	OnBoot      string        `json:"onboot"`
+	Iface       string        `json:"iface"`
+	Ipv4        string        `json:"ipv4"`
+	Ipv4Inherit string        `json:"ipv4inherit"`
+	IsParent    bool          `json:"-"` // has child jails
+	Parent      string        `json:"parent"`
+	Ipv4_addrs  []string      `json:"ipv4_addrs"`
+	Ipv6_addrs  []string      `json:"ipv6_addrs"`
+	Snapshots   []string      `json:"snapshots"`
+	Meta        JailMeta      `json:"meta"`
+	Settings    JailSettings  `json:"settings"`         // from Jails in jmgr.conf
+	Health      *HealthStatus `json:"health,omitempty"` // last healthcheck of 'jmgr daemon', or of 'jmgr health'
+}
+
+// Runs is true if the jail has a jid
+func (j *Jail) Runs() bool {
+	return j.Jid > 0
+}
+
+// Limits return the rctl rules of the jail, the jail settings first, then 'jmgr limits'
+func (jail *Jail) Limits() []string {
+	return append(slices.Clone(jail.Settings.Limits), jail.Meta.Limits...)
+}
+
+// RestartPolicy the Restart of the jail metadata, else of the jail settings
+func (j *Jail) RestartPolicy() string {
+
+	if len(j.Meta.Restart) > 0 {
+		return j.Meta.Restart
+	}
+	return j.Settings.Restart
+}
+
+// StartDelay the StartDelay of the jail metadata, else of the jail settings, 0 if none
+func (j *Jail) StartDelay() time.Duration {
+
+	delay := j.Settings.StartDelay
+	if len(j.Meta.StartDelay) > 0 {
+		delay = j.Meta.StartDelay
+	}
+	d, _ := time.ParseDuration(delay)
+	return max(d, 0)
+}
+
+// StopTimeout the StopTimeout of the jail metadata, else of the jail settings, 0 if none
+func (j *Jail) StopTimeout() time.Duration {
+
+	timeout := j.Settings.StopTimeout
+	if len(j.Meta.StopTimeout) > 0 {
+		timeout = j.Meta.StopTimeout
+	}
+	d, _ := time.ParseDuration(timeout)
+	return max(d, 0)
+}
+
+// jmgr metadata for a jail, stored as YAML in JailMetaDir/<jail name>.yml
+type JailMeta struct {
+	Template        string       `yaml:"Template,omitempty" json:"template,omitempty"`               // jail.conf template used at create
+	Tags            []string     `yaml:"Tags,omitempty" json:"tags,omitempty"`                       // tags, a tag is a group of jails, ex: @myapp
+	Depends         []string     `yaml:"Depends,omitempty" json:"depends,omitempty"`                 // jails this jail depends on, started before and stopped after this jail
+	Standby         string       `yaml:"Standby,omitempty" json:"standby,omitempty"`                 // standby host (user@host) the jail is replicated to
+	StandbySnapshot string       `yaml:"StandbySnapshot,omitempty" json:"standbysnapshot,omitempty"` // last snapshot replicated to the standby host
+	StandbySynced   string       `yaml:"StandbySynced,omitempty" json:"standbysynced,omitempty"`     // time (RFC3339) of last successful replication
+	StandbyOf       string       `yaml:"StandbyOf,omitempty" json:"standbyof,omitempty"`             // on a standby host, the primary host of this replica
+	PkgCache        bool         `yaml:"PkgCache,omitempty" json:"pkgcache,omitempty"`               // PkgCacheDir is nullfs mounted on /var/cache/pkg
+	PkgRepos        []string     `yaml:"PkgRepos,omitempty" json:"pkgrepos,omitempty"`               // pkg repositories from PkgRepos in jmgr.conf
+	Base            string       `yaml:"Base,omitempty" json:"base,omitempty"`                       // pkgbase if the base system is installed with pkg, empty for freebsd-update
+	Upgrade         *RelUpgrade  `yaml:"Upgrade,omitempty" json:"upgrade,omitempty"`                 // release upgrade in progress
+	Hold            bool         `yaml:"Hold,omitempty" json:"hold,omitempty"`                       // change frozen, not updated
+	Window          string       `yaml:"Window,omitempty" json:"window,omitempty"`                   // maintenance window for update -all, ex: Sat,Sun 02:00-05:00
+	Started         string       `yaml:"Started,omitempty" json:"started,omitempty"`                 // time (RFC3339) the jail was started by jmgr
+	Description     string       `yaml:"Description,omitempty" json:"description,omitempty"`         // free text, see 'jmgr describe'
+	StartedJid      int          `yaml:"StartedJid,omitempty" json:"startedjid,omitempty"`           // jid at Started, another jid means started outside jmgr
+	Stopped         string       `yaml:"Stopped,omitempty" json:"stopped,omitempty"`                 // time (RFC3339) the jail was stopped by jmgr, cleared at start
+	Limits          []string     `yaml:"Limits,omitempty" json:"limits,omitempty"`                   // rctl(8) rules of 'jmgr limits', applied at start after the Limits of the jail settings
+	Cpuset          string       `yaml:"Cpuset,omitempty" json:"cpuset,omitempty"`                   // cpu list of 'jmgr cpuset', applied at start, ex: 0-3
+	Nice            int          `yaml:"Nice,omitempty" json:"nice,omitempty"`                       // niceness of the jail processes, applied at start, see 'jmgr nice'
+	Health          *Healthcheck `yaml:"Health,omitempty" json:"health,omitempty"`                   // healthcheck of the service in the jail, see 'jmgr health'
+	Restart         string       `yaml:"Restart,omitempty" json:"restart,omitempty"`                 // restart policy, overrides the jail settings, see 'jmgr lifecycle'
+	StartDelay      string       `yaml:"StartDelay,omitempty" json:"startdelay,omitempty"`           // overrides the jail settings, see 'jmgr lifecycle'
+	StopTimeout     string       `yaml:"StopTimeout,omitempty" json:"stoptimeout,omitempty"`         // overrides the jail settings, see 'jmgr lifecycle'
+	Protected       bool         `yaml:"Protected,omitempty" json:"protected,omitempty"`             // destroy and rollback refuse the jail, see 'jmgr protect'
+}
+
+// per jail settings in jmgr.conf 'Jails: <jail name>:', used by create and apply and merged over the jail
+type JailSettings struct {
+	Template    string   `yaml:"Template,omitempty" json:"template,omitempty"`       // jail.conf template, default JailConfTemplate
+	Iface       string   `yaml:"Iface,omitempty" json:"iface,omitempty"`             // default JailIface
+	Hostname    string   `yaml:"Hostname,omitempty" json:"hostname,omitempty"`       // default jail name
+	Tags        []string `yaml:"Tags,omitempty" json:"tags,omitempty"`               // always set, in addition to the tags of 'jmgr tag'
+	PkgRepos    []string `yaml:"PkgRepos,omitempty" json:"pkgrepos,omitempty"`       // repos from PkgRepos, default for create
+	Pkgs        []string `yaml:"Pkgs,omitempty" json:"pkgs,omitempty"`               // packages installed after create
+	Limits      []string `yaml:"Limits,omitempty" json:"limits,omitempty"`           // rctl(8) rules applied at start, ex: memoryuse:deny=2g
+	Boot        *bool    `yaml:"Boot,omitempty" json:"boot,omitempty"`               // start on boot, enabled at create
+	Priority    int      `yaml:"Priority,omitempty" json:"priority,omitempty"`       // start -all/-tag order, lower first, stopped in reverse
+	Nice        int      `yaml:"Nice,omitempty" json:"nice,omitempty"`               // niceness -20..20 at create, template <Nice>, see 'jmgr nice'
+	Restart     string   `yaml:"Restart,omitempty" json:"restart,omitempty"`         // no (default), on-failure or always, see supervise
+	StartDelay  string   `yaml:"StartDelay,omitempty" json:"startdelay,omitempty"`   // wait after start before the next jail starts, ex: 10s
+	StopTimeout string   `yaml:"StopTimeout,omitempty" json:"stoptimeout,omitempty"` // kill the jail when stop takes longer, template <StopTimeout>
+}
+
+// progress of a release upgrade, see 'jmgr update rel -resume'
+type RelUpgrade struct {
+	From     string `yaml:"From" json:"from"`
+	To       string `yaml:"To" json:"to"`
+	Snapshot string `yaml:"Snapshot,omitempty" json:"snapshot,omitempty"` // pre-upgrade snapshot, see -abort
+	Done     string `yaml:"Done,omitempty" json:"done,omitempty"`         // last completed phase: upgrade, install, restart
+	Error    string `yaml:"Error,omitempty" json:"error,omitempty"`
+}
+
+// Healthcheck of the service in a jail, one of Command, TCP or HTTP
+type Healthcheck struct {
+	Command  string `yaml:"Command,omitempty" json:"command,omitempty"`   // run with jexec, healthy if it exits 0
+	TCP      string `yaml:"TCP,omitempty" json:"tcp,omitempty"`           // [address:]port, healthy if it accepts a connection. Default address the jail IP
+	HTTP     string `yaml:"HTTP,omitempty" json:"http,omitempty"`         // URL or [port]/path on the jail IP, healthy on a 2xx or 3xx reply
+	Interval string `yaml:"Interval,omitempty" json:"interval,omitempty"` // of the checks of 'jmgr daemon', default 30s
+	Timeout  string `yaml:"Timeout,omitempty" json:"timeout,omitempty"`   // default 5s
+}
+
+// String the healthcheck, ex: http /health every 30s
+func (h Healthcheck) String() string {
+
+	check := "cmd " + h.Command
+	if len(h.TCP) > 0 {
+		check = "tcp " + h.TCP
+	} else if len(h.HTTP) > 0 {
+		check = "http " + h.HTTP
+	}
+	return check + " every " + h.Every().String()
+}
+
+// Every return the Interval, default 30s
+func (h Healthcheck) Every() time.Duration {
+
+	if d, err := time.ParseDuration(h.Interval); err == nil && d > 0 {
+		return d
+	}
+	return 30 * time.Second
+}
+
+// HealthStatus the result of a healthcheck
+type HealthStatus struct {
+	Status string `json:"status"`           // healthy, unhealthy or stopped
+	Detail string `json:"detail,omitempty"` // why unhealthy
+	Time   string `json:"time"`             // RFC3339
+}
+
+// result of update check for one jail
+type UpdateCheck struct {
+	Name      string `json:"name"`
+	OsVersion string `json:"os_version"`
+	Patch     string `json:"patch"` // the patch level freebsd-update would update to, empty if none
+	Pkgs      int    `json:"pkgs"`  // number of packages to upgrade
+	Error     string `json:"error"`
+}
+
+// HistorySample the usage of a running jail at a time, sampled by jmgr daemon
+type HistorySample struct {
+	Time    time.Time `json:"time"`
+	Cpu     float64   `json:"cpu"`     // percent of one cpu
+	Memory  int64     `json:"memory"`  // resident bytes
+	Read    int64     `json:"read"`    // disk read bytes per second, -1 if unknown
+	Write   int64     `json:"write"`   // disk write bytes per second, -1 if unknown
+	Dataset int64     `json:"dataset"` // bytes used by the dataset and its snapshots
+}
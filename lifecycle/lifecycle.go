@@ -0,0 +1,313 @@
+/*-
+ * Copyright (c) 2024 peter@libassi.se
+ *
+ * SPDX-License-Identifier: BSD-2-Clause
+ */
+
+// Package lifecycle starts and stops the jails of jmgr(8) with jail(8), and applies the rctl limits, cpuset and
+// niceness of a jail when it starts. A stop may have a timeout, the processes left are then killed, see Stop.
+package lifecycle
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"jmgr/inventory"
+	"jmgr/system"
+)
+
+// StopOptions the timeout of a stop, see Stop
+type StopOptions struct {
+	Timeout time.Duration // 0 waits for jail -r
+	Kill    bool
+	Out     io.Writer // the processes killed and the filesystems unmounted, nil discards them
+}
+
+// TimeoutError a stop without Kill that did not finish within the Timeout, jail(8) is left to finish it
+type TimeoutError struct {
+	Jail    string
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return e.Jail + " did not stop within " + e.Timeout.String() + ", jail(8) is still stopping it."
+}
+
+// Proc a process of a running jail, from ps(1)
+type Proc struct {
+	Jail    string  `json:"jail"`
+	Jid     int     `json:"jid"`
+	Pid     int     `json:"pid"`
+	User    string  `json:"user"`
+	Cpu     float64 `json:"cpu"` // percent of one cpu
+	Rss     int64   `json:"rss"` // resident bytes
+	Time    string  `json:"time"`
+	Command string  `json:"command"`
+	Count   int     `json:"count,omitempty"` // processes of the command, with -s
+}
+
+// Start create the jail with jail(8), a jail that runs is left as is
+func Start(jail *inventory.Jail) error {
+
+	if jail.Runs() {
+		return nil
+	}
+	if _, err := system.Run("/usr/sbin/jail", jailArgs("-c", jail)); err != nil {
+		return err
+	}
+	return started(jail, "start")
+}
+
+// Restart stop and start the jail. With a timeout, a running jail is stopped with Stop first
+func Restart(jail *inventory.Jail, opts StopOptions) error {
+
+	if opts.Timeout > 0 && jail.Runs() {
+		if err := Stop(jail, opts); err != nil {
+			return err
+		}
+		return Start(jail)
+	}
+	if _, err := system.Run("/usr/sbin/jail", jailArgs("-rc", jail)); err != nil {
+		return err
+	}
+	return started(jail, "restart")
+}
+
+// jailArgs the jail(8) args of action for the jail, by name in /etc/jail.conf or its own jail.conf.d file
+func jailArgs(action string, jail *inventory.Jail) []string {
+
+	if !strings.Contains(jail.ConfigPath, "jail.conf.d") {
+		return []string{action, jail.Name}
+	}
+	return []string{action, "-f", jail.ConfigPath}
+}
+
+// started apply the rctl limits, the cpu list and the niceness of the jail after action, and keep its jid current
+func started(jail *inventory.Jail, action string) error {
+
+	// rctl(8) limits from the jail settings and 'jmgr limits'
+	if len(jail.Limits()) > 0 {
+		if err := ApplyLimits(jail); err != nil {
+			return err
+		}
+	}
+
+	b, err := system.Run("/usr/sbin/jls", []string{"-j", jail.Name, "jid"})
+	if err != nil {
+		return fmt.Errorf("%s is not running after %s", jail.Name, action)
+	}
+	jail.Jid, _ = strconv.Atoi(strings.TrimSpace(string(b)))
+
+	// cpu list of 'jmgr cpuset' and niceness of 'jmgr nice'
+	if len(jail.Meta.Cpuset) > 0 {
+		if err := ApplyCpuset(jail, jail.Meta.Cpuset); err != nil {
+			return err
+		}
+	}
+	if jail.Meta.Nice != 0 {
+		if err := ApplyNice(jail, jail.Meta.Nice); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Stop remove the jail with jail(8) and its rctl limits, a jail that is stopped is left as is. Without opts.Timeout
+// wait until it is done, else a stop that takes longer fails with a *TimeoutError and jail(8) is left to finish it,
+// or with Kill the jail processes left get SIGTERM, then SIGKILL 5s later, and jail(8) finishes the stop, its poststop
+// commands and unmounts. If it is still stuck after another timeout, the jail is removed with jail -R and the
+// filesystems mounted in it are unmounted
+func Stop(jail *inventory.Jail, opts StopOptions) error {
+
+	if !jail.Runs() {
+		return nil
+	}
+	if err := stop(jail, []string{"-r", "-f", jail.ConfigPath, jail.Name}, opts); err != nil {
+		return err
+	}
+	if len(jail.Limits()) > 0 {
+		system.Run("/usr/bin/rctl", []string{"-r", "jail:" + jail.Name})
+	}
+	jail.Jid = 0
+	return nil
+}
+
+// stop run jail(8) with args to stop the jail, see Stop
+func stop(jail *inventory.Jail, args []string, opts StopOptions) error {
+
+	if opts.Timeout <= 0 {
+		_, err := system.Run("/usr/sbin/jail", args)
+		return err
+	}
+	out := opts.Out
+	if out == nil {
+		out = io.Discard
+	}
+
+	// a file, not a pipe, jail(8) may outlive jmgr
+	stderr, err := os.CreateTemp("", "jmgr-stop-")
+	if err != nil {
+		return fmt.Errorf("stop() %w", err)
+	}
+	os.Remove(stderr.Name())
+	defer stderr.Close()
+	cmd := exec.Command(system.Tool("/usr/sbin/jail"), args...)
+	cmd.Stderr = stderr
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("stop() %w", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	stopped := func(err error) error {
+		if err != nil {
+			stderr.Seek(0, io.SeekStart)
+			b, _ := io.ReadAll(stderr)
+			return &system.Error{Message: fmt.Sprintf("%s %s failed with:%s", cmd.Path, args, b), Err: err}
+		}
+		return nil
+	}
+
+	select {
+	case err := <-done:
+		return stopped(err)
+	case <-time.After(opts.Timeout):
+	}
+	if !opts.Kill {
+		return &TimeoutError{Jail: jail.Name, Timeout: opts.Timeout}
+	}
+
+	for _, sig := range []string{"TERM", "KILL"} {
+		procs, err := Procs(jail.Jid)
+		if err != nil || len(procs) == 0 {
+			break
+		}
+		kill := []string{"-" + sig}
+		for _, p := range procs {
+			fmt.Fprintln(out, "Kill -"+sig+" "+jail.Name+" "+strconv.Itoa(p.Pid)+" "+p.User+" "+p.Command)
+			kill = append(kill, strconv.Itoa(p.Pid))
+		}
+		system.Run("/bin/kill", kill) // a process may exit before kill
+		for wait := 0; wait < 10; wait++ {
+			if procs, err := Procs(jail.Jid); err != nil || len(procs) == 0 {
+				break
+			}
+			time.Sleep(500 * time.Millisecond)
+		}
+	}
+
+	// the exec.stop commands were killed, jail(8) goes on with the poststop commands and the unmounts
+	select {
+	case err := <-done:
+		return stopped(err)
+	case <-time.After(opts.Timeout):
+	}
+	if _, err := system.Run("/usr/sbin/jls", []string{"-j", jail.Name, "jid"}); err == nil {
+		if _, err := system.Run("/usr/sbin/jail", []string{"-R", jail.Name}); err != nil {
+			return err
+		}
+		fmt.Fprintln(out, jail.Name+" removed with jail -R after "+opts.Timeout.String()+".")
+	}
+	// jail -R does not unmount, the mounts of the config are left
+	for _, mountpoint := range Mounts(jail.Path) {
+		if _, err := system.Run("/sbin/umount", []string{"-f", mountpoint}); err != nil {
+			return err
+		}
+		fmt.Fprintln(out, "Unmounted "+mountpoint)
+	}
+	return nil
+}
+
+// Mounts return the filesystems mounted in the jail path, ex: devfs and nullfs, the deepest first. Not the path itself,
+// the jail dataset
+func Mounts(path string) []string {
+
+	b, err := system.Run("/sbin/mount", []string{"-p"})
+	if err != nil || len(path) == 0 {
+		return nil
+	}
+	var mounts []string
+	for _, line := range strings.Split(string(b), "\n") {
+		if f := strings.Fields(line); len(f) > 1 && strings.HasPrefix(f[1], path+"/") {
+			mounts = append(mounts, f[1])
+		}
+	}
+	slices.SortFunc(mounts, func(a, b string) int { return cmp.Compare(len(b), len(a)) })
+	return mounts
+}
+
+// Procs return the processes of the jail with jid, or of all jails if jid is 0
+func Procs(jid int) ([]Proc, error) {
+
+	args := []string{"-axww", "-o", "jid=,pid=,user=,pcpu=,rss=,etime=,command="}
+	if jid > 0 {
+		args = []string{"-ww", "-J", strconv.Itoa(jid), "-o", "jid=,pid=,user=,pcpu=,rss=,etime=,command="}
+	}
+	b, err := system.Run("/bin/ps", args)
+	if err != nil {
+		return nil, err
+	}
+
+	procs := []Proc{}
+	for _, line := range strings.Split(string(b), "\n") {
+		f := strings.Fields(line)
+		if len(f) < 7 {
+			continue
+		}
+		p := Proc{User: f[2], Time: f[5], Command: strings.Join(f[6:], " ")}
+		p.Jid, _ = strconv.Atoi(f[0])
+		p.Pid, _ = strconv.Atoi(f[1])
+		p.Cpu, _ = strconv.ParseFloat(f[3], 64)
+		p.Rss, _ = strconv.ParseInt(f[4], 10, 64)
+		p.Rss *= 1024
+		if p.Jid > 0 {
+			procs = append(procs, p)
+		}
+	}
+	return procs, nil
+}
+
+// ApplyLimits replace the rctl rules of a running jail with its limits
+func ApplyLimits(jail *inventory.Jail) error {
+
+	system.Run("/usr/bin/rctl", []string{"-r", "jail:" + jail.Name})
+	for _, rule := range jail.Limits() {
+		if _, err := system.Run("/usr/bin/rctl", []string{"-a", "jail:" + jail.Name + ":" + rule}); err != nil {
+			return fmt.Errorf("%s limit %s: %w", jail.Name, rule, err)
+		}
+	}
+	return nil
+}
+
+// ApplyCpuset pin the running jail to the cpus, 'all' for all cpus
+func ApplyCpuset(jail *inventory.Jail, cpus string) error {
+
+	if _, err := system.Run("/usr/bin/cpuset", []string{"-l", cpus, "-j", strconv.Itoa(jail.Jid)}); err != nil {
+		return fmt.Errorf("%s cpuset %s: %w", jail.Name, cpus, err)
+	}
+	return nil
+}
+
+// ApplyNice renice the processes of the running jail, new processes inherit the niceness of their parent
+func ApplyNice(jail *inventory.Jail, nice int) error {
+
+	procs, err := Procs(jail.Jid)
+	if err != nil || len(procs) == 0 {
+		return err
+	}
+	args := []string{strconv.Itoa(nice), "-p"}
+	for _, p := range procs {
+		args = append(args, strconv.Itoa(p.Pid))
+	}
+	// a process may exit before renice
+	if _, err := system.Run("/usr/bin/renice", args); err != nil && !strings.Contains(err.Error(), "No such process") {
+		return fmt.Errorf("%s nice %d: %w", jail.Name, nice, err)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+/*-
+ * Copyright (c) 2024 peter@libassi.se
+ *
+ * SPDX-License-Identifier: BSD-2-Clause
+ */
+
+// Package system runs the external tools of jmgr(8): zfs, jail, jexec, pkg... A tool is found at its default location,
+// or replaced by the Tools of jmgr.conf, see Tool. The other jmgr packages run their commands with Run.
+package system
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+)
+
+// Error an external command that failed, the message has the stderr of the command
+type Error struct {
+	Message string
+	Err     error // ex: *exec.ExitError
+}
+
+func (e *Error) Error() string { return e.Message }
+func (e *Error) Unwrap() error { return e.Err }
+
+// Tools the Tools of jmgr.conf, the external tool paths by name, ex: zfs: /usr/local/sbin/zfs-wrapper
+var Tools map[string]string
+
+// Run execute command and return it's stdout, on error the stderr is in the *Error
+func Run(command string, args []string) ([]byte, error) {
+
+	var stderr bytes.Buffer
+	var stdout bytes.Buffer
+	command = Tool(command)
+	cmd := exec.Command(command, args...)
+	cmd.Stderr = &stderr
+	cmd.Stdout = &stdout
+	err := cmd.Run()
+	if err != nil {
+		return nil, &Error{Message: fmt.Sprintf("%s %s failed with:%s", command, args, stderr.String()), Err: err}
+	}
+	return stdout.Bytes(), nil
+}
+
+// RunStdin Interact with running command.
+func RunStdin(command string, args []string) error {
+
+	cmd := exec.Command(Tool(command), args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Stdin = os.Stdin
+	if err := cmd.Run(); err != nil {
+		return &Error{Message: err.Error(), Err: err}
+	}
+	return nil
+}
+
+// NotRoot Check if current user has sufficent capabilites
+func NotRoot() bool {
+	currentUser, err := user.Current()
+	if err != nil {
+		return false
+
+	} else if currentUser.Uid > "0" {
+		return true
+	}
+
+	return false
+}
+
+// SystemTools the external tools jmgr runs, by name, at their default location. Only these can be overridden by Tools
+var SystemTools = map[string]string{
+	"chflags": "/bin/chflags", "df": "/bin/df", "freebsd-version": "/bin/freebsd-version", "kill": "/bin/kill",
+	"ps": "/bin/ps", "rm": "/bin/rm", "sh": "/bin/sh", "uuidgen": "/bin/uuidgen",
+	"ifconfig": "/sbin/ifconfig", "kldstat": "/sbin/kldstat", "mount": "/sbin/mount", "pfctl": "/sbin/pfctl", "ping": "/sbin/ping",
+	"route": "/sbin/route", "sysctl": "/sbin/sysctl", "umount": "/sbin/umount", "zfs": "/sbin/zfs", "zpool": "/sbin/zpool",
+	"cpuset": "/usr/bin/cpuset", "du": "/usr/bin/du", "env": "/usr/bin/env", "fetch": "/usr/bin/fetch",
+	"rctl": "/usr/bin/rctl", "renice": "/usr/bin/renice", "sockstat": "/usr/bin/sockstat", "ssh": "/usr/bin/ssh",
+	"ssh-keygen": "/usr/bin/ssh-keygen", "tar": "/usr/bin/tar", "uname": "/usr/bin/uname", "rsync": "/usr/local/bin/rsync",
+	"freebsd-update": "/usr/sbin/freebsd-update", "jail": "/usr/sbin/jail", "jexec": "/usr/sbin/jexec", "jls": "/usr/sbin/jls",
+	"pkg": "/usr/sbin/pkg", "service": "/usr/sbin/service", "sysrc": "/usr/sbin/sysrc",
+}
+
+// searchPath where the system tools are looked up, the root owned system directories. Not the PATH of the caller,
+// jmgr runs as root and a user controlled PATH would choose the zfs or jexec that root runs
+var searchPath = []string{"/sbin", "/bin", "/usr/sbin", "/usr/bin", "/usr/local/sbin", "/usr/local/bin"}
+
+// Tool return the path of the external tool path, ex: /sbin/zfs. A system tool at its default location is replaced by
+// the Tools override by name (ex: zfs: /usr/local/sbin/zfs-wrapper), else looked up with exec.LookPath
+// in searchPath, ex: rsync on a host that has it in /usr/bin. Any other path, ex: a hook script, is returned as is
+func Tool(path string) string {
+
+	name := filepath.Base(path)
+	if SystemTools[name] != path {
+		return path
+	}
+	if p := Tools[name]; len(p) > 0 {
+		return p
+	}
+	if _, err := os.Stat(path); err == nil {
+		return path
+	}
+	for _, dir := range searchPath {
+		if p, err := exec.LookPath(filepath.Join(dir, name)); err == nil {
+			return p
+		}
+	}
+	return path
+}
@@ -0,0 +1,122 @@
+/*-
+ * Copyright (c) 2024 peter@libassi.se
+ *
+ * SPDX-License-Identifier: BSD-2-Clause
+ */
+
+// Package templates the jail.conf templates of jmgr(8). A template is a jail.conf(5) file with the <JailName>,
+// <HostName>, <JailPath>, <IPConf>, <PkgCache>, <Nice> and <StopTimeout> placeholders. The default template is
+// JailConfTemplate of jmgr.conf, the named templates are <name>.template files in JailTemplateDir.
+package templates
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Jail the values of the placeholders for a new jail
+type Jail struct {
+	Name        string        // <JailName>
+	Hostname    string        // <HostName>
+	Path        string        // <JailPath>
+	IPConf      string        // <IPConf>, see IPConf
+	PkgCache    string        // <PkgCache>, see PkgCacheMount
+	Nice        int           // <Nice>
+	StopTimeout time.Duration // <StopTimeout>, in seconds
+}
+
+// File return the template file for a named template in dir, empty name is the default template def
+func File(dir string, def string, name string) (string, error) {
+
+	if len(name) == 0 {
+		return def, nil
+	}
+
+	file := filepath.Join(dir, name+".template")
+	if _, err := os.Stat(file); err != nil {
+		return "", fmt.Errorf("no template %s in %s, available templates: %s", name, dir, strings.Join(Names(dir), ", "))
+	}
+	return file, nil
+}
+
+// Names return the names of all templates in dir
+func Names(dir string) []string {
+
+	var names []string
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.template"))
+	for _, f := range files {
+		names = append(names, strings.TrimSuffix(filepath.Base(f), ".template"))
+	}
+	return names
+}
+
+// Render return the jail configuration for a new jail from the template file
+func Render(file string, jail Jail) (string, error) {
+
+	sed := strings.NewReplacer(
+		"<JailName>", jail.Name,
+		"<HostName>", jail.Hostname,
+		"<JailPath>", jail.Path,
+		"<IPConf>", jail.IPConf,
+		"<PkgCache>", jail.PkgCache,
+		"<Nice>", strconv.Itoa(jail.Nice),
+		"<StopTimeout>", strconv.Itoa(int(jail.StopTimeout.Seconds())),
+	)
+
+	Template, err := os.ReadFile(file)
+	if err != nil {
+		return "", fmt.Errorf("can't open jail config template file %s error: %s", file, err.Error())
+	}
+
+	TemplateStr := string(Template) // bytes -> string
+
+	// without a StopTimeout the parameter is left out, ex: stop.timeout = <StopTimeout>;
+	if jail.StopTimeout <= 0 {
+		lines := strings.Split(TemplateStr, "\n")
+		TemplateStr = strings.Join(slices.DeleteFunc(lines, func(l string) bool { return strings.Contains(l, "<StopTimeout>") }), "\n")
+	}
+	return sed.Replace(TemplateStr), nil
+}
+
+// IPConf return the <IPConf> of a jail, the IPv4 address on iface or inherit the host addresses
+func IPConf(ip string, iface string, inherit bool) string {
+
+	if inherit {
+		return "ip4 = inherit;"
+	}
+	return "ip4.addr =  " + ip + ";\n\tinterface = " + iface + ";"
+}
+
+// PkgCacheMark marks the pkg cache mount line in a jail config
+const PkgCacheMark = "# jmgr pkgcache"
+
+// PkgCacheMount return the jail.conf mount line of the shared pkg cache dir on the jail /var/cache/pkg, empty if no dir
+func PkgCacheMount(dir string, path string) string {
+
+	if len(dir) == 0 {
+		return ""
+	}
+	return "mount += \"" + dir + " " + path + "/var/cache/pkg nullfs rw 0 0\"; " + PkgCacheMark
+}
+
+// SetPkgCacheMount replace the pkg cache mount line in a jail config, the line is added at the end of the jail block. Empty mount removes it.
+func SetPkgCacheMount(conf string, mount string) string {
+
+	lines := strings.Split(conf, "\n")
+	lines = slices.DeleteFunc(lines, func(l string) bool { return strings.Contains(l, PkgCacheMark) })
+	if len(mount) > 0 {
+		for i := len(lines) - 1; i >= 0; i-- {
+			if strings.TrimSpace(lines[i]) == "}" {
+				lines = slices.Insert(lines, i, "\t"+mount)
+				break
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
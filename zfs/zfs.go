@@ -0,0 +1,181 @@
+/*-
+ * Copyright (c) 2024 peter@libassi.se
+ *
+ * SPDX-License-Identifier: BSD-2-Clause
+ */
+
+// Package zfs the ZFS operations of jmgr(8) on the jail datasets: snapshots, clones, mountpoints and space used.
+// The zfs command is run with package jmgr/system, the Tools of jmgr.conf apply.
+package zfs
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"jmgr/system"
+)
+
+// Used return the space used by dataset and its snapshots, ex: 1.2G, empty if not on ZFS
+func Used(dataset string) string {
+
+	if len(dataset) == 0 {
+		return ""
+	}
+	b, err := system.Run("/sbin/zfs", []string{"list", "-H", "-o", "used", dataset})
+	if err != nil {
+		return ""
+	}
+	return string(bytes.TrimSpace(b))
+}
+
+// UsedBytes return the bytes used by dataset and its snapshots, 0 if not on ZFS
+func UsedBytes(dataset string) int64 {
+
+	if len(dataset) == 0 {
+		return 0
+	}
+	b, err := system.Run("/sbin/zfs", []string{"list", "-Hp", "-o", "used", dataset})
+	if err != nil {
+		return 0
+	}
+	n, _ := strconv.ParseInt(string(bytes.TrimSpace(b)), 10, 64)
+	return n
+}
+
+// Snapshots return the snapshots of dataset, a jail
+func Snapshots(zfsPath string) ([]string, error) {
+
+	var snaps []string
+
+	b, err := system.Run("/sbin/zfs", []string{"list", "-H", "-t", "snapshot", "-o", "name", zfsPath})
+	if err != nil {
+		return nil, fmt.Errorf("Snapshots() failed: %w", err)
+	}
+
+	for _, snap := range strings.Split(string(b[:]), "\n") {
+		words := strings.Fields(snap)
+		if len(words) > 1 && words[1] == "-" {
+			continue
+		} else {
+			snaps = append(snaps, snap)
+		}
+	}
+	return snaps, nil
+}
+
+// NewestSnapshot return the creation time of the newest snapshot of dataset, false if it has none
+func NewestSnapshot(dataset string) (time.Time, bool) {
+
+	b, err := system.Run("/sbin/zfs", []string{"list", "-Hp", "-t", "snapshot", "-o", "creation", "-s", "creation", "-d", "1", dataset})
+	if err != nil {
+		return time.Time{}, false
+	}
+	lines := strings.Fields(string(b))
+	if len(lines) == 0 {
+		return time.Time{}, false
+	}
+	secs, err := strconv.ParseInt(lines[len(lines)-1], 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(secs, 0), true
+}
+
+// Snapshot create a snapshot of dataset, return its name
+func Snapshot(dataset string) (string, error) {
+
+	return SnapshotPrefix(dataset, "")
+}
+
+// SnapshotPrefix create a snapshot, the snapshot name is prefix + time
+func SnapshotPrefix(dataset string, prefix string) (string, error) {
+
+	t := time.Now()
+	today := t.Format("2006-01-02T15:04:05")
+
+	sname := dataset + "@" + prefix + today
+	_, err := system.Run("/sbin/zfs", []string{"snapshot", sname})
+	if err != nil {
+		return sname, fmt.Errorf("Snapshot() failed: %w", err)
+	}
+
+	return sname, nil
+}
+
+// Mountpoint return the mountpoint for a dataset
+func Mountpoint(dataset string) (string, error) {
+
+	b, err := system.Run("/sbin/zfs", []string{"list", "-H", "-o", "mountpoint", dataset})
+	if err != nil {
+		return "", fmt.Errorf("Mountpoint() failed: %w", err)
+	}
+	ret := strings.Split(string(b[:]), "\n")
+	return ret[0], nil
+}
+
+// Clones return the datasets cloned from a snapshot of dataset or its children, or from the snapshot
+func Clones(dataset string) []string {
+
+	b, err := system.Run("/sbin/zfs", []string{"list", "-H", "-o", "name,origin", "-t", "filesystem,volume"})
+	if err != nil {
+		return nil
+	}
+	var clones []string
+	for _, line := range strings.Split(string(b), "\n") {
+		name, origin, ok := strings.Cut(line, "\t")
+		if ok && (origin == dataset || strings.HasPrefix(origin, dataset+"@") || strings.HasPrefix(origin, dataset+"/")) {
+			clones = append(clones, name)
+		}
+	}
+	return clones
+}
+
+// Origin return the origin snapshot of a cloned dataset, empty if not a clone
+func Origin(dataset string) (string, error) {
+
+	b, err := system.Run("/sbin/zfs", []string{"get", "-H", "-o", "value", "origin", dataset})
+	if err != nil {
+		return "", fmt.Errorf("Origin() failed: %w", err)
+	}
+	origin := string(bytes.TrimRight(b, "\n"))
+	if origin == "-" {
+		return "", nil
+	}
+	return origin, nil
+}
+
+// LatestSnapshot return the latest snapshot of dataset
+func LatestSnapshot(dataset string) (string, error) {
+
+	b, err := system.Run("/sbin/zfs", []string{"list", "-H", "-t", "snapshot", "-o", "name", dataset})
+	if err != nil {
+		return "", fmt.Errorf("LatestSnapshot() failed: %w", err)
+	}
+
+	snaps := strings.Split(string(b[:]), "\n")
+	if len(snaps) < 2 {
+		return "", fmt.Errorf("LatestSnapshot() no snapshots found for: %s", dataset)
+	}
+
+	return snaps[len(snaps)-2], nil
+}
+
+// SendSize return the estimated size in bytes of a zfs send stream, 'zfs send -nP', 0 if unknown
+func SendSize(send []string) int64 {
+
+	b, err := system.Run("/sbin/zfs", append([]string{"send", "-nP"}, send[1:]...))
+	if err != nil {
+		return 0
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		f := strings.Fields(line)
+		if len(f) == 2 && f[0] == "size" {
+			n, _ := strconv.ParseInt(f[1], 10, 64)
+			return n
+		}
+	}
+	return 0
+}